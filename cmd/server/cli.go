@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/amem/mcp-server/pkg/config"
+	"github.com/amem/mcp-server/pkg/memory"
+	"github.com/amem/mcp-server/pkg/services"
+	"go.uber.org/zap"
+)
+
+// openMemorySystem builds the memory system for a subcommand. With
+// checkEmbedding unset the store is opened without checking that its vectors
+// come from the configured embedding model, for commands that only read
+// content or replace every vector.
+func openMemorySystem(ctx context.Context, cfg *config.Config, logger *zap.Logger, checkEmbedding bool) (*memory.System, error) {
+	embeddingService, err := services.NewEmbeddingService(cfg.Embedding, logger.Named("embedding"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedding service: %w", err)
+	}
+
+	var embeddingInfo services.EmbeddingInfo
+	if checkEmbedding {
+		embeddingInfo, err = embeddingService.Info(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reach embedding service: %w", err)
+		}
+	}
+
+	memoryStore, err := services.NewMemoryStore(cfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create memory store: %w", err)
+	}
+
+	if err := memoryStore.Initialize(ctx, embeddingInfo); err != nil {
+		return nil, fmt.Errorf("failed to initialize memory store: %w", err)
+	}
+
+	workspaceRegistry, err := services.NewWorkspaceRegistry(cfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workspace registry: %w", err)
+	}
+
+	if err := workspaceRegistry.Initialize(ctx); err != nil {
+		return nil, fmt.Errorf("failed to initialize workspace registry: %w", err)
+	}
+
+	workspaceService := services.NewWorkspaceService(memoryStore, workspaceRegistry, logger.Named("workspace"))
+	workspaceService.SetGitIdentity(cfg.Workspace.GitIdentity)
+	llmService := services.NewLiteLLMService(cfg.LiteLLM, logger.Named("litellm"))
+	promptManager := services.NewPromptManager(cfg.Prompts, logger.Named("prompts"))
+
+	memorySystem := memory.NewSystem(logger.Named("memory"), llmService, promptManager, memoryStore, embeddingService, workspaceService)
+	memorySystem.SetDedupeConfig(cfg.Dedupe)
+
+	return memorySystem, nil
+}
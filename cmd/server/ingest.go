@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/amem/mcp-server/pkg/ingest"
+	"github.com/amem/mcp-server/pkg/memory"
+	"github.com/amem/mcp-server/pkg/models"
+	"go.uber.org/zap"
+)
+
+// runIngest implements the ingest subcommand, which stores the source files
+// of a local directory as memories
+func runIngest(args []string) int {
+	flags := flag.NewFlagSet("ingest", flag.ExitOnError)
+	var (
+		configPath    = flags.String("config", "", "Path to configuration file")
+		envFile       = flags.String("env", ".env", "Path to environment file")
+		logLevel      = flags.String("log-level", "warn", "Log level (debug, info, warn, error)")
+		workspaceID   = flags.String("workspace", "", "Workspace to store the memories in (default: the directory's absolute path)")
+		maxChunkLines = flags.Int("max-chunk-lines", ingest.DefaultMaxChunkLines, "Longest chunk in lines; longer definitions are split")
+		concurrency   = flags.Int("concurrency", 4, "Notes constructed by the LLM at once")
+		dryRun        = flags.Bool("dry-run", false, "Count the files and chunks without storing them")
+	)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s ingest [flags] [directory]\n\n", os.Args[0])
+		fmt.Fprintln(flags.Output(), "Stores the source files of a directory (default: the current one) as memories,")
+		fmt.Fprintln(flags.Output(), "split at functions and other definitions, skipping files excluded by .gitignore.")
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	path := "."
+	if flags.NArg() > 0 {
+		path = flags.Arg(0)
+	}
+
+	cfg, logger := bootstrap(*configPath, *envFile, *logLevel)
+	defer logger.Sync()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	memorySystem, err := openMemorySystem(ctx, cfg, logger, true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+
+	req := models.IngestRequest{
+		Path:          path,
+		WorkspaceID:   *workspaceID,
+		MaxChunkLines: *maxChunkLines,
+		Concurrency:   *concurrency,
+		DryRun:        *dryRun,
+	}
+	response, err := memorySystem.IngestRepository(ctx, req)
+	if err != nil {
+		logger.Error("Ingestion failed", zap.Error(err))
+		fmt.Fprintf(os.Stderr, "Ingestion failed: %v\n", err)
+		return 1
+	}
+
+	fmt.Print(memory.FormatIngestResponse(response))
+	if response.Failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"net"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 
 	"github.com/amem/mcp-server/pkg/config"
@@ -20,45 +23,35 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "reindex":
+			os.Exit(runReindex(os.Args[2:]))
+		case "export":
+			os.Exit(runExport(os.Args[2:]))
+		case "import":
+			os.Exit(runImport(os.Args[2:]))
+		case "ingest":
+			os.Exit(runIngest(os.Args[2:]))
+		}
+	}
+
 	// Parse command line flags
 	var (
 		configPath = flag.String("config", "", "Path to configuration file")
 		envFile    = flag.String("env", ".env", "Path to environment file")
 		logLevel   = flag.String("log-level", "info", "Log level (debug, info, warn, error)")
+		transport  = flag.String("transport", "stdio", "MCP transport: stdio, or http to serve several clients on server.port")
 	)
 	flag.Parse()
 
-	// Load environment variables
-	if *envFile != "" {
-		if err := godotenv.Load(*envFile); err != nil {
-			// Don't fail if .env file doesn't exist
-			fmt.Fprintf(os.Stderr, "Warning: Could not load .env file: %v\n", err)
-		}
-	}
-
-	// Initialize logger
-	logger, err := initLogger(*logLevel)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
-		os.Exit(1)
-	}
+	cfg, logger := bootstrap(*configPath, *envFile, *logLevel)
 	defer logger.Sync()
 
-	logger.Info("Starting A-MEM MCP Server",
-		zap.String("version", "1.0.0"),
-		zap.String("config_path", *configPath))
-
-	// Load configuration
-	cfg, err := config.LoadConfig(*configPath)
-	if err != nil {
-		logger.Fatal("Failed to load configuration", zap.Error(err))
+	if *transport != "stdio" && *transport != "http" {
+		logger.Fatal("Unknown transport", zap.String("transport", *transport))
 	}
 
-	logger.Info("Configuration loaded",
-		zap.String("chromadb_url", cfg.ChromaDB.URL),
-		zap.String("default_model", cfg.LiteLLM.DefaultModel),
-		zap.Bool("evolution_enabled", cfg.Evolution.Enabled))
-
 	// Create context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -70,24 +63,73 @@ func main() {
 	llmService := services.NewLiteLLMService(cfg.LiteLLM, logger.Named("litellm"))
 
 	// Initialize embedding service
-	embeddingService := services.NewEmbeddingService(cfg.Embedding, logger.Named("embedding"))
+	embeddingService, err := services.NewEmbeddingService(cfg.Embedding, logger.Named("embedding"))
+	if err != nil {
+		logger.Fatal("Failed to create embedding service", zap.Error(err))
+	}
 
-	// Initialize ChromaDB service
-	chromaService := services.NewChromaDBService(cfg.ChromaDB, logger.Named("chromadb"))
+	embeddingInfo, err := embeddingService.Info(ctx)
+	if err != nil {
+		logger.Fatal("Failed to reach embedding service", zap.Error(err))
+	}
+
+	logger.Info("Embedding service ready",
+		zap.String("service", cfg.Embedding.Service),
+		zap.String("model", embeddingInfo.Model),
+		zap.Int("dimension", embeddingInfo.Dimension))
 
-	// Initialize ChromaDB collection
-	if err := chromaService.Initialize(ctx); err != nil {
-		logger.Fatal("Failed to initialize ChromaDB", zap.Error(err))
+	// Initialize memory store
+	memoryStore, err := services.NewMemoryStore(cfg, logger)
+	if err != nil {
+		logger.Fatal("Failed to create memory store", zap.Error(err))
+	}
+
+	// Stored vectors from another embedding model can be neither searched nor
+	// added to, so the server then starts with the admin tools only, which can
+	// reindex them
+	adminOnly := false
+	if err := memoryStore.Initialize(ctx, embeddingInfo); err != nil {
+		if !errors.Is(err, services.ErrEmbeddingMismatch) {
+			logger.Fatal("Failed to initialize memory store", zap.Error(err))
+		}
+
+		logger.Warn("Stored memories were embedded with another model; serving admin tools only until they are reindexed", zap.Error(err))
+		if err := memoryStore.Initialize(ctx, services.EmbeddingInfo{}); err != nil {
+			logger.Fatal("Failed to initialize memory store", zap.Error(err))
+		}
+		adminOnly = true
 	}
 
 	// Initialize prompt manager
-	_ = services.NewPromptManager(cfg.Prompts, logger.Named("prompts"))
+	promptManager := services.NewPromptManager(cfg.Prompts, logger.Named("prompts"))
 
-	// Initialize workspace service
-	workspaceService := services.NewWorkspaceService(chromaService, logger.Named("workspace"))
+	// Initialize workspace registry and service
+	workspaceRegistry, err := services.NewWorkspaceRegistry(cfg, logger)
+	if err != nil {
+		logger.Fatal("Failed to create workspace registry", zap.Error(err))
+	}
+
+	if err := workspaceRegistry.Initialize(ctx); err != nil {
+		logger.Fatal("Failed to initialize workspace registry", zap.Error(err))
+	}
+
+	workspaceService := services.NewWorkspaceService(memoryStore, workspaceRegistry, logger.Named("workspace"))
+	workspaceService.SetGitIdentity(cfg.Workspace.GitIdentity)
+
+	// Register workspaces whose memories were stored before they were
+	// registered, so workspace listings read only the registry
+	if registered, err := workspaceService.RegisterStoredWorkspaces(ctx); err != nil {
+		logger.Warn("Failed to register stored workspaces", zap.Error(err))
+	} else if registered > 0 {
+		logger.Info("Registered stored workspaces", zap.Int("count", registered))
+	}
 
 	// Initialize memory system
-	memorySystem := memory.NewSystem(logger.Named("memory"), llmService, chromaService, embeddingService, workspaceService)
+	memorySystem := memory.NewSystem(logger.Named("memory"), llmService, promptManager, memoryStore, embeddingService, workspaceService)
+	memorySystem.SetDedupeConfig(cfg.Dedupe)
+	if err := memorySystem.SetIngestConfig(cfg.Ingest); err != nil {
+		logger.Fatal("Invalid ingest configuration", zap.Error(err))
+	}
 
 	// Initialize evolution manager
 	evolutionManager := memory.NewEvolutionManager(memorySystem, logger.Named("evolution"))
@@ -101,26 +143,116 @@ func main() {
 	}()
 
 	// Initialize scheduler
-	taskScheduler := scheduler.NewScheduler(cfg.Evolution, evolutionManager, logger.Named("scheduler"))
-	if err := taskScheduler.Start(ctx); err != nil {
-		logger.Error("Failed to start scheduler", zap.Error(err))
+	if !adminOnly {
+		taskScheduler := scheduler.NewScheduler(cfg.Evolution, evolutionManager, logger.Named("scheduler"))
+		if err := taskScheduler.Start(ctx); err != nil {
+			logger.Error("Failed to start scheduler", zap.Error(err))
+		}
 	}
 
 	// Initialize MCP server
 	mcpServer := mcp.NewServer(logger.Named("mcp"))
+	mcpServer.SetMaxConcurrentRequests(cfg.Server.MaxConcurrency)
+	if cfg.Server.MaxRequestSize != "" {
+		maxRequestSize, err := config.ParseSize(cfg.Server.MaxRequestSize)
+		if err != nil {
+			logger.Fatal("Invalid max request size", zap.Error(err))
+		}
+		mcpServer.SetMaxRequestSize(maxRequestSize)
+	}
 
 	// Register tools
 	logger.Info("Registering MCP tools...")
 
+	reindexTool := memory.NewReindexMemoriesTool(memorySystem, logger.Named("reindex_tool"))
+	mcpServer.RegisterTool(reindexTool)
+
+	exportTool := memory.NewExportMemoriesTool(memorySystem, logger.Named("export_tool"))
+	mcpServer.RegisterTool(exportTool)
+
+	if adminOnly {
+		logger.Warn("Only reindex_memories and export_memories are available; restart the server after reindex_memories switches collections")
+	} else {
+		registerMemoryTools(mcpServer, cfg, *transport, memorySystem, evolutionManager, workspaceService, logger)
+	}
+
+	logger.Info("All tools registered successfully")
+
+	// Expose workspaces and memories as resources and push updates to subscribers
+	mcpServer.SetResourceProvider(memory.NewResourceProvider(memorySystem, workspaceService, logger.Named("resources")))
+	notifyResources := func(memoryID, workspaceID string) {
+		mcpServer.NotifyResourceUpdated(memory.MemoryResourceURI(memoryID))
+		mcpServer.NotifyResourceUpdated(memory.WorkspaceResourceURI(workspaceID))
+	}
+	memorySystem.AddChangeListener(notifyResources)
+	workspaceService.AddChangeListener(notifyResources)
+
+	// Serve the prompt templates as MCP prompts
+	mcpServer.SetPromptProvider(promptManager)
+
+	// Set up graceful shutdown
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		<-sigChan
+		logger.Info("Received shutdown signal")
+		cancel()
+	}()
+
+	// Start MCP server
+	logger.Info("Starting MCP server...", zap.String("transport", *transport))
+	if *transport == "http" {
+		addr := net.JoinHostPort(cfg.Server.Host, strconv.Itoa(cfg.Server.Port))
+		err = mcpServer.StartHTTP(ctx, addr, cfg.Server.AuthToken)
+	} else {
+		err = mcpServer.Start(ctx)
+	}
+	if err != nil && err != context.Canceled {
+		logger.Fatal("MCP server failed", zap.Error(err))
+	}
+
+	logger.Info("A-MEM MCP Server shutdown complete")
+}
+
+// registerMemoryTools registers the tools that search and write memories,
+// which need stored vectors that match the embedding model
+func registerMemoryTools(mcpServer *mcp.Server, cfg *config.Config, transport string, memorySystem *memory.System, evolutionManager *memory.EvolutionManager, workspaceService *services.WorkspaceService, logger *zap.Logger) {
 	storeTool := memory.NewStoreCodingMemoryTool(memorySystem, logger.Named("store_tool"))
 	mcpServer.RegisterTool(storeTool)
 
+	storeBatchTool := memory.NewStoreCodingMemoriesBatchTool(memorySystem, logger.Named("store_batch_tool"))
+	mcpServer.RegisterTool(storeBatchTool)
+
 	retrieveTool := memory.NewRetrieveRelevantMemoriesTool(memorySystem, logger.Named("retrieve_tool"))
 	mcpServer.RegisterTool(retrieveTool)
 
+	getTool := memory.NewGetMemoryTool(memorySystem, logger.Named("get_tool"))
+	mcpServer.RegisterTool(getTool)
+
+	updateTool := memory.NewUpdateMemoryTool(memorySystem, logger.Named("update_tool"))
+	mcpServer.RegisterTool(updateTool)
+
+	deleteTool := memory.NewDeleteMemoryTool(memorySystem, logger.Named("delete_tool"))
+	mcpServer.RegisterTool(deleteTool)
+
+	listTool := memory.NewListMemoriesTool(memorySystem, logger.Named("list_tool"))
+	mcpServer.RegisterTool(listTool)
+
+	// Remote clients may only ingest from the configured directories
+	if transport == "stdio" || len(cfg.Ingest.AllowedRoots) > 0 {
+		ingestTool := memory.NewIngestRepositoryTool(memorySystem, logger.Named("ingest_tool"))
+		mcpServer.RegisterTool(ingestTool)
+	} else {
+		logger.Info("ingest_repository is disabled over HTTP until ingest.allowed_roots is set")
+	}
+
 	evolveTool := memory.NewEvolveMemoryNetworkTool(evolutionManager, logger.Named("evolve_tool"))
 	mcpServer.RegisterTool(evolveTool)
 
+	importTool := memory.NewImportMemoriesTool(memorySystem, logger.Named("import_tool"))
+	mcpServer.RegisterTool(importTool)
+
 	// Register workspace management tools
 	workspaceInitTool := memory.NewWorkspaceInitTool(workspaceService, logger.Named("workspace_init_tool"))
 	mcpServer.RegisterTool(workspaceInitTool)
@@ -131,25 +263,63 @@ func main() {
 	workspaceRetrieveTool := memory.NewWorkspaceRetrieveTool(workspaceService, logger.Named("workspace_retrieve_tool"))
 	mcpServer.RegisterTool(workspaceRetrieveTool)
 
-	logger.Info("All tools registered successfully")
+	workspaceListTool := memory.NewWorkspaceListTool(workspaceService, logger.Named("workspace_list_tool"))
+	mcpServer.RegisterTool(workspaceListTool)
 
-	// Set up graceful shutdown
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	workspaceStatsTool := memory.NewWorkspaceStatsTool(workspaceService, logger.Named("workspace_stats_tool"))
+	mcpServer.RegisterTool(workspaceStatsTool)
 
-	go func() {
-		<-sigChan
-		logger.Info("Received shutdown signal")
-		cancel()
-	}()
+	workspaceUpdateTool := memory.NewWorkspaceUpdateTool(workspaceService, logger.Named("workspace_update_tool"))
+	mcpServer.RegisterTool(workspaceUpdateTool)
 
-	// Start MCP server
-	logger.Info("Starting MCP server...")
-	if err := mcpServer.Start(ctx); err != nil && err != context.Canceled {
-		logger.Fatal("MCP server failed", zap.Error(err))
+	workspaceDeleteTool := memory.NewWorkspaceDeleteTool(memorySystem, logger.Named("workspace_delete_tool"))
+	mcpServer.RegisterTool(workspaceDeleteTool)
+
+	workspaceRenameTool := memory.NewWorkspaceRenameTool(workspaceService, logger.Named("workspace_rename_tool"))
+	mcpServer.RegisterTool(workspaceRenameTool)
+
+	workspaceMergeTool := memory.NewWorkspaceMergeTool(workspaceService, logger.Named("workspace_merge_tool"))
+	mcpServer.RegisterTool(workspaceMergeTool)
+
+	moveMemoriesTool := memory.NewMoveMemoriesTool(workspaceService, logger.Named("move_memories_tool"))
+	mcpServer.RegisterTool(moveMemoriesTool)
+}
+
+// bootstrap loads environment variables, the logger and the configuration,
+// exiting if any of them cannot be set up
+func bootstrap(configPath, envFile, logLevel string) (*config.Config, *zap.Logger) {
+	// Load environment variables
+	if envFile != "" {
+		if err := godotenv.Load(envFile); err != nil {
+			// Don't fail if .env file doesn't exist
+			fmt.Fprintf(os.Stderr, "Warning: Could not load .env file: %v\n", err)
+		}
 	}
 
-	logger.Info("A-MEM MCP Server shutdown complete")
+	// Initialize logger
+	logger, err := initLogger(logLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
+		os.Exit(1)
+	}
+
+	logger.Info("Starting A-MEM MCP Server",
+		zap.String("version", "1.0.0"),
+		zap.String("config_path", configPath))
+
+	// Load configuration
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		logger.Fatal("Failed to load configuration", zap.Error(err))
+	}
+
+	logger.Info("Configuration loaded",
+		zap.String("storage_backend", cfg.Storage.Backend),
+		zap.String("chromadb_url", cfg.ChromaDB.URL),
+		zap.String("default_model", cfg.LiteLLM.DefaultModel),
+		zap.Bool("evolution_enabled", cfg.Evolution.Enabled))
+
+	return cfg, logger
 }
 
 // initLogger initializes the logger with the specified level
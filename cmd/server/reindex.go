@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/amem/mcp-server/pkg/memory"
+	"github.com/amem/mcp-server/pkg/models"
+	"go.uber.org/zap"
+)
+
+// runReindex implements the reindex subcommand, which re-embeds every memory
+// with the configured embedding model and switches to the rebuilt collection
+func runReindex(args []string) int {
+	flags := flag.NewFlagSet("reindex", flag.ExitOnError)
+	var (
+		configPath = flags.String("config", "", "Path to configuration file")
+		envFile    = flags.String("env", ".env", "Path to environment file")
+		logLevel   = flags.String("log-level", "warn", "Log level (debug, info, warn, error)")
+		batchSize  = flags.Int("batch-size", 100, "Memories re-embedded per embedding request")
+		noSwitch   = flags.Bool("no-switch", false, "Build the new collection without switching to it")
+	)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s reindex [flags]\n\n", os.Args[0])
+		fmt.Fprintln(flags.Output(), "Re-embeds every memory with the configured embedding model into a new collection,")
+		fmt.Fprintln(flags.Output(), "then switches to it. Rerun after a failure to resume. Stop the server first, or")
+		fmt.Fprintln(flags.Output(), "call the reindex_memories tool of the running server instead.")
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	cfg, logger := bootstrap(*configPath, *envFile, *logLevel)
+	defer logger.Sync()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	// The live collection may hold vectors from another model, which is the
+	// point of reindexing, so open it without the embedding check
+	memorySystem, err := openMemorySystem(ctx, cfg, logger, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+
+	req := models.ReindexRequest{BatchSize: *batchSize, Switch: !*noSwitch}
+	response, err := memorySystem.Reindex(ctx, req, func(progress models.ReindexResponse) {
+		fmt.Fprintf(os.Stderr, "Scanned %d memories: %d re-embedded, %d already current\n",
+			progress.Scanned, progress.Reembedded, progress.Unchanged)
+	})
+	if err != nil {
+		logger.Error("Reindex failed", zap.Error(err))
+		fmt.Fprintf(os.Stderr, "Reindex failed: %v\n", err)
+		return 1
+	}
+
+	fmt.Print(memory.FormatReindexResponse(response))
+	return 0
+}
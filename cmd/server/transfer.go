@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/amem/mcp-server/pkg/memory"
+	"github.com/amem/mcp-server/pkg/models"
+	"go.uber.org/zap"
+)
+
+// runExport implements the export subcommand, which writes memories as JSONL
+// to a file or standard output
+func runExport(args []string) int {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	var (
+		configPath  = flags.String("config", "", "Path to configuration file")
+		envFile     = flags.String("env", ".env", "Path to environment file")
+		logLevel    = flags.String("log-level", "warn", "Log level (debug, info, warn, error)")
+		output      = flags.String("out", "", "File to write (default: standard output)")
+		workspaceID = flags.String("workspace", "", "Only export memories in this workspace")
+		embeddings  = flags.Bool("embeddings", false, "Include embeddings so an import with the same model can skip re-embedding")
+	)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s export [flags]\n\n", os.Args[0])
+		fmt.Fprintln(flags.Output(), "Writes memories with their links and metadata as JSONL, for backups or")
+		fmt.Fprintln(flags.Output(), "moving memories to another server with the import command.")
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	cfg, logger := bootstrap(*configPath, *envFile, *logLevel)
+	defer logger.Sync()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	// Embeddings are only read, and labelled with the configured model, when included
+	memorySystem, err := openMemorySystem(ctx, cfg, logger, *embeddings)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create %s: %v\n", *output, err)
+			return 1
+		}
+		defer file.Close()
+		out = file
+	}
+
+	req := models.ExportRequest{WorkspaceID: *workspaceID, IncludeEmbeddings: *embeddings}
+	response, err := memorySystem.ExportMemories(ctx, req, out)
+	if err != nil {
+		logger.Error("Export failed", zap.Error(err))
+		fmt.Fprintf(os.Stderr, "Export failed: %v\n", err)
+		return 1
+	}
+
+	response.Path = *output
+	fmt.Fprint(os.Stderr, memory.FormatExportResponse(response))
+	return 0
+}
+
+// runImport implements the import subcommand, which stores memories from an
+// export file or standard input
+func runImport(args []string) int {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	var (
+		configPath  = flags.String("config", "", "Path to configuration file")
+		envFile     = flags.String("env", ".env", "Path to environment file")
+		logLevel    = flags.String("log-level", "warn", "Log level (debug, info, warn, error)")
+		input       = flags.String("in", "", "File to read (default: standard input)")
+		onConflict  = flags.String("on-conflict", models.ImportConflictSkip, "For IDs already stored: skip, overwrite, new_id or fail")
+		workspaceID = flags.String("workspace", "", "Import every memory into this workspace")
+		reembed     = flags.Bool("reembed", false, "Re-embed every memory even when the export has embeddings from the configured model")
+	)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s import [flags]\n\n", os.Args[0])
+		fmt.Fprintln(flags.Output(), "Stores memories from a JSONL file written by the export command. Memories without")
+		fmt.Fprintln(flags.Output(), "embeddings from the configured model are re-embedded.")
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	cfg, logger := bootstrap(*configPath, *envFile, *logLevel)
+	defer logger.Sync()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	memorySystem, err := openMemorySystem(ctx, cfg, logger, true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+
+	var in io.Reader = os.Stdin
+	if *input != "" {
+		file, err := os.Open(*input)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open %s: %v\n", *input, err)
+			return 1
+		}
+		defer file.Close()
+		in = file
+	}
+
+	req := models.ImportRequest{OnConflict: *onConflict, WorkspaceID: *workspaceID, Reembed: *reembed}
+	response, err := memorySystem.ImportMemories(ctx, req, in)
+	if err != nil {
+		logger.Error("Import failed", zap.Error(err))
+		fmt.Fprintf(os.Stderr, "Import failed: %v\n", err)
+		if response != nil {
+			fmt.Fprintf(os.Stderr, "%d of %d memories were imported before the failure and were kept\n", response.Imported, response.Read)
+		}
+		return 1
+	}
+
+	response.Path = *input
+	fmt.Print(memory.FormatImportResponse(response))
+	return 0
+}
@@ -89,6 +89,7 @@ async def generate_embeddings(request: EmbeddingRequest):
         embeddings = model.encode(
             request.sentences,
             convert_to_numpy=True,
+            normalize_embeddings=True,
             show_progress_bar=False
         )
         
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -12,26 +13,40 @@ import (
 // Config represents the application configuration
 type Config struct {
 	Server     ServerConfig     `yaml:"server"`
+	Storage    StorageConfig    `yaml:"storage"`
 	ChromaDB   ChromaDBConfig   `yaml:"chromadb"`
 	LiteLLM    LiteLLMConfig    `yaml:"litellm"`
 	Embedding  EmbeddingConfig  `yaml:"embedding"`
 	Evolution  EvolutionConfig  `yaml:"evolution"`
+	Dedupe     DedupeConfig     `yaml:"dedupe"`
+	Workspace  WorkspaceConfig  `yaml:"workspace"`
+	Ingest     IngestConfig     `yaml:"ingest"`
 	Prompts    PromptsConfig    `yaml:"prompts"`
 	Monitoring MonitoringConfig `yaml:"monitoring"`
 }
 
 // ServerConfig represents server configuration
 type ServerConfig struct {
-	Port           int    `yaml:"port"`
+	Host           string `yaml:"host"`       // Interface the HTTP transport listens on
+	Port           int    `yaml:"port"`       // Port the HTTP transport listens on
+	AuthToken      string `yaml:"auth_token"` // Bearer token HTTP clients must send, required off loopback
 	LogLevel       string `yaml:"log_level"`
 	MaxRequestSize string `yaml:"max_request_size"`
+	MaxConcurrency int    `yaml:"max_concurrency"` // MCP requests handled at once
+}
+
+// StorageConfig selects the memory storage backend
+type StorageConfig struct {
+	Backend string `yaml:"backend"` // chromadb|local
+	Path    string `yaml:"path"`    // Data file for the local backend
 }
 
 // ChromaDBConfig represents ChromaDB configuration
 type ChromaDBConfig struct {
-	URL        string `yaml:"url"`
-	Collection string `yaml:"collection"`
-	BatchSize  int    `yaml:"batch_size"`
+	URL            string `yaml:"url"`
+	Collection     string `yaml:"collection"`
+	BatchSize      int    `yaml:"batch_size"`
+	DistanceMetric string `yaml:"distance_metric"` // cosine|l2|ip, also used by the local backend
 }
 
 // LiteLLMConfig represents LiteLLM configuration
@@ -45,10 +60,13 @@ type LiteLLMConfig struct {
 
 // EmbeddingConfig represents embedding service configuration
 type EmbeddingConfig struct {
-	Service   string `yaml:"service"`
-	Model     string `yaml:"model"`
-	BatchSize int    `yaml:"batch_size"`
-	URL       string `yaml:"url"`
+	Service   string        `yaml:"service"` // openai|sentence-transformers|ollama
+	Model     string        `yaml:"model"`
+	BatchSize int           `yaml:"batch_size"`
+	URL       string        `yaml:"url"`       // Empty uses the provider's default endpoint
+	APIKey    string        `yaml:"api_key"`   // OpenAI-compatible providers only
+	Dimension int           `yaml:"dimension"` // Expected vector size, 0 detects it from the model
+	Timeout   time.Duration `yaml:"timeout"`
 }
 
 // EvolutionConfig represents memory evolution configuration
@@ -59,11 +77,28 @@ type EvolutionConfig struct {
 	WorkerCount int    `yaml:"worker_count"`
 }
 
+// DedupeConfig controls how new memories that duplicate existing ones are handled
+type DedupeConfig struct {
+	Mode      string  `yaml:"mode"`      // skip|merge|force
+	Threshold float64 `yaml:"threshold"` // Minimum similarity for a duplicate, 0.0-1.0
+}
+
+// IngestConfig limits which directories the ingest_repository tool may read
+type IngestConfig struct {
+	AllowedRoots []string `yaml:"allowed_roots"` // Directories ingestion is confined to; the tool is stdio-only when empty
+}
+
+// WorkspaceConfig controls how workspace identifiers are resolved
+type WorkspaceConfig struct {
+	GitIdentity bool `yaml:"git_identity"` // Key paths inside a git repository on its canonical remote
+}
+
 // PromptsConfig represents prompt management configuration
 type PromptsConfig struct {
-	Directory    string `yaml:"directory"`
-	CacheEnabled bool   `yaml:"cache_enabled"`
-	HotReload    bool   `yaml:"hot_reload"`
+	Directory        string `yaml:"directory"`
+	CacheEnabled     bool   `yaml:"cache_enabled"`
+	HotReload        bool   `yaml:"hot_reload"`
+	NoteConstruction string `yaml:"note_construction"` // Template used to analyze new memories
 }
 
 // MonitoringConfig represents monitoring configuration
@@ -78,14 +113,22 @@ func LoadConfig(configPath string) (*Config, error) {
 	config := &Config{
 		// Set defaults
 		Server: ServerConfig{
+			Host:           getEnvString("AMEM_HOST", "127.0.0.1"),
 			Port:           getEnvInt("AMEM_PORT", 8080),
+			AuthToken:      getEnvString("AMEM_AUTH_TOKEN", ""),
 			LogLevel:       getEnvString("AMEM_LOG_LEVEL", "info"),
 			MaxRequestSize: getEnvString("AMEM_MAX_REQUEST_SIZE", "10MB"),
+			MaxConcurrency: getEnvInt("AMEM_MAX_CONCURRENCY", 8),
+		},
+		Storage: StorageConfig{
+			Backend: getEnvString("AMEM_STORAGE_BACKEND", "chromadb"),
+			Path:    getEnvString("AMEM_STORAGE_PATH", "./data/memories.json"),
 		},
 		ChromaDB: ChromaDBConfig{
-			URL:        getEnvString("CHROMADB_HOST", "http://localhost:8000"),
-			Collection: getEnvString("CHROMADB_COLLECTION", "amem_memories"),
-			BatchSize:  getEnvInt("CHROMADB_BATCH_SIZE", 100),
+			URL:            getEnvString("CHROMADB_HOST", "http://localhost:8000"),
+			Collection:     getEnvString("CHROMADB_COLLECTION", "amem_memories"),
+			BatchSize:      getEnvInt("CHROMADB_BATCH_SIZE", 100),
+			DistanceMetric: getEnvString("CHROMADB_DISTANCE_METRIC", "cosine"),
 		},
 		LiteLLM: LiteLLMConfig{
 			DefaultModel:   getEnvString("LITELLM_DEFAULT_MODEL", "gpt-4-turbo"),
@@ -98,7 +141,10 @@ func LoadConfig(configPath string) (*Config, error) {
 			Service:   getEnvString("EMBEDDING_SERVICE", "sentence-transformers"),
 			Model:     getEnvString("EMBEDDING_MODEL", "all-MiniLM-L6-v2"),
 			BatchSize: getEnvInt("EMBEDDING_BATCH_SIZE", 32),
-			URL:       getEnvString("EMBEDDING_SERVICE_URL", "http://localhost:8005"),
+			URL:       getEnvString("EMBEDDING_SERVICE_URL", ""),
+			APIKey:    getEnvString("EMBEDDING_API_KEY", ""),
+			Dimension: getEnvInt("EMBEDDING_DIMENSION", 0),
+			Timeout:   time.Duration(getEnvInt("EMBEDDING_TIMEOUT_SECONDS", 30)) * time.Second,
 		},
 		Evolution: EvolutionConfig{
 			Enabled:     getEnvBool("AMEM_EVOLUTION_ENABLED", true),
@@ -106,10 +152,21 @@ func LoadConfig(configPath string) (*Config, error) {
 			BatchSize:   getEnvInt("AMEM_EVOLUTION_BATCH_SIZE", 50),
 			WorkerCount: getEnvInt("AMEM_EVOLUTION_WORKER_COUNT", 3),
 		},
+		Dedupe: DedupeConfig{
+			Mode:      getEnvString("AMEM_DEDUPE_MODE", "force"),
+			Threshold: getEnvFloat("AMEM_DEDUPE_THRESHOLD", 0.95),
+		},
+		Workspace: WorkspaceConfig{
+			GitIdentity: getEnvBool("AMEM_WORKSPACE_GIT_IDENTITY", false),
+		},
+		Ingest: IngestConfig{
+			AllowedRoots: getEnvList("AMEM_INGEST_ALLOWED_ROOTS"),
+		},
 		Prompts: PromptsConfig{
-			Directory:    getEnvString("AMEM_PROMPTS_PATH", "/app/prompts"),
-			CacheEnabled: getEnvBool("AMEM_PROMPTS_CACHE_ENABLED", true),
-			HotReload:    getEnvBool("AMEM_PROMPTS_HOT_RELOAD", true),
+			Directory:        getEnvString("AMEM_PROMPTS_PATH", "/app/prompts"),
+			CacheEnabled:     getEnvBool("AMEM_PROMPTS_CACHE_ENABLED", true),
+			HotReload:        getEnvBool("AMEM_PROMPTS_HOT_RELOAD", true),
+			NoteConstruction: getEnvString("AMEM_NOTE_CONSTRUCTION_PROMPT", "note_construction"),
 		},
 		Monitoring: MonitoringConfig{
 			MetricsPort:   getEnvInt("AMEM_METRICS_PORT", 9090),
@@ -144,8 +201,49 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid server port: %d", c.Server.Port)
 	}
 
-	if c.ChromaDB.URL == "" {
-		return fmt.Errorf("ChromaDB URL is required")
+	if c.Server.MaxRequestSize != "" {
+		if _, err := ParseSize(c.Server.MaxRequestSize); err != nil {
+			return fmt.Errorf("invalid max request size: %w", err)
+		}
+	}
+
+	switch c.Storage.Backend {
+	case "", "chromadb":
+		if c.ChromaDB.URL == "" {
+			return fmt.Errorf("ChromaDB URL is required")
+		}
+	case "local":
+		if c.Storage.Path == "" {
+			return fmt.Errorf("storage path is required for the local backend")
+		}
+	default:
+		return fmt.Errorf("invalid storage backend: %s", c.Storage.Backend)
+	}
+
+	switch c.ChromaDB.DistanceMetric {
+	case "", "cosine", "l2", "ip":
+	default:
+		return fmt.Errorf("invalid distance metric: %s", c.ChromaDB.DistanceMetric)
+	}
+
+	switch c.Embedding.Service {
+	case "openai", "sentence-transformers", "ollama":
+	default:
+		return fmt.Errorf("invalid embedding service: %s", c.Embedding.Service)
+	}
+
+	if c.Embedding.Dimension < 0 {
+		return fmt.Errorf("embedding dimension must be non-negative")
+	}
+
+	switch c.Dedupe.Mode {
+	case "", "skip", "merge", "force":
+	default:
+		return fmt.Errorf("invalid dedupe mode: %s", c.Dedupe.Mode)
+	}
+
+	if c.Dedupe.Threshold < 0 || c.Dedupe.Threshold > 1 {
+		return fmt.Errorf("dedupe threshold must be between 0 and 1")
 	}
 
 	if c.LiteLLM.DefaultModel == "" {
@@ -159,6 +257,37 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// sizeUnits are the suffixes ParseSize accepts, longest first
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// ParseSize parses a byte size such as "10MB", "512KB" or "1048576". Units
+// are powers of 1024 and case-insensitive.
+func ParseSize(value string) (int64, error) {
+	number := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(number, unit.suffix) {
+			number = strings.TrimSpace(strings.TrimSuffix(number, unit.suffix))
+			multiplier = unit.bytes
+			break
+		}
+	}
+
+	size, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("%q is not a positive size such as 10MB", value)
+	}
+	return size * multiplier, nil
+}
+
 // Helper functions for environment variables
 func getEnvString(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -167,6 +296,17 @@ func getEnvString(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvList splits a comma-separated environment variable, nil if unset
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 func getEnvInt(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
 		if intValue, err := strconv.Atoi(value); err == nil {
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -105,11 +106,52 @@ func TestConfigValidation(t *testing.T) {
 		t.Error("Expected validation error for missing LiteLLM model")
 	}
 
-	// Test valid config
+	// Test unknown storage backend
 	cfg = &Config{
 		Server:   ServerConfig{Port: 8080},
+		Storage:  StorageConfig{Backend: "redis"},
 		ChromaDB: ChromaDBConfig{URL: "http://localhost:8000"},
-		LiteLLM:  LiteLLMConfig{DefaultModel: "gpt-4", MaxRetries: 3},
+		LiteLLM:  LiteLLMConfig{DefaultModel: "gpt-4"},
+	}
+
+	err = cfg.Validate()
+	if err == nil {
+		t.Error("Expected validation error for unknown storage backend")
+	}
+
+	// Test local backend without ChromaDB URL
+	cfg = &Config{
+		Server:    ServerConfig{Port: 8080},
+		Storage:   StorageConfig{Backend: "local", Path: "./data/memories.json"},
+		ChromaDB:  ChromaDBConfig{URL: ""},
+		LiteLLM:   LiteLLMConfig{DefaultModel: "gpt-4"},
+		Embedding: EmbeddingConfig{Service: "sentence-transformers"},
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		t.Errorf("Expected local backend to pass validation without ChromaDB URL, got error: %v", err)
+	}
+
+	// Test unknown embedding service
+	cfg = &Config{
+		Server:    ServerConfig{Port: 8080},
+		ChromaDB:  ChromaDBConfig{URL: "http://localhost:8000"},
+		LiteLLM:   LiteLLMConfig{DefaultModel: "gpt-4"},
+		Embedding: EmbeddingConfig{Service: "hash"},
+	}
+
+	err = cfg.Validate()
+	if err == nil {
+		t.Error("Expected validation error for unknown embedding service")
+	}
+
+	// Test valid config
+	cfg = &Config{
+		Server:    ServerConfig{Port: 8080},
+		ChromaDB:  ChromaDBConfig{URL: "http://localhost:8000"},
+		LiteLLM:   LiteLLMConfig{DefaultModel: "gpt-4", MaxRetries: 3},
+		Embedding: EmbeddingConfig{Service: "sentence-transformers"},
 	}
 
 	err = cfg.Validate()
@@ -161,3 +203,34 @@ func TestGetEnvHelpers(t *testing.T) {
 		t.Errorf("Expected false, got %v", boolValue)
 	}
 }
+
+func TestParseSize(t *testing.T) {
+	for value, expected := range map[string]int64{
+		"10MB":    10 << 20,
+		"512kb":   512 << 10,
+		"1 GB":    1 << 30,
+		"2048":    2048,
+		"100B":    100,
+		" 64MB  ": 64 << 20,
+	} {
+		if size, err := ParseSize(value); err != nil || size != expected {
+			t.Errorf("ParseSize(%q): expected %d, got %d, %v", value, expected, size, err)
+		}
+	}
+
+	for _, value := range []string{"", "MB", "ten MB", "-1MB", "0"} {
+		if _, err := ParseSize(value); err == nil {
+			t.Errorf("ParseSize(%q): expected an error", value)
+		}
+	}
+
+	cfg := &Config{
+		Server:    ServerConfig{Port: 8080, MaxRequestSize: "lots"},
+		ChromaDB:  ChromaDBConfig{URL: "http://localhost:8000"},
+		Embedding: EmbeddingConfig{Service: "openai"},
+		LiteLLM:   LiteLLMConfig{DefaultModel: "gpt-4"},
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "max request size") {
+		t.Errorf("Expected an invalid max request size to be rejected, got %v", err)
+	}
+}
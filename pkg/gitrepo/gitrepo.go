@@ -0,0 +1,301 @@
+// Package gitrepo reads the identity of a git repository, its remote, branch
+// and commit, straight from the .git directory without running git
+package gitrepo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Repository describes the git repository enclosing a path
+type Repository struct {
+	Root       string // Main working tree, shared by all worktrees of the repository
+	WorkTree   string // Working tree holding the path, differs from Root in a linked worktree
+	RemoteName string // Remote the identity is taken from, empty without remotes
+	RemoteURL  string
+	Branch     string // Empty when HEAD is detached
+	Commit     string // Empty before the first commit
+}
+
+// ID returns the canonical remote of the repository, such as
+// "github.com/org/repo", or its main working tree when it has no usable remote
+func (r *Repository) ID() string {
+	if remote, ok := CanonicalRemote(r.RemoteURL); ok {
+		return remote
+	}
+	return r.Root
+}
+
+// Find returns the git repository enclosing path, or nil if there is none.
+// Linked worktrees and submodules, whose .git is a file pointing at the real
+// git directory, are followed.
+func Find(path string) (*Repository, error) {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+
+	for {
+		gitDir, err := resolveGitDir(filepath.Join(dir, ".git"))
+		if err != nil {
+			return nil, err
+		}
+		if gitDir != "" {
+			return open(dir, gitDir)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// resolveGitDir returns the git directory a .git entry stands for, or "" if
+// the entry does not exist
+func resolveGitDir(dotGit string) (string, error) {
+	info, err := os.Stat(dotGit)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return dotGit, nil
+	}
+
+	// A worktree or submodule: ".git" holds "gitdir: <path>"
+	data, err := os.ReadFile(dotGit)
+	if err != nil {
+		return "", err
+	}
+	line := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(line, "gitdir:") {
+		return "", fmt.Errorf("%s is not a git directory reference", dotGit)
+	}
+	return resolvePath(filepath.Dir(dotGit), strings.TrimSpace(strings.TrimPrefix(line, "gitdir:"))), nil
+}
+
+// open reads the repository whose working tree is workTree
+func open(workTree, gitDir string) (*Repository, error) {
+	// Linked worktrees keep config and refs in the main git directory
+	commonDir := gitDir
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir = resolvePath(gitDir, strings.TrimSpace(string(data)))
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	repo := &Repository{Root: workTree, WorkTree: workTree}
+	if filepath.Base(commonDir) == ".git" {
+		repo.Root = filepath.Dir(commonDir)
+	}
+
+	remotes, err := readRemotes(filepath.Join(commonDir, "config"))
+	if err != nil {
+		return nil, err
+	}
+	repo.RemoteName, repo.RemoteURL = pickRemote(remotes)
+
+	if err := readHead(repo, gitDir, commonDir); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// remote is a [remote "name"] section of a git config
+type remote struct {
+	name string
+	url  string
+}
+
+// readRemotes returns the remotes of a git config in file order
+func readRemotes(path string) ([]remote, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var remotes []remote
+	current := -1
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if line[0] == '[' {
+			current = -1
+			if name, ok := remoteSection(line); ok {
+				remotes = append(remotes, remote{name: name})
+				current = len(remotes) - 1
+			}
+			continue
+		}
+
+		if current < 0 {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if ok && strings.EqualFold(strings.TrimSpace(key), "url") && remotes[current].url == "" {
+			remotes[current].url = strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return remotes, nil
+}
+
+// remoteSection returns the remote name of a `[remote "name"]` header
+func remoteSection(header string) (string, bool) {
+	header = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(header, "["), "]"))
+	section, subsection, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(section, "remote") {
+		return "", false
+	}
+	return strings.Trim(strings.TrimSpace(subsection), `"`), true
+}
+
+// pickRemote prefers origin, then the first remote with a URL
+func pickRemote(remotes []remote) (string, string) {
+	for _, r := range remotes {
+		if r.name == "origin" && r.url != "" {
+			return r.name, r.url
+		}
+	}
+	for _, r := range remotes {
+		if r.url != "" {
+			return r.name, r.url
+		}
+	}
+	return "", ""
+}
+
+// readHead fills in the branch and commit checked out in a working tree
+func readHead(repo *Repository, gitDir, commonDir string) error {
+	data, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	head := strings.TrimSpace(string(data))
+	ref, symbolic := strings.CutPrefix(head, "ref:")
+	if !symbolic {
+		repo.Commit = head
+		return nil
+	}
+
+	ref = strings.TrimSpace(ref)
+	repo.Branch = strings.TrimPrefix(ref, "refs/heads/")
+	repo.Commit, err = resolveRef(ref, gitDir, commonDir)
+	return err
+}
+
+// resolveRef returns the commit a ref points to, looking at loose refs before
+// packed ones. An unborn branch resolves to "".
+func resolveRef(ref, gitDir, commonDir string) (string, error) {
+	for _, dir := range []string{gitDir, commonDir} {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(ref)))
+		if err == nil {
+			return strings.TrimSpace(string(data)), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+	}
+
+	file, err := os.Open(filepath.Join(commonDir, "packed-refs"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == '#' || line[0] == '^' {
+			continue
+		}
+		if commit, name, ok := strings.Cut(line, " "); ok && name == ref {
+			return commit, nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+// resolvePath resolves a path read from a git file relative to its directory
+func resolvePath(base, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(base, path)
+}
+
+// CanonicalRemote reduces a remote URL to host and path, so that the HTTPS,
+// SSH and scp-style URLs of a repository agree: "git@github.com:org/repo.git"
+// and "https://github.com/org/repo" both become "github.com/org/repo". Remotes
+// on the local filesystem have no canonical form.
+func CanonicalRemote(remoteURL string) (string, bool) {
+	remoteURL = strings.TrimSpace(remoteURL)
+	if remoteURL == "" {
+		return "", false
+	}
+
+	var host, path string
+	if strings.Contains(remoteURL, "://") {
+		u, err := url.Parse(remoteURL)
+		if err != nil || u.Scheme == "file" {
+			return "", false
+		}
+		host, path = u.Hostname(), u.Path
+	} else {
+		// scp-style [user@]host:path; a slash before the colon or a drive
+		// letter makes it a local path
+		colon := strings.Index(remoteURL, ":")
+		if colon <= 1 || strings.Contains(remoteURL[:colon], "/") {
+			return "", false
+		}
+		host, path = remoteURL[:colon], remoteURL[colon+1:]
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	path = strings.Trim(path, "/")
+	if host == "" || path == "" {
+		return "", false
+	}
+
+	return strings.ToLower(host) + "/" + path, true
+}
@@ -0,0 +1,206 @@
+package ingest
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// span is a range of lines, 0-based and end-exclusive
+type span struct {
+	start, end int
+}
+
+// newChunk builds the chunk for a span of a file's lines
+func newChunk(path, language string, lines []string, s span, symbol string) Chunk {
+	return Chunk{
+		Path:      path,
+		Language:  language,
+		StartLine: s.start + 1,
+		EndLine:   s.end,
+		Symbol:    symbol,
+		Content:   strings.Join(lines[s.start:s.end], "\n"),
+	}
+}
+
+// splitLines splits content into lines, without a trailing empty line
+func splitLines(content string) []string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// textSpans splits lines at blank lines followed by unindented code, which
+// in most languages starts a new top-level definition or section. Spans are
+// at least a quarter of maxLines where possible and never longer than maxLines.
+func textSpans(lines []string, maxLines int) []span {
+	minLines := maxLines / 4
+	if minLines < 1 {
+		minLines = 1
+	}
+
+	var spans []span
+	start := 0
+	for i := 1; i <= len(lines); i++ {
+		atEnd := i == len(lines)
+		size := i - start
+		boundary := !atEnd && size >= minLines && isBlank(lines[i-1]) && startsDefinition(lines[i])
+		if atEnd || boundary || size >= maxLines {
+			if s, ok := trimSpan(lines, span{start, i}); ok {
+				spans = append(spans, s)
+			}
+			start = i
+		}
+	}
+	return spans
+}
+
+// startsDefinition reports whether a line is unindented code rather than the
+// closing bracket of the previous block
+func startsDefinition(line string) bool {
+	if line == "" || line[0] == ' ' || line[0] == '\t' {
+		return false
+	}
+	return !strings.ContainsAny(line[:1], "})]")
+}
+
+// trimSpan drops blank lines from both ends of a span, reporting false if
+// nothing is left
+func trimSpan(lines []string, s span) (span, bool) {
+	for s.start < s.end && isBlank(lines[s.start]) {
+		s.start++
+	}
+	for s.end > s.start && isBlank(lines[s.end-1]) {
+		s.end--
+	}
+	return s, s.start < s.end
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// chunkGo splits a Go file at its top-level declarations. Each function is a
+// chunk of its own; consecutive type, const and var declarations are grouped
+// up to maxLines. Doc comments stay with their declaration, imports are left
+// out, and declarations longer than maxLines are split into parts.
+func chunkGo(path string, content []byte, maxLines int) ([]Chunk, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, content, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := splitLines(string(content))
+	var chunks []Chunk
+
+	addSpan := func(s span, symbol string) {
+		if s.end-s.start <= maxLines {
+			chunks = append(chunks, newChunk(path, "go", lines, s, symbol))
+			return
+		}
+		part := 1
+		for start := s.start; start < s.end; start += maxLines {
+			end := start + maxLines
+			if end > s.end {
+				end = s.end
+			}
+			chunks = append(chunks, newChunk(path, "go", lines, span{start, end}, fmt.Sprintf("%s (part %d)", symbol, part)))
+			part++
+		}
+	}
+
+	// Pending group of type, const and var declarations
+	var group span
+	var groupSymbols []string
+	flush := func() {
+		if len(groupSymbols) > 0 {
+			addSpan(group, strings.Join(groupSymbols, ", "))
+			groupSymbols = nil
+		}
+	}
+
+	for _, decl := range file.Decls {
+		start := decl.Pos()
+		var symbol string
+
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+			symbol = funcSymbol(d)
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+			symbol = genDeclSymbol(d)
+		}
+
+		s := span{fset.Position(start).Line - 1, fset.Position(decl.End()).Line}
+
+		if _, isFunc := decl.(*ast.FuncDecl); isFunc {
+			flush()
+			addSpan(s, symbol)
+			continue
+		}
+
+		if len(groupSymbols) > 0 && s.end-group.start > maxLines {
+			flush()
+		}
+		if len(groupSymbols) == 0 {
+			group.start = s.start
+		}
+		group.end = s.end
+		groupSymbols = append(groupSymbols, symbol)
+	}
+	flush()
+
+	return chunks, nil
+}
+
+// funcSymbol names a function, qualifying methods with their receiver type
+func funcSymbol(decl *ast.FuncDecl) string {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return decl.Name.Name
+	}
+
+	receiver := decl.Recv.List[0].Type
+	if star, ok := receiver.(*ast.StarExpr); ok {
+		receiver = star.X
+	}
+	if index, ok := receiver.(*ast.IndexExpr); ok {
+		receiver = index.X
+	}
+	if index, ok := receiver.(*ast.IndexListExpr); ok {
+		receiver = index.X
+	}
+	if ident, ok := receiver.(*ast.Ident); ok {
+		return ident.Name + "." + decl.Name.Name
+	}
+	return decl.Name.Name
+}
+
+// genDeclSymbol names the types, constants or variables a declaration holds
+func genDeclSymbol(decl *ast.GenDecl) string {
+	var names []string
+	for _, spec := range decl.Specs {
+		switch s := spec.(type) {
+		case *ast.TypeSpec:
+			names = append(names, s.Name.Name)
+		case *ast.ValueSpec:
+			for _, name := range s.Names {
+				names = append(names, name.Name)
+			}
+		}
+	}
+
+	if len(names) > 3 {
+		names = append(names[:3], "...")
+	}
+	return strings.Join(names, ", ")
+}
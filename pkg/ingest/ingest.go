@@ -0,0 +1,208 @@
+// Package ingest walks a source tree and splits its files into chunks that can
+// be stored as memories
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Defaults used when Options fields are zero
+const (
+	DefaultMaxChunkLines = 80
+	DefaultMaxFileBytes  = 1 << 20
+)
+
+// Options controls which files are read and how they are chunked
+type Options struct {
+	MaxChunkLines int   // Longest chunk, in lines, before it is split
+	MaxFileBytes  int64 // Larger files are skipped
+}
+
+// Chunk is a contiguous range of lines from one file
+type Chunk struct {
+	Path      string // Relative to the walked root, with forward slashes
+	Language  string
+	StartLine int // 1-based, inclusive
+	EndLine   int
+	Symbol    string // Declarations the chunk holds, when the language is parsed
+	Content   string
+}
+
+// Result is the outcome of walking a source tree
+type Result struct {
+	Chunks  []Chunk
+	Files   int // Files chunked
+	Ignored int // Files excluded by .gitignore
+	Skipped int // Files of unknown type, binary or too large
+}
+
+// Walk reads every source file under root that .gitignore files do not
+// exclude and splits it into chunks. Go files are split at declarations;
+// other files at blank lines before unindented code.
+func Walk(ctx context.Context, root string, opts Options) (*Result, error) {
+	if opts.MaxChunkLines <= 0 {
+		opts.MaxChunkLines = DefaultMaxChunkLines
+	}
+	if opts.MaxFileBytes <= 0 {
+		opts.MaxFileBytes = DefaultMaxFileBytes
+	}
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	result := &Result{}
+	ignore := &ignoreMatcher{}
+
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if entry.IsDir() {
+			if rel == "." {
+				return ignore.load(path, "")
+			}
+			if entry.Name() == ".git" || ignore.ignored(rel, true) {
+				return filepath.SkipDir
+			}
+			return ignore.load(path, rel)
+		}
+
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		if ignore.ignored(rel, false) {
+			result.Ignored++
+			return nil
+		}
+
+		language := Language(rel)
+		if language == "" {
+			result.Skipped++
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() > opts.MaxFileBytes {
+			result.Skipped++
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if isBinary(content) {
+			result.Skipped++
+			return nil
+		}
+
+		chunks := ChunkFile(rel, language, content, opts.MaxChunkLines)
+		if len(chunks) > 0 {
+			result.Files++
+			result.Chunks = append(result.Chunks, chunks...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ChunkFile splits a file's content into chunks of at most maxLines lines
+func ChunkFile(path, language string, content []byte, maxLines int) []Chunk {
+	if language == "go" {
+		if chunks, err := chunkGo(path, content, maxLines); err == nil {
+			return chunks
+		}
+		// Files that do not parse are still worth ingesting
+	}
+
+	lines := splitLines(string(content))
+	var chunks []Chunk
+	for _, span := range textSpans(lines, maxLines) {
+		chunks = append(chunks, newChunk(path, language, lines, span, ""))
+	}
+	return chunks
+}
+
+// languages maps file extensions to the code types memories are stored with
+var languages = map[string]string{
+	".go":    "go",
+	".py":    "python",
+	".js":    "javascript",
+	".jsx":   "javascript",
+	".mjs":   "javascript",
+	".ts":    "typescript",
+	".tsx":   "typescript",
+	".java":  "java",
+	".kt":    "kotlin",
+	".scala": "scala",
+	".rb":    "ruby",
+	".rs":    "rust",
+	".c":     "c",
+	".h":     "c",
+	".cc":    "cpp",
+	".cpp":   "cpp",
+	".hpp":   "cpp",
+	".cs":    "csharp",
+	".php":   "php",
+	".swift": "swift",
+	".lua":   "lua",
+	".ex":    "elixir",
+	".exs":   "elixir",
+	".sh":    "shell",
+	".bash":  "shell",
+	".sql":   "sql",
+	".proto": "protobuf",
+	".tf":    "terraform",
+	".yaml":  "yaml",
+	".yml":   "yaml",
+	".toml":  "toml",
+	".md":    "markdown",
+}
+
+// Language returns the code type of a file from its name, or "" for files
+// that are not ingested
+func Language(path string) string {
+	switch strings.ToLower(filepath.Base(path)) {
+	case "dockerfile":
+		return "dockerfile"
+	case "makefile":
+		return "makefile"
+	}
+	return languages[strings.ToLower(filepath.Ext(path))]
+}
+
+// isBinary reports whether content looks binary, judging by NUL bytes near the start
+func isBinary(content []byte) bool {
+	if len(content) > 8000 {
+		content = content[:8000]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
@@ -0,0 +1,123 @@
+package ingest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestWalkRespectsGitignore(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		".gitignore":           "*.log\nbuild/\n/secret.py\n!keep.log\n",
+		"main.go":              "package main\n\nfunc main() {}\n",
+		"debug.log":            "noise\n",
+		"keep.log":             "kept but not source\n",
+		"secret.py":            "token = 1\n",
+		"build/out.go":         "package build\n",
+		"lib/secret.py":        "print('only the root secret.py is ignored')\n",
+		"lib/.gitignore":       "generated_*.ts\n",
+		"lib/generated_a.ts":   "export const a = 1\n",
+		"lib/handwritten.ts":   "export const b = 2\n",
+		"other/generated_b.ts": "export const c = 3\n",
+		"image.png":            "\x89PNG\x00",
+		".git/config":          "[core]\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := Walk(context.Background(), root, Options{})
+	if err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+
+	var paths []string
+	for _, chunk := range result.Chunks {
+		paths = append(paths, chunk.Path)
+	}
+	sort.Strings(paths)
+
+	expected := []string{"lib/handwritten.ts", "lib/secret.py", "main.go", "other/generated_b.ts"}
+	if strings.Join(paths, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected chunks from %v, got %v", expected, paths)
+	}
+	if result.Ignored != 3 {
+		t.Errorf("Expected debug.log, secret.py and lib/generated_a.ts to be ignored, got %d", result.Ignored)
+	}
+}
+
+func TestChunkGoSplitsAtDeclarations(t *testing.T) {
+	source := `package store
+
+import "fmt"
+
+// Limit caps results
+const Limit = 10
+
+type Store struct {
+	items []string
+}
+
+// Add appends an item
+func (s *Store) Add(item string) {
+	s.items = append(s.items, item)
+}
+
+func describe(s *Store) string {
+	return fmt.Sprint(len(s.items))
+}
+`
+	chunks := ChunkFile("store/store.go", "go", []byte(source), DefaultMaxChunkLines)
+
+	type summary struct {
+		symbol     string
+		start, end int
+	}
+	var got []summary
+	for _, chunk := range chunks {
+		got = append(got, summary{chunk.Symbol, chunk.StartLine, chunk.EndLine})
+	}
+
+	expected := []summary{
+		{"Limit, Store", 5, 10},
+		{"Store.Add", 12, 15},
+		{"describe", 17, 19},
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Chunk %d: expected %v, got %v", i, expected[i], got[i])
+		}
+	}
+
+	if !strings.HasPrefix(chunks[1].Content, "// Add appends an item\nfunc (s *Store) Add") {
+		t.Errorf("Expected the doc comment to stay with its function, got %q", chunks[1].Content)
+	}
+
+	// Files that do not parse fall back to splitting at blank lines
+	broken := ChunkFile("broken.go", "go", []byte("package broken\n\nfunc {\n"), DefaultMaxChunkLines)
+	if len(broken) != 1 || broken[0].StartLine != 1 || broken[0].EndLine != 3 {
+		t.Errorf("Expected one chunk for the unparsable file, got %+v", broken)
+	}
+}
+
+func TestTextSpansBreakAtUnindentedCode(t *testing.T) {
+	lines := splitLines("def a():\n    return 1\n\n\ndef b():\n    return 2\n\n    # still b\n")
+
+	spans := textSpans(lines, 4)
+	if len(spans) != 2 || spans[0] != (span{0, 2}) || spans[1] != (span{4, 8}) {
+		t.Errorf("Expected a and b as separate spans, got %v", spans)
+	}
+}
@@ -0,0 +1,449 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amem/mcp-server/pkg/models"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// sessionHeader carries the session ID on every request after initialize
+const sessionHeader = "Mcp-Session-Id"
+
+// Limits for the HTTP transport
+const (
+	sessionIdleTTL    = 30 * time.Minute
+	sessionEventQueue = 64
+	maxHTTPSessions   = 256
+)
+
+// httpSession is a session of the streamable HTTP transport
+type httpSession struct {
+	*session
+	lastSeen time.Time
+	stream   chan []byte // Open SSE stream for server-initiated messages, nil if none
+}
+
+// httpTransport serves the MCP streamable HTTP transport: clients POST
+// JSON-RPC messages, GET an SSE stream for server-initiated messages and
+// DELETE their session when done
+type httpTransport struct {
+	server      *Server
+	logger      *zap.Logger
+	authToken   string // Bearer token every request must carry, none if empty
+	maxSessions int    // Sessions open at once before initialize is refused, no limit if zero
+	maxBodySize int64  // Largest request body accepted, no limit if zero
+	mu          sync.Mutex
+	sessions    map[string]*httpSession
+}
+
+// StartHTTP serves the streamable HTTP transport on addr at /mcp until ctx is
+// done. Requests must carry authToken as a bearer token if it is set, and it
+// must be set unless addr is a loopback address.
+func (s *Server) StartHTTP(ctx context.Context, addr, authToken string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid listen address %q: %w", addr, err)
+	}
+	if authToken == "" && !isLoopbackHost(host) {
+		return fmt.Errorf("an auth token is required to listen on %s, set server.auth_token or AMEM_AUTH_TOKEN", addr)
+	}
+
+	transport := &httpTransport{
+		server:      s,
+		logger:      s.logger.Named("http"),
+		authToken:   authToken,
+		maxSessions: maxHTTPSessions,
+		maxBodySize: s.maxRequestSize,
+		sessions:    make(map[string]*httpSession),
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/mcp", transport)
+
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go transport.expireSessions(ctx)
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	s.logger.Info("Starting MCP server",
+		zap.String("transport", "http"),
+		zap.String("addr", addr),
+		zap.Bool("auth", authToken != ""))
+
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// ServeHTTP dispatches MCP endpoint requests by method
+func (t *httpTransport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !allowedOrigin(r) {
+		http.Error(w, "Origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	if !t.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="mcp"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		t.handlePost(w, r)
+	case http.MethodGet:
+		t.handleStream(w, r)
+	case http.MethodDelete:
+		t.handleDelete(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handlePost handles one JSON-RPC message or a batch of them
+func (t *httpTransport) handlePost(w http.ResponseWriter, r *http.Request) {
+	if t.maxBodySize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, t.maxBodySize)
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	messages, batch, err := splitBatch(body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse(nil, models.ParseError, "Invalid JSON", nil))
+		return
+	}
+
+	var sess *httpSession
+	if isInitializeRequest(messages) {
+		if sess = t.newSession(); sess == nil {
+			w.Header().Set("Retry-After", "60")
+			http.Error(w, "Too many open sessions", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set(sessionHeader, sess.id)
+	} else if sess = t.lookupSession(w, r); sess == nil {
+		return
+	}
+
+	responses := make([]interface{}, 0, len(messages))
+	for _, message := range messages {
+		if response := t.server.handleMessage(r.Context(), sess.session, message); response != nil {
+			responses = append(responses, response)
+		}
+	}
+
+	// Notifications and responses from the client need no reply
+	if len(responses) == 0 {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	if batch {
+		writeJSON(w, http.StatusOK, responses)
+		return
+	}
+	writeJSON(w, http.StatusOK, responses[0])
+}
+
+// handleStream opens an SSE stream for server-initiated messages
+func (t *httpTransport) handleStream(w http.ResponseWriter, r *http.Request) {
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		http.Error(w, "Accept must include text/event-stream", http.StatusNotAcceptable)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	sess := t.lookupSession(w, r)
+	if sess == nil {
+		return
+	}
+
+	stream := make(chan []byte, sessionEventQueue)
+	t.mu.Lock()
+	if sess.stream != nil {
+		t.mu.Unlock()
+		http.Error(w, "Session already has an open stream", http.StatusConflict)
+		return
+	}
+	sess.stream = stream
+	t.mu.Unlock()
+
+	defer func() {
+		t.mu.Lock()
+		if sess.stream == stream {
+			sess.stream = nil
+		}
+		sess.lastSeen = time.Now()
+		t.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	t.logger.Debug("SSE stream opened", zap.String("session", sess.id))
+
+	keepAlive := time.NewTicker(30 * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			t.logger.Debug("SSE stream closed", zap.String("session", sess.id))
+			return
+		case data, ok := <-stream:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+			flusher.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// handleDelete ends a session
+func (t *httpTransport) handleDelete(w http.ResponseWriter, r *http.Request) {
+	sess := t.lookupSession(w, r)
+	if sess == nil {
+		return
+	}
+
+	t.closeSession(sess.id)
+	w.WriteHeader(http.StatusOK)
+}
+
+// newSession creates and registers a session, returning nil if the maximum
+// number of sessions is already open
+func (t *httpTransport) newSession() *httpSession {
+	sess := &httpSession{
+		session:  &session{id: uuid.New().String()},
+		lastSeen: time.Now(),
+	}
+	sess.notify = func(message interface{}) error {
+		return t.push(sess, message)
+	}
+
+	t.mu.Lock()
+	if t.maxSessions > 0 && len(t.sessions) >= t.maxSessions {
+		t.mu.Unlock()
+		t.logger.Warn("Refused session, too many open", zap.Int("max_sessions", t.maxSessions))
+		return nil
+	}
+	t.sessions[sess.id] = sess
+	t.mu.Unlock()
+	t.server.addSession(sess.session)
+
+	t.logger.Info("Session started", zap.String("session", sess.id))
+	return sess
+}
+
+// lookupSession finds the session named in the request header, writing an
+// error response and returning nil if there is none
+func (t *httpTransport) lookupSession(w http.ResponseWriter, r *http.Request) *httpSession {
+	id := r.Header.Get(sessionHeader)
+	if id == "" {
+		http.Error(w, "Missing "+sessionHeader+" header", http.StatusBadRequest)
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sess, ok := t.sessions[id]
+	if !ok {
+		// 404 tells the client to start a new session
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return nil
+	}
+
+	sess.lastSeen = time.Now()
+	return sess
+}
+
+// closeSession removes a session and closes its stream
+func (t *httpTransport) closeSession(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sess, ok := t.sessions[id]
+	if !ok {
+		return
+	}
+
+	if sess.stream != nil {
+		close(sess.stream)
+		sess.stream = nil
+	}
+	delete(t.sessions, id)
+	t.server.removeSession(sess.session)
+
+	t.logger.Info("Session ended", zap.String("session", id))
+}
+
+// push queues a server-initiated message on the session's SSE stream
+func (t *httpTransport) push(sess *httpSession, message interface{}) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if sess.stream == nil {
+		return fmt.Errorf("session %s has no open stream", sess.id)
+	}
+
+	select {
+	case sess.stream <- data:
+		return nil
+	default:
+		return fmt.Errorf("session %s stream is full", sess.id)
+	}
+}
+
+// expireSessions drops sessions that have been idle without a stream for too long
+func (t *httpTransport) expireSessions(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			var expired []string
+			t.mu.Lock()
+			for id, sess := range t.sessions {
+				if sess.stream == nil && time.Since(sess.lastSeen) > sessionIdleTTL {
+					expired = append(expired, id)
+				}
+			}
+			t.mu.Unlock()
+
+			for _, id := range expired {
+				t.closeSession(id)
+			}
+		}
+	}
+}
+
+// splitBatch splits a request body into its JSON-RPC messages, reporting
+// whether the body was a batch
+func splitBatch(body []byte) ([]json.RawMessage, bool, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var messages []json.RawMessage
+		if err := json.Unmarshal(trimmed, &messages); err != nil {
+			return nil, true, err
+		}
+		if len(messages) == 0 {
+			return nil, true, fmt.Errorf("empty batch")
+		}
+		return messages, true, nil
+	}
+
+	if !json.Valid(trimmed) {
+		return nil, false, fmt.Errorf("invalid JSON")
+	}
+	return []json.RawMessage{trimmed}, false, nil
+}
+
+// isInitializeRequest reports whether a POST starts a new session
+func isInitializeRequest(messages []json.RawMessage) bool {
+	for _, message := range messages {
+		var request models.MCPRequest
+		if json.Unmarshal(message, &request) == nil && request.Method == models.MethodInitialize {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedOrigin rejects browser requests from non-local origins, which guards
+// a locally bound server against DNS rebinding
+func allowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	parsed, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return isLoopbackHost(parsed.Hostname())
+}
+
+// authorized reports whether a request carries the transport's bearer token
+func (t *httpTransport) authorized(r *http.Request) bool {
+	if t.authToken == "" {
+		return true
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(t.authToken)) == 1
+}
+
+// isLoopbackHost reports whether a host name or address only reaches this machine
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// writeJSON writes a JSON response body
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
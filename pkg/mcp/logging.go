@@ -0,0 +1,65 @@
+package mcp
+
+import "fmt"
+
+// elidedArguments are tool arguments that carry memory content or export
+// payloads. They are replaced by their size wherever arguments are logged.
+var elidedArguments = map[string]bool{
+	"content": true,
+	"data":    true,
+}
+
+// argumentSizes describes tool arguments without their values, as the size of
+// each argument by name: the length of strings, arrays and objects, and 1 for
+// other values
+func argumentSizes(args map[string]interface{}) map[string]int {
+	sizes := make(map[string]int, len(args))
+	for key, value := range args {
+		sizes[key] = valueSize(value)
+	}
+	return sizes
+}
+
+// valueSize returns the length of a string, array or object, and 1 otherwise
+func valueSize(value interface{}) int {
+	switch v := value.(type) {
+	case string:
+		return len(v)
+	case []interface{}:
+		return len(v)
+	case map[string]interface{}:
+		return len(v)
+	default:
+		return 1
+	}
+}
+
+// elideArguments copies tool arguments for logging, replacing the content and
+// data fields at any depth with their size
+func elideArguments(args map[string]interface{}) map[string]interface{} {
+	elided := make(map[string]interface{}, len(args))
+	for key, value := range args {
+		if elidedArguments[key] {
+			elided[key] = fmt.Sprintf("<%d elided>", valueSize(value))
+			continue
+		}
+		elided[key] = elideValue(value)
+	}
+	return elided
+}
+
+// elideValue elides the fields of objects nested in a value
+func elideValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return elideArguments(v)
+	case []interface{}:
+		elided := make([]interface{}, len(v))
+		for i, item := range v {
+			elided[i] = elideValue(item)
+		}
+		return elided
+	default:
+		return value
+	}
+}
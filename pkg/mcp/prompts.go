@@ -0,0 +1,72 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/amem/mcp-server/pkg/models"
+)
+
+// PromptProvider serves the prompts capability
+type PromptProvider interface {
+	// ListPromptDefinitions returns the prompts clients can request
+	ListPromptDefinitions(ctx context.Context) ([]models.MCPPrompt, error)
+
+	// GetPromptMessages renders a prompt, returning an error wrapping
+	// models.ErrPromptNotFound or models.ErrMissingPromptArguments for
+	// invalid requests
+	GetPromptMessages(ctx context.Context, name string, args map[string]string) (*models.MCPGetPromptResult, error)
+}
+
+// SetPromptProvider enables the prompts capability
+func (s *Server) SetPromptProvider(provider PromptProvider) {
+	s.prompts = provider
+}
+
+// handlePromptRequest handles the prompts/* methods
+func (s *Server) handlePromptRequest(ctx context.Context, request models.MCPRequest) (interface{}, *models.MCPError) {
+	if s.prompts == nil {
+		return nil, &models.MCPError{
+			Code:    models.MethodNotFound,
+			Message: fmt.Sprintf("Method not found: %s", request.Method),
+		}
+	}
+
+	if request.Method == models.MethodListPrompts {
+		prompts, err := s.prompts.ListPromptDefinitions(ctx)
+		if err != nil {
+			return nil, &models.MCPError{Code: models.InternalError, Message: err.Error()}
+		}
+		return map[string]interface{}{"prompts": prompts}, nil
+	}
+
+	params, _ := request.Params.(map[string]interface{})
+	name, ok := params["name"].(string)
+	if !ok || name == "" {
+		return nil, &models.MCPError{Code: models.InvalidParams, Message: "Prompt name required"}
+	}
+
+	// Prompt arguments are strings; other JSON values are rejected
+	args := make(map[string]string)
+	rawArgs, _ := params["arguments"].(map[string]interface{})
+	for key, value := range rawArgs {
+		str, ok := value.(string)
+		if !ok {
+			return nil, &models.MCPError{
+				Code:    models.InvalidParams,
+				Message: fmt.Sprintf("Prompt argument %s must be a string", key),
+			}
+		}
+		args[key] = str
+	}
+
+	result, err := s.prompts.GetPromptMessages(ctx, name, args)
+	if errors.Is(err, models.ErrPromptNotFound) || errors.Is(err, models.ErrMissingPromptArguments) {
+		return nil, &models.MCPError{Code: models.InvalidParams, Message: err.Error()}
+	}
+	if err != nil {
+		return nil, &models.MCPError{Code: models.InternalError, Message: err.Error()}
+	}
+	return result, nil
+}
@@ -0,0 +1,126 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/amem/mcp-server/pkg/models"
+	"go.uber.org/zap"
+)
+
+// ResourceProvider serves the resources capability
+type ResourceProvider interface {
+	// ListResources returns the resources clients can browse
+	ListResources(ctx context.Context) ([]models.MCPResource, error)
+
+	// ResourceTemplates returns URI templates for resources that are not listed
+	ResourceTemplates() []models.MCPResourceTemplate
+
+	// ReadResource returns the contents of a resource, or an error wrapping
+	// models.ErrResourceNotFound for unknown URIs
+	ReadResource(ctx context.Context, uri string) ([]models.MCPResourceContents, error)
+}
+
+// SetResourceProvider enables the resources capability
+func (s *Server) SetResourceProvider(provider ResourceProvider) {
+	s.resources = provider
+}
+
+// NotifyResourceUpdated sends notifications/resources/updated to every
+// session subscribed to the URI
+func (s *Server) NotifyResourceUpdated(uri string) {
+	notification := models.MCPNotification{
+		JSONRPC: "2.0",
+		Method:  models.MethodResourceUpdated,
+		Params:  map[string]interface{}{"uri": uri},
+	}
+
+	for _, sess := range s.activeSessions() {
+		if !sess.isSubscribed(uri) {
+			continue
+		}
+
+		if err := sess.notify(notification); err != nil {
+			s.logger.Debug("Failed to deliver resource update",
+				zap.String("session", sess.id),
+				zap.String("uri", uri),
+				zap.Error(err))
+		}
+	}
+}
+
+// handleResourceRequest handles the resources/* methods
+func (s *Server) handleResourceRequest(ctx context.Context, sess *session, request models.MCPRequest) (interface{}, *models.MCPError) {
+	if s.resources == nil {
+		return nil, &models.MCPError{
+			Code:    models.MethodNotFound,
+			Message: fmt.Sprintf("Method not found: %s", request.Method),
+		}
+	}
+
+	switch request.Method {
+	case models.MethodListResources:
+		resources, err := s.resources.ListResources(ctx)
+		if err != nil {
+			return nil, &models.MCPError{Code: models.InternalError, Message: err.Error()}
+		}
+		return map[string]interface{}{"resources": resources}, nil
+
+	case models.MethodListResourceTemplates:
+		return map[string]interface{}{"resourceTemplates": s.resources.ResourceTemplates()}, nil
+	}
+
+	params, _ := request.Params.(map[string]interface{})
+	uri, ok := params["uri"].(string)
+	if !ok || uri == "" {
+		return nil, &models.MCPError{Code: models.InvalidParams, Message: "Resource uri required"}
+	}
+
+	switch request.Method {
+	case models.MethodReadResource:
+		contents, err := s.resources.ReadResource(ctx, uri)
+		if errors.Is(err, models.ErrResourceNotFound) {
+			return nil, &models.MCPError{
+				Code:    models.ResourceNotFound,
+				Message: "Resource not found",
+				Data:    map[string]interface{}{"uri": uri},
+			}
+		}
+		if err != nil {
+			return nil, &models.MCPError{Code: models.InternalError, Message: err.Error()}
+		}
+		return map[string]interface{}{"contents": contents}, nil
+
+	case models.MethodSubscribeResource:
+		sess.subscribe(uri, true)
+		return map[string]interface{}{}, nil
+
+	default: // models.MethodUnsubscribeResource
+		sess.subscribe(uri, false)
+		return map[string]interface{}{}, nil
+	}
+}
+
+// subscribe adds or removes a resource subscription
+func (sess *session) subscribe(uri string, subscribed bool) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	if !subscribed {
+		delete(sess.subscriptions, uri)
+		return
+	}
+
+	if sess.subscriptions == nil {
+		sess.subscriptions = make(map[string]bool)
+	}
+	sess.subscriptions[uri] = true
+}
+
+// isSubscribed reports whether the session is subscribed to a resource
+func (sess *session) isSubscribed(uri string) bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.subscriptions[uri]
+}
@@ -1,24 +1,29 @@
 package mcp
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"os"
+	"strings"
+	"sync"
 
 	"github.com/amem/mcp-server/pkg/models"
 	"go.uber.org/zap"
 )
 
-// Server represents the MCP server
+// Server represents the MCP server. Protocol handling is shared by every
+// transport; each client connection gets its own session.
 type Server struct {
-	logger      *zap.Logger
-	tools       map[string]Tool
-	initialized bool
-	reader      *bufio.Reader
-	writer      io.Writer
+	logger    *zap.Logger
+	tools     map[string]Tool
+	resources ResourceProvider // Optional, enables the resources capability
+	prompts   PromptProvider   // Optional, enables the prompts capability
+	workers   chan struct{}    // Bounds the number of requests handled at once
+
+	maxRequestSize int64 // Largest HTTP request body accepted, in bytes
+
+	sessionsMu sync.Mutex
+	sessions   map[*session]bool // Connected sessions, for server-initiated notifications
 }
 
 // Tool represents an MCP tool handler
@@ -29,213 +34,414 @@ type Tool interface {
 	Execute(ctx context.Context, args map[string]interface{}) (*models.MCPToolResult, error)
 }
 
+// StructuredTool is implemented by tools whose results carry structured
+// content matching an output schema
+type StructuredTool interface {
+	Tool
+	OutputSchema() map[string]interface{}
+}
+
+// session holds the protocol state of one client connection
+type session struct {
+	id            string
+	mu            sync.Mutex
+	initialized   bool
+	inFlight      map[string]context.CancelFunc   // Cancels running requests, keyed by requestKey
+	subscriptions map[string]bool                 // Subscribed resource URIs
+	notify        func(message interface{}) error // Delivers server-initiated messages
+}
+
+// DefaultMaxConcurrentRequests is the number of requests handled at once
+// unless SetMaxConcurrentRequests is called
+const DefaultMaxConcurrentRequests = 8
+
+// DefaultMaxRequestSize is the largest HTTP request body accepted unless
+// SetMaxRequestSize is called
+const DefaultMaxRequestSize = 10 << 20 // 10MB
+
+// supportedProtocolVersions lists the protocol versions the server speaks, newest first
+var supportedProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
 // NewServer creates a new MCP server
 func NewServer(logger *zap.Logger) *Server {
 	return &Server{
-		logger: logger,
-		tools:  make(map[string]Tool),
-		reader: bufio.NewReader(os.Stdin),
-		writer: os.Stdout,
+		logger:   logger,
+		tools:    make(map[string]Tool),
+		workers:  make(chan struct{}, DefaultMaxConcurrentRequests),
+		sessions: make(map[*session]bool),
+
+		maxRequestSize: DefaultMaxRequestSize,
 	}
 }
 
+// addSession registers a connected session
+func (s *Server) addSession(sess *session) {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	s.sessions[sess] = true
+}
+
+// removeSession forgets a disconnected session
+func (s *Server) removeSession(sess *session) {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	delete(s.sessions, sess)
+}
+
+// activeSessions returns a snapshot of the connected sessions
+func (s *Server) activeSessions() []*session {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+
+	sessions := make([]*session, 0, len(s.sessions))
+	for sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	return sessions
+}
+
+// SetMaxConcurrentRequests sets how many requests are handled at once across
+// all sessions. It must be called before the server starts.
+func (s *Server) SetMaxConcurrentRequests(n int) {
+	if n < 1 {
+		n = 1
+	}
+	s.workers = make(chan struct{}, n)
+}
+
+// SetMaxRequestSize sets the largest HTTP request body accepted, in bytes.
+// It must be called before the server starts.
+func (s *Server) SetMaxRequestSize(n int64) {
+	if n < 1 {
+		n = DefaultMaxRequestSize
+	}
+	s.maxRequestSize = n
+}
+
 // RegisterTool registers a tool with the server
 func (s *Server) RegisterTool(tool Tool) {
 	s.tools[tool.Name()] = tool
 	s.logger.Info("Registered tool", zap.String("name", tool.Name()))
 }
 
-// Start starts the MCP server
-func (s *Server) Start(ctx context.Context) error {
-	s.logger.Info("Starting MCP server")
-
-	for {
-		select {
-		case <-ctx.Done():
-			s.logger.Info("MCP server shutting down")
-			return ctx.Err()
-		default:
-			if err := s.handleRequest(ctx); err != nil {
-				if err == io.EOF {
-					s.logger.Info("Client disconnected")
-					return nil
-				}
-				s.logger.Error("Error handling request", zap.Error(err))
-				continue
-			}
-		}
+// isInitialized reports whether the session has completed initialization
+func (sess *session) isInitialized() bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.initialized
+}
+
+// requestKey identifies a request ID within a session. The type is part of
+// the key because the string "1" and the number 1 are different IDs.
+func requestKey(id interface{}) string {
+	return fmt.Sprintf("%T:%v", id, id)
+}
+
+// track registers a request as in flight and returns its context, which is
+// cancelled by a matching notifications/cancelled, and a function to call
+// when the request completes
+func (sess *session) track(ctx context.Context, id interface{}) (context.Context, func(), error) {
+	key := requestKey(id)
+
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	if _, exists := sess.inFlight[key]; exists {
+		return nil, nil, fmt.Errorf("request ID %v is already in use", id)
 	}
+	if sess.inFlight == nil {
+		sess.inFlight = make(map[string]context.CancelFunc)
+	}
+
+	reqCtx, cancel := context.WithCancel(ctx)
+	sess.inFlight[key] = cancel
+
+	return reqCtx, func() {
+		sess.mu.Lock()
+		delete(sess.inFlight, key)
+		sess.mu.Unlock()
+		cancel()
+	}, nil
 }
 
-// handleRequest handles a single JSON-RPC request or notification
-func (s *Server) handleRequest(ctx context.Context) error {
-	line, err := s.reader.ReadString('\n')
-	if err != nil {
-		return err
+// cancel cancels an in-flight request, reporting whether it was found
+func (sess *session) cancel(id interface{}) bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	cancel, ok := sess.inFlight[requestKey(id)]
+	if ok {
+		cancel()
 	}
+	return ok
+}
+
+// isRequest reports whether a message is a request, which needs a response,
+// rather than a notification
+func isRequest(data []byte) bool {
+	var request models.MCPRequest
+	return json.Unmarshal(data, &request) == nil && request.ID != nil
+}
+
+// handleMessage handles a single JSON-RPC request or notification and returns
+// the response to send, or nil for notifications and cancelled requests
+func (s *Server) handleMessage(ctx context.Context, sess *session, data []byte) interface{} {
+	return s.dispatch(ctx, sess, data)()
+}
 
+// dispatch parses a message and returns a function that handles it and
+// returns the response to send, or nil for notifications and cancelled
+// requests. A request is registered as in flight before dispatch returns, so
+// a notifications/cancelled read right after it finds it even if the handler
+// has not started. Requests wait for a free worker; notifications are handled
+// immediately so that cancellations get through while the workers are busy.
+func (s *Server) dispatch(ctx context.Context, sess *session, data []byte) func() interface{} {
 	// First try to parse as a request (with ID)
 	var request models.MCPRequest
-	if err := json.Unmarshal([]byte(line), &request); err == nil && request.ID != nil {
+	if err := json.Unmarshal(data, &request); err == nil && request.ID != nil {
 		s.logger.Debug("Received request",
+			zap.String("session", sess.id),
 			zap.String("method", request.Method),
 			zap.Any("id", request.ID))
-		return s.handleJSONRPCRequest(ctx, request)
+
+		reqCtx, done, err := sess.track(ctx, request.ID)
+		if err != nil {
+			return func() interface{} {
+				return errorResponse(request.ID, models.InvalidRequest, err.Error(), nil)
+			}
+		}
+
+		return func() interface{} {
+			defer done()
+			return s.handleRequest(ctx, reqCtx, sess, request)
+		}
 	}
 
-	// If that fails, try to parse as a notification (no ID)
-	var notification models.MCPNotification
-	if err := json.Unmarshal([]byte(line), &notification); err == nil {
-		s.logger.Debug("Received notification",
-			zap.String("method", notification.Method))
-		return s.handleJSONRPCNotification(notification)
+	return func() interface{} {
+		// If that fails, try to parse as a notification (no ID)
+		var notification models.MCPNotification
+		if err := json.Unmarshal(data, &notification); err == nil {
+			s.logger.Debug("Received notification",
+				zap.String("session", sess.id),
+				zap.String("method", notification.Method))
+			s.handleJSONRPCNotification(sess, notification)
+			return nil
+		}
+
+		// If both fail, send error response (only for requests, not notifications)
+		return errorResponse(nil, models.ParseError, "Invalid JSON", nil)
+	}
+}
+
+// handleRequest runs a tracked request on a worker and returns its response.
+// ctx is the session's context and reqCtx the request's own.
+func (s *Server) handleRequest(ctx, reqCtx context.Context, sess *session, request models.MCPRequest) interface{} {
+	select {
+	case s.workers <- struct{}{}:
+		defer func() { <-s.workers }()
+	case <-reqCtx.Done():
 	}
 
-	// If both fail, send error response (only for requests, not notifications)
-	s.sendError(nil, models.ParseError, "Invalid JSON", nil)
-	return nil
+	var result interface{}
+	var rpcErr *models.MCPError
+	if reqCtx.Err() == nil {
+		result, rpcErr = s.handleJSONRPCRequest(reqCtx, sess, request)
+	}
+
+	// A request cancelled by the client gets no response
+	if reqCtx.Err() != nil && ctx.Err() == nil {
+		s.logger.Info("Request cancelled",
+			zap.String("session", sess.id),
+			zap.String("method", request.Method),
+			zap.Any("id", request.ID))
+		return nil
+	}
+	if reqCtx.Err() != nil {
+		return errorResponse(request.ID, models.InternalError, "Server shutting down", nil)
+	}
+
+	if rpcErr != nil {
+		return errorResponse(request.ID, rpcErr.Code, rpcErr.Message, rpcErr.Data)
+	}
+	return models.MCPSuccessResponse{JSONRPC: "2.0", ID: request.ID, Result: result}
 }
 
 // handleJSONRPCRequest handles requests that require responses
-func (s *Server) handleJSONRPCRequest(ctx context.Context, request models.MCPRequest) error {
+func (s *Server) handleJSONRPCRequest(ctx context.Context, sess *session, request models.MCPRequest) (interface{}, *models.MCPError) {
 	switch request.Method {
 	case models.MethodInitialize:
-		return s.handleInitialize(request)
+		return s.handleInitialize(sess, request)
+	case models.MethodPing:
+		return map[string]interface{}{}, nil
+	}
+
+	if !sess.isInitialized() {
+		return nil, &models.MCPError{Code: models.InvalidRequest, Message: "Server not initialized"}
+	}
+
+	switch request.Method {
 	case models.MethodListTools:
-		return s.handleListTools(request)
+		return s.handleListTools()
 	case models.MethodCallTool:
 		return s.handleCallTool(ctx, request)
+	case models.MethodListResources, models.MethodReadResource, models.MethodListResourceTemplates,
+		models.MethodSubscribeResource, models.MethodUnsubscribeResource:
+		return s.handleResourceRequest(ctx, sess, request)
+	case models.MethodListPrompts, models.MethodGetPrompt:
+		return s.handlePromptRequest(ctx, request)
 	default:
-		s.sendError(request.ID, models.MethodNotFound,
-			fmt.Sprintf("Method not found: %s", request.Method), nil)
+		return nil, &models.MCPError{
+			Code:    models.MethodNotFound,
+			Message: fmt.Sprintf("Method not found: %s", request.Method),
+		}
 	}
-
-	return nil
 }
 
 // handleJSONRPCNotification handles notifications that don't require responses
-func (s *Server) handleJSONRPCNotification(notification models.MCPNotification) error {
+func (s *Server) handleJSONRPCNotification(sess *session, notification models.MCPNotification) {
 	switch notification.Method {
-	case "notifications/initialized":
+	case models.MethodNotification:
 		// Client has finished initialization - no response needed
-		s.logger.Debug("Client initialization complete")
-		return nil
+		s.logger.Debug("Client initialization complete", zap.String("session", sess.id))
+	case models.MethodCancelled:
+		params, _ := notification.Params.(map[string]interface{})
+		requestID := params["requestId"]
+		if requestID == nil || !sess.cancel(requestID) {
+			// The request may already have finished, which is fine
+			s.logger.Debug("Cancellation for unknown request", zap.Any("id", requestID))
+			return
+		}
+		s.logger.Debug("Cancelling request",
+			zap.String("session", sess.id),
+			zap.Any("id", requestID),
+			zap.Any("reason", params["reason"]))
 	default:
 		// Unknown notification - log but don't respond
 		s.logger.Debug("Unknown notification received",
 			zap.String("method", notification.Method))
-		return nil
 	}
 }
 
 // handleInitialize handles the initialize request
-func (s *Server) handleInitialize(request models.MCPRequest) error {
-	s.initialized = true
+func (s *Server) handleInitialize(sess *session, request models.MCPRequest) (interface{}, *models.MCPError) {
+	sess.mu.Lock()
+	sess.initialized = true
+	sess.mu.Unlock()
+
+	// Answer with the client's version if we speak it, otherwise our newest
+	protocolVersion := supportedProtocolVersions[0]
+	if params, ok := request.Params.(map[string]interface{}); ok {
+		if requested, ok := params["protocolVersion"].(string); ok {
+			for _, version := range supportedProtocolVersions {
+				if version == requested {
+					protocolVersion = requested
+				}
+			}
+		}
+	}
+
+	capabilities := map[string]interface{}{
+		"tools": map[string]interface{}{},
+	}
+	if s.resources != nil {
+		capabilities["resources"] = map[string]interface{}{"subscribe": true}
+	}
+	if s.prompts != nil {
+		capabilities["prompts"] = map[string]interface{}{}
+	}
 
 	result := map[string]interface{}{
-		"protocolVersion": "2024-11-05",
-		"capabilities": map[string]interface{}{
-			"tools": map[string]interface{}{},
-		},
+		"protocolVersion": protocolVersion,
+		"capabilities":    capabilities,
 		"serverInfo": map[string]interface{}{
 			"name":    "A-MEM MCP Server",
 			"version": "1.0.0",
 		},
 	}
 
-	return s.sendResponse(request.ID, result)
+	return result, nil
 }
 
 // handleListTools handles the list tools request
-func (s *Server) handleListTools(request models.MCPRequest) error {
-	if !s.initialized {
-		s.sendError(request.ID, models.InvalidRequest, "Server not initialized", nil)
-		return nil
-	}
-
+func (s *Server) handleListTools() (interface{}, *models.MCPError) {
 	tools := make([]models.MCPTool, 0, len(s.tools))
 	for _, tool := range s.tools {
-		tools = append(tools, models.MCPTool{
+		definition := models.MCPTool{
 			Name:        tool.Name(),
 			Description: tool.Description(),
 			InputSchema: tool.InputSchema(),
-		})
+		}
+		if structured, ok := tool.(StructuredTool); ok {
+			definition.OutputSchema = structured.OutputSchema()
+		}
+		tools = append(tools, definition)
 	}
 
 	result := map[string]interface{}{
 		"tools": tools,
 	}
 
-	return s.sendResponse(request.ID, result)
+	return result, nil
 }
 
 // handleCallTool handles tool call requests
-func (s *Server) handleCallTool(ctx context.Context, request models.MCPRequest) error {
-	if !s.initialized {
-		s.sendError(request.ID, models.InvalidRequest, "Server not initialized", nil)
-		return nil
-	}
-
+func (s *Server) handleCallTool(ctx context.Context, request models.MCPRequest) (interface{}, *models.MCPError) {
 	params, ok := request.Params.(map[string]interface{})
 	if !ok {
-		s.sendError(request.ID, models.InvalidParams, "Invalid params", nil)
-		return nil
+		return nil, &models.MCPError{Code: models.InvalidParams, Message: "Invalid params"}
 	}
 
 	toolName, ok := params["name"].(string)
 	if !ok {
-		s.sendError(request.ID, models.InvalidParams, "Tool name required", nil)
-		return nil
+		return nil, &models.MCPError{Code: models.InvalidParams, Message: "Tool name required"}
 	}
 
 	tool, exists := s.tools[toolName]
 	if !exists {
-		s.sendError(request.ID, models.MethodNotFound,
-			fmt.Sprintf("Tool not found: %s", toolName), nil)
-		return nil
+		return nil, &models.MCPError{
+			Code:    models.MethodNotFound,
+			Message: fmt.Sprintf("Tool not found: %s", toolName),
+		}
 	}
 
-	arguments, ok := params["arguments"].(map[string]interface{})
-	if !ok {
-		arguments = make(map[string]interface{})
+	arguments := make(map[string]interface{})
+	if rawArguments, present := params["arguments"]; present && rawArguments != nil {
+		if arguments, ok = rawArguments.(map[string]interface{}); !ok {
+			return nil, &models.MCPError{Code: models.InvalidParams, Message: "Tool arguments must be an object"}
+		}
 	}
 
+	if violations := validateArguments(tool.InputSchema(), arguments); len(violations) > 0 {
+		return nil, &models.MCPError{
+			Code:    models.InvalidParams,
+			Message: fmt.Sprintf("Invalid arguments for %s: %s", toolName, strings.Join(violations, "; ")),
+			Data:    map[string]interface{}{"violations": violations},
+		}
+	}
+
+	// Arguments can hold whole memories or exports, so only their sizes are
+	// logged at info level
 	s.logger.Info("Executing tool",
 		zap.String("tool", toolName),
-		zap.Any("arguments", arguments))
+		zap.Any("argument_sizes", argumentSizes(arguments)))
+	s.logger.Debug("Tool arguments",
+		zap.String("tool", toolName),
+		zap.Any("arguments", elideArguments(arguments)))
 
 	result, err := tool.Execute(ctx, arguments)
 	if err != nil {
 		s.logger.Error("Tool execution failed",
 			zap.String("tool", toolName),
 			zap.Error(err))
-		s.sendError(request.ID, models.InternalError, err.Error(), nil)
-		return nil
-	}
-
-	return s.sendResponse(request.ID, result)
-}
-
-// sendResponse sends a JSON-RPC response
-func (s *Server) sendResponse(id interface{}, result interface{}) error {
-	response := models.MCPSuccessResponse{
-		JSONRPC: "2.0",
-		ID:      id,
-		Result:  result,
+		return nil, &models.MCPError{Code: models.InternalError, Message: err.Error()}
 	}
 
-	data, err := json.Marshal(response)
-	if err != nil {
-		return err
-	}
-
-	_, err = fmt.Fprintf(s.writer, "%s\n", data)
-	return err
+	return result, nil
 }
 
-// sendError sends a JSON-RPC error response
-func (s *Server) sendError(id interface{}, code int, message string, data interface{}) error {
-	response := models.MCPErrorResponse{
+// errorResponse builds a JSON-RPC error response
+func errorResponse(id interface{}, code int, message string, data interface{}) models.MCPErrorResponse {
+	return models.MCPErrorResponse{
 		JSONRPC: "2.0",
 		ID:      id,
 		Error: models.MCPError{
@@ -244,12 +450,4 @@ func (s *Server) sendError(id interface{}, code int, message string, data interf
 			Data:    data,
 		},
 	}
-
-	responseData, err := json.Marshal(response)
-	if err != nil {
-		return err
-	}
-
-	_, err = fmt.Fprintf(s.writer, "%s\n", responseData)
-	return err
 }
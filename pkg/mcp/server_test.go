@@ -0,0 +1,314 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amem/mcp-server/pkg/models"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// blockingTool runs until its context is cancelled
+type blockingTool struct{}
+
+func (blockingTool) Name() string        { return "block" }
+func (blockingTool) Description() string { return "Blocks until cancelled" }
+func (blockingTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{"type": "object"}
+}
+func (blockingTool) Execute(ctx context.Context, args map[string]interface{}) (*models.MCPToolResult, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// stdioClient drives a server over in-memory pipes
+type stdioClient struct {
+	t         *testing.T
+	in        *io.PipeWriter
+	responses chan map[string]interface{}
+	done      chan error
+}
+
+func startStdio(t *testing.T, server *Server) *stdioClient {
+	t.Helper()
+
+	clientIn, serverIn := io.Pipe()
+	serverOut, clientOut := io.Pipe()
+
+	client := &stdioClient{
+		t:         t,
+		in:        serverIn,
+		responses: make(chan map[string]interface{}, 10),
+		done:      make(chan error, 1),
+	}
+
+	go func() { client.done <- server.ServeStdio(context.Background(), clientIn, clientOut) }()
+	go func() {
+		scanner := bufio.NewScanner(serverOut)
+		for scanner.Scan() {
+			var response map[string]interface{}
+			json.Unmarshal(scanner.Bytes(), &response)
+			client.responses <- response
+		}
+	}()
+
+	return client
+}
+
+func (c *stdioClient) send(message string) {
+	if _, err := io.WriteString(c.in, message+"\n"); err != nil {
+		c.t.Fatalf("Failed to send %s: %v", message, err)
+	}
+}
+
+func (c *stdioClient) receive() map[string]interface{} {
+	select {
+	case response := <-c.responses:
+		return response
+	case <-time.After(2 * time.Second):
+		c.t.Fatal("Timed out waiting for a response")
+		return nil
+	}
+}
+
+func (c *stdioClient) close() {
+	c.in.Close()
+	if err := <-c.done; err != nil {
+		c.t.Fatalf("Expected clean shutdown, got %v", err)
+	}
+}
+
+func TestStdioRunsRequestsConcurrentlyAndCancels(t *testing.T) {
+	server := NewServer(zap.NewNop())
+	server.RegisterTool(blockingTool{})
+
+	client := startStdio(t, server)
+	client.send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	client.receive()
+
+	// The blocked call must not hold up the ping behind it
+	client.send(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"block"}}`)
+	client.send(`{"jsonrpc":"2.0","id":3,"method":"ping"}`)
+	if response := client.receive(); response["id"] != float64(3) {
+		t.Fatalf("Expected the ping response first, got %v", response)
+	}
+
+	// A cancelled request gets no response
+	client.send(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":2}}`)
+	client.send(`{"jsonrpc":"2.0","id":4,"method":"ping"}`)
+	if response := client.receive(); response["id"] != float64(4) {
+		t.Fatalf("Expected no response to the cancelled call, got %v", response)
+	}
+
+	client.close()
+}
+
+func TestStdioCancelsRequestsCancelledRightAway(t *testing.T) {
+	server := NewServer(zap.NewNop())
+	server.SetMaxConcurrentRequests(1)
+	server.RegisterTool(blockingTool{})
+
+	client := startStdio(t, server)
+	client.send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	client.receive()
+
+	// The cancellation may be read before the call starts running; a missed
+	// one would leave the only worker blocked and the ping unanswered
+	client.send(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"block"}}` + "\n" +
+		`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":2}}`)
+	client.send(`{"jsonrpc":"2.0","id":3,"method":"ping"}`)
+	if response := client.receive(); response["id"] != float64(3) {
+		t.Fatalf("Expected only the ping response, got %v", response)
+	}
+
+	client.close()
+}
+
+// staticResources serves a single resource
+type staticResources struct{}
+
+func (staticResources) ListResources(ctx context.Context) ([]models.MCPResource, error) {
+	return []models.MCPResource{{URI: "amem://memory/1", Name: "one"}}, nil
+}
+
+func (staticResources) ResourceTemplates() []models.MCPResourceTemplate { return nil }
+
+func (staticResources) ReadResource(ctx context.Context, uri string) ([]models.MCPResourceContents, error) {
+	if uri != "amem://memory/1" {
+		return nil, models.ErrResourceNotFound
+	}
+	return []models.MCPResourceContents{{URI: uri, Text: "{}"}}, nil
+}
+
+func TestResourceSubscriptionsAndErrors(t *testing.T) {
+	server := NewServer(zap.NewNop())
+	server.SetResourceProvider(staticResources{})
+
+	client := startStdio(t, server)
+	client.send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	capabilities := client.receive()["result"].(map[string]interface{})["capabilities"].(map[string]interface{})
+	if _, ok := capabilities["resources"]; !ok {
+		t.Fatalf("Expected the resources capability, got %v", capabilities)
+	}
+
+	client.send(`{"jsonrpc":"2.0","id":2,"method":"resources/read","params":{"uri":"amem://memory/2"}}`)
+	response := client.receive()
+	if code := response["error"].(map[string]interface{})["code"]; code != float64(models.ResourceNotFound) {
+		t.Fatalf("Expected resource not found, got %v", response)
+	}
+
+	client.send(`{"jsonrpc":"2.0","id":3,"method":"resources/subscribe","params":{"uri":"amem://memory/1"}}`)
+	client.receive()
+
+	server.NotifyResourceUpdated("amem://memory/2") // Not subscribed, so not delivered
+	server.NotifyResourceUpdated("amem://memory/1")
+	notification := client.receive()
+	if notification["method"] != models.MethodResourceUpdated {
+		t.Fatalf("Expected a resource update notification, got %v", notification)
+	}
+
+	client.close()
+}
+
+func TestHTTPRequiresBearerToken(t *testing.T) {
+	server := NewServer(zap.NewNop())
+
+	if err := server.StartHTTP(context.Background(), "0.0.0.0:0", ""); err == nil {
+		t.Error("Expected listening on every interface without a token to be refused")
+	}
+
+	transport := &httpTransport{server: server, logger: zap.NewNop(), authToken: "secret", sessions: make(map[string]*httpSession)}
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`
+
+	for _, c := range []struct {
+		header string
+		status int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"Basic secret", http.StatusUnauthorized},
+		{"Bearer secret", http.StatusOK},
+	} {
+		request := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(initialize))
+		if c.header != "" {
+			request.Header.Set("Authorization", c.header)
+		}
+		recorder := httptest.NewRecorder()
+		transport.ServeHTTP(recorder, request)
+
+		if recorder.Code != c.status {
+			t.Errorf("Authorization %q: expected status %d, got %d", c.header, c.status, recorder.Code)
+		}
+	}
+}
+
+func TestHTTPLimitsOpenSessions(t *testing.T) {
+	transport := &httpTransport{server: NewServer(zap.NewNop()), logger: zap.NewNop(), maxSessions: 2, sessions: make(map[string]*httpSession)}
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`
+
+	post := func() *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		transport.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(initialize)))
+		return recorder
+	}
+
+	first := post()
+	if first.Code != http.StatusOK || post().Code != http.StatusOK {
+		t.Fatal("Expected the first two sessions to start")
+	}
+	if recorder := post(); recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected a third session to be refused, got status %d", recorder.Code)
+	}
+
+	// Ending a session makes room for another
+	request := httptest.NewRequest(http.MethodDelete, "/mcp", nil)
+	request.Header.Set(sessionHeader, first.Header().Get(sessionHeader))
+	transport.ServeHTTP(httptest.NewRecorder(), request)
+
+	if recorder := post(); recorder.Code != http.StatusOK {
+		t.Errorf("Expected a session to start after one ended, got status %d", recorder.Code)
+	}
+}
+
+// echoTool returns a fixed result for any arguments
+type echoTool struct{}
+
+func (echoTool) Name() string        { return "echo" }
+func (echoTool) Description() string { return "Returns a fixed result" }
+func (echoTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{"type": "object", "additionalProperties": true}
+}
+func (echoTool) Execute(ctx context.Context, args map[string]interface{}) (*models.MCPToolResult, error) {
+	return &models.MCPToolResult{}, nil
+}
+
+func TestToolCallsDoNotLogContent(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	server := NewServer(zap.New(core))
+	server.RegisterTool(echoTool{})
+
+	_, mcpErr := server.handleCallTool(context.Background(), models.MCPRequest{
+		JSONRPC: "2.0",
+		ID:      float64(1),
+		Method:  "tools/call",
+		Params: map[string]interface{}{
+			"name": "echo",
+			"arguments": map[string]interface{}{
+				"workspace_id": "project",
+				"data":         "secret export",
+				"memories":     []interface{}{map[string]interface{}{"content": "secret note", "code_type": "go"}},
+			},
+		},
+	})
+	if mcpErr != nil {
+		t.Fatalf("Tool call failed: %+v", mcpErr)
+	}
+
+	if entries := logs.FilterMessage("Executing tool").AllUntimed(); len(entries) != 1 || entries[0].Level != zap.InfoLevel {
+		t.Fatalf("Expected one info entry for the call, got %+v", entries)
+	}
+	if entries := logs.FilterMessage("Tool arguments").AllUntimed(); len(entries) != 1 || entries[0].Level != zap.DebugLevel {
+		t.Fatalf("Expected one debug entry with the arguments, got %+v", entries)
+	}
+
+	for _, entry := range logs.AllUntimed() {
+		logged, err := json.Marshal(entry.ContextMap())
+		if err != nil {
+			t.Fatalf("Failed to encode log entry: %v", err)
+		}
+		if strings.Contains(string(logged), "secret") {
+			t.Errorf("Expected content and data to be elided, got %s: %s", entry.Message, logged)
+		}
+		if entry.Message == "Tool arguments" && !strings.Contains(string(logged), "project") {
+			t.Errorf("Expected the other arguments at debug level, got %s", logged)
+		}
+	}
+}
+
+func TestHTTPRejectsOversizedBodies(t *testing.T) {
+	transport := &httpTransport{server: NewServer(zap.NewNop()), logger: zap.NewNop(), maxBodySize: 64, sessions: make(map[string]*httpSession)}
+
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`
+	padded := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"padding":"` + strings.Repeat("x", 64) + `"}}`
+	for _, c := range []struct {
+		body   string
+		status int
+	}{
+		{initialize, http.StatusOK},
+		{padded, http.StatusRequestEntityTooLarge},
+	} {
+		recorder := httptest.NewRecorder()
+		transport.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(c.body)))
+		if recorder.Code != c.status {
+			t.Errorf("Body of %d bytes: expected status %d, got %d: %s", len(c.body), c.status, recorder.Code, recorder.Body.String())
+		}
+	}
+}
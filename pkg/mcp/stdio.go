@@ -0,0 +1,91 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// Start serves a single client over stdin and stdout
+func (s *Server) Start(ctx context.Context) error {
+	return s.ServeStdio(ctx, os.Stdin, os.Stdout)
+}
+
+// ServeStdio serves a single client reading newline-delimited JSON-RPC
+// messages from r and writing responses to w
+func (s *Server) ServeStdio(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.logger.Info("Starting MCP server", zap.String("transport", "stdio"))
+
+	reader := bufio.NewReader(r)
+	var writeMu sync.Mutex
+	write := func(message interface{}) error {
+		data, err := json.Marshal(message)
+		if err != nil {
+			return err
+		}
+
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	}
+
+	sess := &session{id: "stdio", notify: write}
+	s.addSession(sess)
+	defer s.removeSession(sess)
+
+	// Requests run concurrently, bounded by the worker pool; wait for them
+	// so their responses are written before returning
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("MCP server shutting down")
+			return ctx.Err()
+		default:
+		}
+
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				s.logger.Info("Client disconnected")
+				return nil
+			}
+			return err
+		}
+
+		data := []byte(line)
+		handle := s.dispatch(ctx, sess, data)
+		if !isRequest(data) {
+			// Notifications, including cancellations, are handled in order
+			if response := handle(); response != nil {
+				if err := write(response); err != nil {
+					s.logger.Error("Error writing response", zap.Error(err))
+				}
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			response := handle()
+			if response == nil {
+				return
+			}
+
+			if err := write(response); err != nil {
+				s.logger.Error("Error writing response", zap.Error(err))
+			}
+		}()
+	}
+}
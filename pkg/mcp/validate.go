@@ -0,0 +1,248 @@
+package mcp
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// validateArguments checks tool arguments against the tool's input schema and
+// returns every violation found. It supports the subset of JSON Schema the
+// tools use: type, properties, required, enum, minimum, maximum, minLength,
+// items and additionalProperties. Unknown top-level arguments are rejected
+// unless the schema sets additionalProperties.
+func validateArguments(schema map[string]interface{}, args map[string]interface{}) []string {
+	var violations []string
+	validateObject(schema, args, "", true, &violations)
+	return violations
+}
+
+// validateValue checks a single value against a schema
+func validateValue(schema map[string]interface{}, value interface{}, path string, violations *[]string) {
+	if schema == nil {
+		return
+	}
+
+	if !matchesType(schema["type"], value) {
+		*violations = append(*violations, fmt.Sprintf("%s must be %s, got %s", path, describeType(schema["type"]), jsonTypeName(value)))
+		return
+	}
+
+	if enum, ok := schema["enum"]; ok && !inEnum(enum, value) {
+		*violations = append(*violations, fmt.Sprintf("%s must be one of %s", path, describeEnum(enum)))
+	}
+
+	switch v := value.(type) {
+	case float64:
+		if minimum, ok := toFloat(schema["minimum"]); ok && v < minimum {
+			*violations = append(*violations, fmt.Sprintf("%s must be at least %v", path, minimum))
+		}
+		if maximum, ok := toFloat(schema["maximum"]); ok && v > maximum {
+			*violations = append(*violations, fmt.Sprintf("%s must be at most %v", path, maximum))
+		}
+	case string:
+		if minLength, ok := toFloat(schema["minLength"]); ok && float64(len(v)) < minLength {
+			*violations = append(*violations, fmt.Sprintf("%s must be at least %v characters", path, minLength))
+		}
+	case []interface{}:
+		items, _ := schema["items"].(map[string]interface{})
+		for i, item := range v {
+			validateValue(items, item, fmt.Sprintf("%s[%d]", path, i), violations)
+		}
+	case map[string]interface{}:
+		validateObject(schema, v, path, false, violations)
+	}
+}
+
+// validateObject checks an object's properties, required fields and, when
+// strict, rejects properties the schema does not declare
+func validateObject(schema map[string]interface{}, object map[string]interface{}, path string, strict bool, violations *[]string) {
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	for _, name := range requiredNames(schema["required"]) {
+		if _, ok := object[name]; !ok {
+			*violations = append(*violations, fmt.Sprintf("%s is required", joinPath(path, name)))
+		}
+	}
+
+	additional, hasAdditional := schema["additionalProperties"]
+	allowUnknown := !strict
+	if hasAdditional {
+		allowUnknown = additional != false
+	}
+
+	// Sort names so the violations are reported in a stable order
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		propertySchema, known := properties[name].(map[string]interface{})
+		if !known {
+			if additionalSchema, ok := additional.(map[string]interface{}); ok {
+				validateValue(additionalSchema, object[name], joinPath(path, name), violations)
+			} else if !allowUnknown {
+				*violations = append(*violations, fmt.Sprintf("%s is not a known argument", joinPath(path, name)))
+			}
+			continue
+		}
+		validateValue(propertySchema, object[name], joinPath(path, name), violations)
+	}
+}
+
+// matchesType reports whether a decoded JSON value has one of the schema types
+func matchesType(schemaType interface{}, value interface{}) bool {
+	types := schemaTypes(schemaType)
+	if len(types) == 0 {
+		return true
+	}
+
+	for _, typ := range types {
+		switch typ {
+		case "string":
+			if _, ok := value.(string); ok {
+				return true
+			}
+		case "number":
+			if _, ok := value.(float64); ok {
+				return true
+			}
+		case "integer":
+			if n, ok := value.(float64); ok && n == math.Trunc(n) {
+				return true
+			}
+		case "boolean":
+			if _, ok := value.(bool); ok {
+				return true
+			}
+		case "array":
+			if _, ok := value.([]interface{}); ok {
+				return true
+			}
+		case "object":
+			if _, ok := value.(map[string]interface{}); ok {
+				return true
+			}
+		case "null":
+			if value == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// schemaTypes normalizes a schema type, which may be a string or a list
+func schemaTypes(schemaType interface{}) []string {
+	switch t := schemaType.(type) {
+	case string:
+		return []string{t}
+	case []string:
+		return t
+	case []interface{}:
+		types := make([]string, 0, len(t))
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// requiredNames normalizes a required list, which may be []string or []interface{}
+func requiredNames(required interface{}) []string {
+	return schemaTypes(required)
+}
+
+// inEnum reports whether value is one of the enum values
+func inEnum(enum interface{}, value interface{}) bool {
+	values := reflect.ValueOf(enum)
+	if values.Kind() != reflect.Slice {
+		return true
+	}
+
+	for i := 0; i < values.Len(); i++ {
+		candidate := values.Index(i).Interface()
+		if number, ok := toFloat(candidate); ok {
+			if v, ok := value.(float64); ok && v == number {
+				return true
+			}
+			continue
+		}
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// toFloat converts the numeric types used in schema literals to float64
+func toFloat(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func describeType(schemaType interface{}) string {
+	types := schemaTypes(schemaType)
+	for i, typ := range types {
+		if typ == "integer" || typ == "array" || typ == "object" {
+			types[i] = "an " + typ
+		} else if typ != "null" {
+			types[i] = "a " + typ
+		}
+	}
+	return strings.Join(types, " or ")
+}
+
+func describeEnum(enum interface{}) string {
+	values := reflect.ValueOf(enum)
+	parts := make([]string, 0, values.Len())
+	for i := 0; i < values.Len(); i++ {
+		parts = append(parts, fmt.Sprintf("%q", fmt.Sprint(values.Index(i).Interface())))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// jsonTypeName names the JSON type of a decoded value
+func jsonTypeName(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
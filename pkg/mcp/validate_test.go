@@ -0,0 +1,55 @@
+package mcp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateArgumentsReportsEveryViolation(t *testing.T) {
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"query":       map[string]interface{}{"type": "string", "minLength": 1},
+			"max_results": map[string]interface{}{"type": "integer", "minimum": 1, "maximum": 100},
+			"mode":        map[string]interface{}{"type": "string", "enum": []string{"vector", "keyword"}},
+			"tags":        map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		},
+		"required": []string{"query"},
+	}
+
+	valid := map[string]interface{}{"query": "retry", "max_results": float64(10), "mode": "vector", "tags": []interface{}{"go"}}
+	if violations := validateArguments(schema, valid); len(violations) != 0 {
+		t.Errorf("Expected valid arguments to pass, got %v", violations)
+	}
+
+	violations := validateArguments(schema, map[string]interface{}{
+		"max_results": "10",
+		"mode":        "fuzzy",
+		"tags":        []interface{}{"go", float64(1)},
+		"limit":       float64(5),
+	})
+
+	expected := []string{
+		"query is required",
+		"limit is not a known argument",
+		"max_results must be an integer, got string",
+		"mode must be one of",
+		"tags[1] must be a string, got integer",
+	}
+	joined := strings.Join(violations, "\n")
+	if len(violations) != len(expected) {
+		t.Errorf("Expected %d violations, got %v", len(expected), violations)
+	}
+	for _, want := range expected {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Expected a violation containing %q, got %v", want, violations)
+		}
+	}
+
+	if violations := validateArguments(schema, map[string]interface{}{"query": "x", "max_results": 2.5}); len(violations) != 1 {
+		t.Errorf("Expected a fractional integer to be rejected, got %v", violations)
+	}
+	if violations := validateArguments(schema, map[string]interface{}{"query": "x", "max_results": float64(101)}); len(violations) != 1 {
+		t.Errorf("Expected an out of range integer to be rejected, got %v", violations)
+	}
+}
@@ -0,0 +1,332 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/amem/mcp-server/pkg/models"
+	"go.uber.org/zap"
+)
+
+// ReindexMemoriesTool implements the reindex_memories MCP admin tool
+type ReindexMemoriesTool struct {
+	system *System
+	logger *zap.Logger
+}
+
+// NewReindexMemoriesTool creates a new reindex memories tool
+func NewReindexMemoriesTool(system *System, logger *zap.Logger) *ReindexMemoriesTool {
+	return &ReindexMemoriesTool{
+		system: system,
+		logger: logger,
+	}
+}
+
+func (t *ReindexMemoriesTool) Name() string {
+	return models.ToolReindexMemories
+}
+
+func (t *ReindexMemoriesTool) Description() string {
+	return "Admin: re-embed every memory with the configured embedding model into a new collection and switch to it. Rerun after a failure to resume where it stopped. A server started in admin-only mode must be restarted after the switch."
+}
+
+func (t *ReindexMemoriesTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"batch_size": map[string]interface{}{
+				"type":        "integer",
+				"description": "Memories re-embedded per embedding request (default: 100)",
+				"default":     defaultReindexBatchSize,
+				"minimum":     1,
+			},
+			"switch": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Switch to the new collection when done; the old one is kept as a backup (default: true)",
+				"default":     true,
+			},
+			"format": formatProperty(),
+		},
+	}
+}
+
+func (t *ReindexMemoriesTool) OutputSchema() map[string]interface{} {
+	return models.JSONSchemaFor(models.ReindexResponse{})
+}
+
+func (t *ReindexMemoriesTool) Execute(ctx context.Context, args map[string]interface{}) (*models.MCPToolResult, error) {
+	format, err := parseFormat(args)
+	if err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	req := models.ReindexRequest{
+		BatchSize: defaultReindexBatchSize,
+		Switch:    true,
+	}
+
+	if err := models.DecodeArguments(args, &req); err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	response, err := t.system.Reindex(ctx, req, nil)
+	if err != nil {
+		t.logger.Error("Reindex failed", zap.Error(err))
+		return &models.MCPToolResult{
+			IsError: true,
+			Content: []models.MCPContent{{
+				Type: "text",
+				Text: fmt.Sprintf("Reindex failed: %v", err),
+			}},
+		}, nil
+	}
+
+	markdown := func() string {
+		return FormatReindexResponse(response)
+	}
+	compact := func() string {
+		return fmt.Sprintf("scanned=%d reembedded=%d unchanged=%d removed=%d switched=%t",
+			response.Scanned, response.Reembedded, response.Unchanged, response.Removed, response.Switched)
+	}
+
+	return structuredResult(format, response, markdown, compact), nil
+}
+
+// ExportMemoriesTool implements the export_memories MCP admin tool
+type ExportMemoriesTool struct {
+	system *System
+	logger *zap.Logger
+}
+
+// NewExportMemoriesTool creates a new export memories tool
+func NewExportMemoriesTool(system *System, logger *zap.Logger) *ExportMemoriesTool {
+	return &ExportMemoriesTool{
+		system: system,
+		logger: logger,
+	}
+}
+
+func (t *ExportMemoriesTool) Name() string {
+	return models.ToolExportMemories
+}
+
+func (t *ExportMemoriesTool) Description() string {
+	return fmt.Sprintf("Admin: export memories with their links and metadata as JSONL returned in the structured content's data field, for backups or moving memories to another server. Exports over %d MB fail; use the export command for those", maxInlineExportSize>>20)
+}
+
+func (t *ExportMemoriesTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"workspace_id": map[string]interface{}{
+				"type":        "string",
+				"description": "Only export memories in this workspace (default: all workspaces)",
+			},
+			"include_embeddings": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Include embeddings so an import with the same embedding model can skip re-embedding (default: false)",
+				"default":     false,
+			},
+			"format": formatProperty(),
+		},
+	}
+}
+
+func (t *ExportMemoriesTool) OutputSchema() map[string]interface{} {
+	return models.JSONSchemaFor(models.ExportResponse{})
+}
+
+func (t *ExportMemoriesTool) Execute(ctx context.Context, args map[string]interface{}) (*models.MCPToolResult, error) {
+	format, err := parseFormat(args)
+	if err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	var req models.ExportRequest
+	if err := models.DecodeArguments(args, &req); err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	data := &cappedBuilder{limit: maxInlineExportSize}
+	response, err := t.system.ExportMemories(ctx, req, data)
+	if err != nil {
+		message := fmt.Sprintf("Export failed: %v", err)
+		if errors.Is(err, errExportTooLarge) {
+			message = fmt.Sprintf("Export failed: the export is larger than %d MB. Narrow it with workspace_id or without include_embeddings, or run the export command on the server instead", maxInlineExportSize>>20)
+		}
+		t.logger.Error("Export failed", zap.Error(err))
+		return &models.MCPToolResult{
+			IsError: true,
+			Content: []models.MCPContent{{
+				Type: "text",
+				Text: message,
+			}},
+		}, nil
+	}
+
+	// The JSONL is sent once, in the structured content; the text only
+	// summarizes it
+	summary := *response
+	markdown := func() string {
+		return FormatExportResponse(&summary)
+	}
+	compact := func() string {
+		return fmt.Sprintf("exported=%d embeddings=%t", summary.Exported, summary.IncludesEmbeddings)
+	}
+
+	result := structuredResult(format, &summary, markdown, compact)
+	response.Data = data.String()
+	result.StructuredContent = response
+	return result, nil
+}
+
+// maxInlineExportSize is the largest export the export_memories tool returns
+const maxInlineExportSize = 8 << 20 // 8MB
+
+// errExportTooLarge is returned by writes past the limit of a cappedBuilder
+var errExportTooLarge = errors.New("export too large to return inline")
+
+// cappedBuilder collects an export in memory, failing once it grows past limit
+type cappedBuilder struct {
+	strings.Builder
+	limit int
+}
+
+func (b *cappedBuilder) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, errExportTooLarge
+	}
+	return b.Builder.Write(p)
+}
+
+// FormatExportResponse renders an export outcome for display
+func FormatExportResponse(response *models.ExportResponse) string {
+	scope := "all workspaces"
+	if response.WorkspaceID != "" {
+		scope = "workspace " + response.WorkspaceID
+	}
+
+	embeddings := "without embeddings"
+	if response.IncludesEmbeddings {
+		embeddings = "with embeddings"
+	}
+
+	text := fmt.Sprintf("Exported %d memories from %s %s in %d ms\n", response.Exported, scope, embeddings, response.DurationMs)
+	if response.Path != "" {
+		text += fmt.Sprintf("\nWritten to %s\n", response.Path)
+	}
+	return text
+}
+
+// ImportMemoriesTool implements the import_memories MCP admin tool
+type ImportMemoriesTool struct {
+	system *System
+	logger *zap.Logger
+}
+
+// NewImportMemoriesTool creates a new import memories tool
+func NewImportMemoriesTool(system *System, logger *zap.Logger) *ImportMemoriesTool {
+	return &ImportMemoriesTool{
+		system: system,
+		logger: logger,
+	}
+}
+
+func (t *ImportMemoriesTool) Name() string {
+	return models.ToolImportMemories
+}
+
+func (t *ImportMemoriesTool) Description() string {
+	return "Admin: import memories from JSONL written by export_memories, passed inline, re-embedding them when the embedding model differs"
+}
+
+func (t *ImportMemoriesTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"data": map[string]interface{}{
+				"type":        "string",
+				"description": "The JSONL export, header line first",
+				"minLength":   1,
+			},
+			"on_conflict": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{models.ImportConflictSkip, models.ImportConflictOverwrite, models.ImportConflictNewID, models.ImportConflictFail},
+				"description": "What to do with a memory whose ID is already stored: 'skip' keeps the stored one, 'overwrite' replaces it, 'new_id' imports it under a new ID, 'fail' aborts the import (default: skip)",
+				"default":     models.ImportConflictSkip,
+			},
+			"workspace_id": map[string]interface{}{
+				"type":        "string",
+				"description": "Import every memory into this workspace instead of the one it was exported from",
+			},
+			"reembed": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Re-embed every memory even when the export has embeddings from the configured model (default: false)",
+				"default":     false,
+			},
+			"format": formatProperty(),
+		},
+		"required": []string{"data"},
+	}
+}
+
+func (t *ImportMemoriesTool) OutputSchema() map[string]interface{} {
+	return models.JSONSchemaFor(models.ImportResponse{})
+}
+
+func (t *ImportMemoriesTool) Execute(ctx context.Context, args map[string]interface{}) (*models.MCPToolResult, error) {
+	format, err := parseFormat(args)
+	if err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	req := models.ImportRequest{OnConflict: models.ImportConflictSkip}
+	if err := models.DecodeArguments(args, &req); err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	response, err := t.system.ImportMemories(ctx, req, strings.NewReader(req.Data))
+	if err != nil {
+		t.logger.Error("Import failed", zap.Error(err))
+		result := &models.MCPToolResult{
+			IsError: true,
+			Content: []models.MCPContent{{
+				Type: "text",
+				Text: fmt.Sprintf("Import failed: %v", err),
+			}},
+		}
+		// Memories written before the failure stay imported
+		if response != nil {
+			result.StructuredContent = response
+		}
+		return result, nil
+	}
+
+	markdown := func() string {
+		return FormatImportResponse(response)
+	}
+	compact := func() string {
+		return fmt.Sprintf("read=%d imported=%d overwritten=%d renamed=%d skipped=%d reembedded=%d",
+			response.Read, response.Imported, response.Overwritten, response.Renamed, response.Skipped, response.Reembedded)
+	}
+
+	return structuredResult(format, response, markdown, compact), nil
+}
+
+// FormatImportResponse renders an import outcome for display
+func FormatImportResponse(response *models.ImportResponse) string {
+	return fmt.Sprintf(`Import completed
+
+- Memories Read: %d
+- Memories Imported: %d
+- Overwritten: %d
+- Imported Under a New ID: %d
+- Skipped (already stored): %d
+- Re-embedded: %d
+- Duration: %d ms
+`,
+		response.Read, response.Imported, response.Overwritten, response.Renamed,
+		response.Skipped, response.Reembedded, response.DurationMs)
+}
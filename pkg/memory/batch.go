@@ -0,0 +1,368 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amem/mcp-server/pkg/gitrepo"
+	"github.com/amem/mcp-server/pkg/models"
+	"github.com/amem/mcp-server/pkg/services"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// defaultBatchConcurrency is the number of notes constructed at once for a batch
+const defaultBatchConcurrency = 4
+
+// batchItem tracks one memory of a batch through the store pipeline
+type batchItem struct {
+	index       int
+	req         models.StoreMemoryRequest
+	workspaceID string
+	repo        *gitrepo.Repository // Repository the workspace was resolved from
+	mode        string
+	threshold   float32
+	embedding   []float32
+
+	duplicate      *models.Memory // Stored memory the item duplicates
+	duplicateIndex int            // Earlier batch item the item duplicates, or -1
+	similarity     float32
+
+	memory   *models.Memory // New memory built for the item
+	response *models.StoreMemoryResponse
+	err      error
+}
+
+// CreateMemories stores several memories at once. Embeddings are generated in
+// batches, notes are constructed with bounded concurrency and new memories are
+// written to the store together. Each memory succeeds or fails on its own; an
+// error is only returned when the batch cannot be embedded.
+func (s *System) CreateMemories(ctx context.Context, req models.StoreMemoriesBatchRequest) (*models.StoreMemoriesBatchResponse, error) {
+	startTime := time.Now()
+
+	if len(req.Memories) == 0 {
+		return nil, fmt.Errorf("no memories to store")
+	}
+
+	concurrency := req.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+
+	s.logger.Info("Creating memories",
+		zap.Int("count", len(req.Memories)),
+		zap.Int("concurrency", concurrency))
+
+	// Step 1: Validate each request and resolve its workspace and dedupe
+	// settings. Workspaces are registered once a memory is written to them.
+	items := make([]*batchItem, len(req.Memories))
+	var valid []*batchItem
+	for i, memoryReq := range req.Memories {
+		item := &batchItem{index: i, req: memoryReq, duplicateIndex: -1}
+		items[i] = item
+
+		if strings.TrimSpace(memoryReq.Content) == "" {
+			item.err = fmt.Errorf("content is required")
+			continue
+		}
+		if item.workspaceID, item.repo, item.err = s.resolveStoreWorkspace(memoryReq); item.err != nil {
+			continue
+		}
+		if item.mode, item.threshold, item.err = s.dedupeSettings(memoryReq); item.err != nil {
+			continue
+		}
+		valid = append(valid, item)
+	}
+
+	if len(valid) > 0 {
+		// Step 2: Generate every embedding in batched requests
+		texts := make([]string, len(valid))
+		for i, item := range valid {
+			texts[i] = item.req.Content
+		}
+		embeddings, err := s.embeddingService.GenerateBatchEmbeddings(ctx, texts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate embeddings: %w", err)
+		}
+		for i, item := range valid {
+			item.embedding = embeddings[i]
+		}
+
+		// Step 3: Find duplicates among stored memories and earlier items of the batch
+		created := s.classifyBatch(ctx, valid)
+
+		// Step 4: Construct notes for the new memories using the LLM
+		s.constructBatchNotes(ctx, created, concurrency)
+
+		// Step 5: Store the new memories together and link them
+		s.storeBatch(ctx, created)
+
+		// Step 6: Resolve duplicates now that the memories they may refer to are stored
+		for _, item := range valid {
+			if item.err == nil && item.response == nil {
+				s.resolveBatchDuplicate(ctx, items, item)
+			}
+		}
+	}
+
+	response := &models.StoreMemoriesBatchResponse{
+		Results: make([]models.BatchStoreResult, len(items)),
+	}
+	for i, item := range items {
+		result := models.BatchStoreResult{Index: item.index, Response: item.response}
+		if item.err != nil {
+			result.Error = item.err.Error()
+		} else {
+			result.Success = true
+		}
+		response.Results[i] = result
+
+		if !result.Success {
+			response.Failed++
+			continue
+		}
+		switch result.Response.Action {
+		case models.StoreActionCreated:
+			response.Created++
+		case models.StoreActionSkipped:
+			response.Skipped++
+		case models.StoreActionMerged:
+			response.Merged++
+		}
+	}
+	response.DurationMs = time.Since(startTime).Milliseconds()
+
+	s.logger.Info("Memories created",
+		zap.Int("created", response.Created),
+		zap.Int("skipped", response.Skipped),
+		zap.Int("merged", response.Merged),
+		zap.Int("failed", response.Failed),
+		zap.Int64("duration_ms", response.DurationMs))
+
+	return response, nil
+}
+
+// classifyBatch records which items duplicate a stored memory or an earlier
+// item of the batch and returns the items to store as new memories
+func (s *System) classifyBatch(ctx context.Context, items []*batchItem) []*batchItem {
+	metric := s.store.DistanceMetric()
+
+	var created []*batchItem
+	for _, item := range items {
+		if item.mode != models.DedupeForce {
+			duplicate, similarity, err := s.findDuplicate(ctx, item.embedding, item.workspaceID, item.threshold)
+			if err != nil {
+				item.err = err
+				continue
+			}
+			if duplicate != nil {
+				item.duplicate = duplicate
+				item.similarity = similarity
+				continue
+			}
+
+			// The store does not hold the batch yet, so compare with earlier items directly
+			for _, earlier := range created {
+				if earlier.workspaceID != item.workspaceID {
+					continue
+				}
+				similarity := services.Similarity(metric, services.VectorDistance(metric, item.embedding, earlier.embedding))
+				if similarity >= item.threshold && similarity > item.similarity {
+					item.duplicateIndex = earlier.index
+					item.similarity = similarity
+				}
+			}
+			if item.duplicateIndex >= 0 {
+				continue
+			}
+		}
+
+		created = append(created, item)
+	}
+
+	return created
+}
+
+// constructBatchNotes constructs the notes of new memories, running at most
+// concurrency LLM calls at once
+func (s *System) constructBatchNotes(ctx context.Context, items []*batchItem, concurrency int) {
+	now := time.Now()
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for _, item := range items {
+		wg.Add(1)
+		go func(item *batchItem) {
+			defer wg.Done()
+
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				item.err = ctx.Err()
+				return
+			}
+
+			noteResult, err := s.constructNote(ctx, item.req)
+			if err != nil {
+				item.err = fmt.Errorf("failed to construct note: %w", err)
+				return
+			}
+
+			item.memory = &models.Memory{
+				ID:          uuid.New().String(),
+				Content:     item.req.Content,
+				Context:     noteResult.Context,
+				Keywords:    noteResult.Keywords,
+				Tags:        noteResult.Tags,
+				ProjectPath: item.req.ProjectPath, // Keep for backward compatibility
+				WorkspaceID: item.workspaceID,
+				CodeType:    item.req.CodeType,
+				Embedding:   item.embedding,
+				CreatedAt:   now,
+				UpdatedAt:   now,
+				Metadata:    requestMetadata(item.req, item.repo),
+			}
+		}(item)
+	}
+
+	wg.Wait()
+}
+
+// storeBatch links the new memories to stored ones, writes them in a single
+// store call, then links the written memories back and to each other. If the
+// store saved only some of them, those succeed and the rest fail.
+func (s *System) storeBatch(ctx context.Context, items []*batchItem) {
+	var memories []*models.Memory
+	var stored []*batchItem
+	for _, item := range items {
+		if item.err != nil {
+			continue
+		}
+
+		links, err := s.generateLinks(ctx, item.memory)
+		if err != nil {
+			s.logger.Warn("Failed to generate links",
+				zap.Int("index", item.index),
+				zap.Error(err))
+			// Continue without links rather than failing
+		}
+		item.memory.Links = links
+
+		memories = append(memories, item.memory)
+		stored = append(stored, item)
+	}
+
+	if len(memories) == 0 {
+		return
+	}
+
+	if err := s.store.StoreMemories(ctx, memories); err != nil {
+		written := 0
+		var partial *services.PartialWriteError
+		if errors.As(err, &partial) {
+			written = partial.Written
+			err = partial.Err
+		}
+
+		for _, item := range stored[written:] {
+			item.err = fmt.Errorf("failed to store memory: %w", err)
+		}
+		stored = stored[:written]
+	}
+
+	for _, item := range stored {
+		if err := s.workspaceService.RegisterMemoryWorkspace(ctx, item.workspaceID); err != nil {
+			// The memory is written, so report it stored; the next write to
+			// the workspace registers it again
+			s.logger.Warn("Failed to register workspace",
+				zap.String("workspace_id", item.workspaceID),
+				zap.Error(err))
+		}
+		s.notifyChange(item.memory)
+		s.writeBacklinks(ctx, item.memory)
+	}
+
+	// The store did not hold the batch when its links were generated
+	siblingLinks := s.linkBatchSiblings(ctx, stored)
+
+	for _, item := range stored {
+		item.response = &models.StoreMemoryResponse{
+			MemoryID:     item.memory.ID,
+			Keywords:     item.memory.Keywords,
+			Tags:         item.memory.Tags,
+			LinksCreated: len(item.memory.Links) + siblingLinks[item.index],
+			EventEmitted: true,
+			Action:       models.StoreActionCreated,
+		}
+	}
+}
+
+// linkBatchSiblings links every pair of written memories of a batch that are
+// similar enough, in both directions, and returns the number of links each
+// item gained by its index
+func (s *System) linkBatchSiblings(ctx context.Context, items []*batchItem) map[int]int {
+	metric := s.store.DistanceMetric()
+	created := make(map[int]int)
+
+	for i, item := range items {
+		for _, other := range items[i+1:] {
+			similarity := services.Similarity(metric, services.VectorDistance(metric, item.embedding, other.embedding))
+			if similarity <= linkThreshold {
+				continue
+			}
+
+			link := models.MemoryLink{
+				TargetID: other.memory.ID,
+				LinkType: s.determineLinkType(item.memory, other.memory),
+				Strength: similarity,
+				Reason:   s.generateLinkReason(item.memory, other.memory, similarity),
+			}
+			result, err := s.addLink(ctx, item.memory.ID, link)
+			if err != nil {
+				s.logger.Warn("Failed to link batch memories",
+					zap.String("source_id", item.memory.ID),
+					zap.String("target_id", other.memory.ID),
+					zap.Error(err))
+				continue
+			}
+			if result == linkCreated {
+				created[item.index]++
+			}
+
+			result, err = s.addBacklink(ctx, item.memory.ID, link)
+			if err != nil {
+				s.logger.Warn("Failed to write back-link",
+					zap.String("source_id", item.memory.ID),
+					zap.String("target_id", other.memory.ID),
+					zap.Error(err))
+				continue
+			}
+			if result == linkCreated {
+				created[other.index]++
+			}
+		}
+	}
+
+	return created
+}
+
+// resolveBatchDuplicate skips or merges an item that duplicates a stored
+// memory or an earlier item of the batch
+func (s *System) resolveBatchDuplicate(ctx context.Context, items []*batchItem, item *batchItem) {
+	duplicate := item.duplicate
+	if item.duplicateIndex >= 0 {
+		earlier := items[item.duplicateIndex]
+		if earlier.response == nil {
+			item.err = fmt.Errorf("duplicates memory %d, which was not stored", earlier.index)
+			return
+		}
+		duplicate = earlier.memory
+	}
+
+	item.response, item.err = s.handleDuplicate(ctx, item.mode, duplicate, item.similarity, item.req)
+}
@@ -0,0 +1,144 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/amem/mcp-server/pkg/models"
+)
+
+func TestCreateMemoriesReportsEachItem(t *testing.T) {
+	ctx := context.Background()
+	system, store := newTestSystem(t)
+
+	if err := store.StoreMemory(ctx, &models.Memory{
+		ID:          "existing",
+		Content:     "wrap the HTTP call in a retry loop",
+		WorkspaceID: "project-a",
+		Embedding:   []float32{1, 0},
+	}); err != nil {
+		t.Fatalf("Failed to store memory: %v", err)
+	}
+
+	// The test LLM is unreachable, so only items that need no note can succeed
+	response, err := system.CreateMemories(ctx, models.StoreMemoriesBatchRequest{
+		Memories: []models.StoreMemoryRequest{
+			{Content: "add a retry loop around the HTTP call", WorkspaceID: "project-a", Dedupe: models.DedupeSkip},
+			{Content: "", WorkspaceID: "project-a"},
+			{Content: "cache the token", WorkspaceID: "project-b", Dedupe: models.DedupeForce},
+			{Content: "cache the token in memory", WorkspaceID: "project-b", Dedupe: models.DedupeSkip},
+			{Content: "retry", WorkspaceID: "project-a", Dedupe: "replace"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to store batch: %v", err)
+	}
+
+	if len(response.Results) != 5 || response.Skipped != 1 || response.Failed != 4 {
+		t.Fatalf("Expected 1 skipped and 4 failed, got %+v", response)
+	}
+
+	if skipped := response.Results[0]; !skipped.Success || skipped.Response.DuplicateOf != "existing" {
+		t.Errorf("Expected the first memory to duplicate the stored one, got %+v", skipped)
+	}
+
+	expectedErrors := map[int]string{
+		1: "content is required",
+		2: "failed to construct note",
+		3: "duplicates memory 2",
+		4: "invalid dedupe mode",
+	}
+	for index, expected := range expectedErrors {
+		result := response.Results[index]
+		if result.Index != index || result.Success || !strings.Contains(result.Error, expected) {
+			t.Errorf("Expected memory %d to fail with %q, got %+v", index, expected, result)
+		}
+	}
+}
+
+func TestCreateMemoriesRegistersOnlyWrittenWorkspaces(t *testing.T) {
+	ctx := context.Background()
+	system, _ := newTestSystem(t)
+
+	// The test LLM is unreachable, so the note of the memory cannot be constructed
+	response, err := system.CreateMemories(ctx, models.StoreMemoriesBatchRequest{
+		Memories: []models.StoreMemoryRequest{{Content: "cache the token", WorkspaceID: "project-failed"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to store batch: %v", err)
+	}
+	if response.Failed != 1 {
+		t.Fatalf("Expected the memory to fail, got %+v", response)
+	}
+
+	// Skip the LLM by handing notes to the store step directly
+	var items []*batchItem
+	for i, content := range []string{"retry the request", "retry with backoff", "cache the token"} {
+		embedding := []float32{0, 1}
+		if strings.Contains(content, "retry") {
+			embedding = []float32{1, 0}
+		}
+		items = append(items, &batchItem{
+			index:       i,
+			workspaceID: "project-written",
+			embedding:   embedding,
+			memory:      &models.Memory{ID: fmt.Sprintf("memory-%d", i), Content: content, WorkspaceID: "project-written", Embedding: embedding},
+		})
+	}
+	system.storeBatch(ctx, items)
+
+	workspaces, err := system.workspaceService.ListWorkspaces(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list workspaces: %v", err)
+	}
+	if len(workspaces) != 1 || workspaces[0].ID != "project-written" {
+		t.Errorf("Expected only the written workspace to be registered, got %+v", workspaces)
+	}
+
+	// The two retry memories are linked to each other, the third to neither
+	for i, expected := range []string{"memory-1", "memory-0", ""} {
+		item := items[i]
+		if item.err != nil {
+			t.Fatalf("Failed to store memory %d: %v", i, item.err)
+		}
+		memory, err := system.store.GetMemory(ctx, item.memory.ID)
+		if err != nil {
+			t.Fatalf("Failed to get memory: %v", err)
+		}
+		var targets []string
+		for _, link := range memory.Links {
+			targets = append(targets, link.TargetID)
+		}
+		if expected == "" && len(targets) != 0 || expected != "" && (len(targets) != 1 || targets[0] != expected) {
+			t.Errorf("Expected %s to link to %q, got %v", item.memory.ID, expected, targets)
+		}
+		if item.response.LinksCreated != len(targets) {
+			t.Errorf("Expected %s to report %d links, got %d", item.memory.ID, len(targets), item.response.LinksCreated)
+		}
+	}
+}
+
+func TestLinkBatchSiblingsCountsOnlyCreatedLinks(t *testing.T) {
+	ctx := context.Background()
+	system, store := newTestSystem(t)
+
+	// The second memory already links to the first as strongly as possible,
+	// so its back-link changes nothing
+	items := []*batchItem{
+		{index: 0, embedding: []float32{1, 0}, memory: &models.Memory{ID: "first", Content: "retry the request", WorkspaceID: "project", Embedding: []float32{1, 0}}},
+		{index: 1, embedding: []float32{1, 0}, memory: &models.Memory{ID: "second", Content: "retry with backoff", WorkspaceID: "project", Embedding: []float32{1, 0},
+			Links: []models.MemoryLink{{TargetID: "first", LinkType: "pattern", Strength: 1}}}},
+	}
+	for _, item := range items {
+		if err := store.StoreMemory(ctx, item.memory); err != nil {
+			t.Fatalf("Failed to store memory: %v", err)
+		}
+	}
+
+	created := system.linkBatchSiblings(ctx, items)
+	if created[0] != 1 || created[1] != 0 {
+		t.Errorf("Expected one link created on the first memory only, got %v", created)
+	}
+}
@@ -0,0 +1,151 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/amem/mcp-server/pkg/config"
+	"github.com/amem/mcp-server/pkg/models"
+	"github.com/amem/mcp-server/pkg/services"
+	"go.uber.org/zap"
+)
+
+// mergePrompt is the template used to merge a duplicate into an existing memory
+const mergePrompt = "memory_merge"
+
+// Defaults used until SetDedupeConfig is called
+const (
+	defaultDedupeMode      = models.DedupeForce
+	defaultDedupeThreshold = 0.95
+)
+
+// SetDedupeConfig sets the default dedupe mode and similarity threshold for
+// new memories. It must be called before the system is used.
+func (s *System) SetDedupeConfig(cfg config.DedupeConfig) {
+	if cfg.Mode != "" {
+		s.dedupeMode = cfg.Mode
+	}
+	if cfg.Threshold > 0 {
+		s.dedupeThreshold = float32(cfg.Threshold)
+		s.workspaceService.SetDedupeThreshold(s.dedupeThreshold)
+	}
+}
+
+// dedupeSettings resolves the dedupe mode and threshold for a request
+func (s *System) dedupeSettings(req models.StoreMemoryRequest) (string, float32, error) {
+	mode := req.Dedupe
+	if mode == "" {
+		mode = s.dedupeMode
+	}
+
+	switch mode {
+	case models.DedupeSkip, models.DedupeMerge, models.DedupeForce:
+	default:
+		return "", 0, fmt.Errorf("invalid dedupe mode %q: must be skip, merge or force", mode)
+	}
+
+	threshold := req.SimilarityThreshold
+	if threshold <= 0 {
+		threshold = s.dedupeThreshold
+	}
+	if threshold > 1 {
+		return "", 0, fmt.Errorf("similarity threshold must be between 0 and 1")
+	}
+
+	return mode, threshold, nil
+}
+
+// findDuplicate returns the memory in the workspace most similar to the
+// embedding if its similarity reaches the threshold, or nil if there is none
+func (s *System) findDuplicate(ctx context.Context, embedding []float32, workspaceID string, threshold float32) (*models.Memory, float32, error) {
+	memories, distances, err := s.store.SearchSimilar(ctx, embedding, 1, map[string]interface{}{
+		"workspace_id": workspaceID,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search for duplicates: %w", err)
+	}
+
+	if len(memories) == 0 || len(distances) == 0 {
+		return nil, 0, nil
+	}
+
+	similarity := services.Similarity(s.store.DistanceMetric(), distances[0])
+	if similarity < threshold {
+		return nil, 0, nil
+	}
+
+	return memories[0], similarity, nil
+}
+
+// mergeMemory merges the content of a new memory into an existing one using
+// the LLM and saves the result
+func (s *System) mergeMemory(ctx context.Context, existing *models.Memory, req models.StoreMemoryRequest) error {
+	prompt, err := s.promptManager.RenderPrompt(mergePrompt, services.PromptData{
+		Content:  req.Content,
+		CodeType: existing.CodeType,
+		Context:  req.Context,
+		Custom: map[string]interface{}{
+			"ExistingContent":  existing.Content,
+			"ExistingContext":  existing.Context,
+			"ExistingKeywords": strings.Join(existing.Keywords, ", "),
+			"ExistingTags":     strings.Join(existing.Tags, ", "),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	modelConfig, err := s.promptManager.GetModelConfig(mergePrompt)
+	if err != nil {
+		return err
+	}
+
+	response, err := s.llmService.CallWithConfig(ctx, prompt, *modelConfig, true)
+	if err != nil {
+		return fmt.Errorf("LLM call failed: %w", err)
+	}
+
+	var result models.MemoryMergeResult
+	if err := json.Unmarshal([]byte(response), &result); err != nil {
+		return fmt.Errorf("failed to parse LLM response: %w", err)
+	}
+
+	if result.Content == "" {
+		return fmt.Errorf("LLM returned an empty merged content")
+	}
+
+	contentChanged := result.Content != existing.Content
+	existing.Content = result.Content
+	if result.Context != "" {
+		existing.Context = result.Context
+	}
+	existing.Keywords = mergeStrings(existing.Keywords, result.Keywords)
+	existing.Tags = mergeStrings(existing.Tags, result.Tags)
+
+	if err := s.saveMemory(ctx, existing, contentChanged); err != nil {
+		return err
+	}
+
+	s.logger.Info("Merged duplicate memory",
+		zap.String("memory_id", existing.ID),
+		zap.Bool("reembedded", contentChanged))
+
+	return nil
+}
+
+// mergeStrings returns the union of two lists, keeping the order of first appearance
+func mergeStrings(existing, added []string) []string {
+	seen := make(map[string]bool, len(existing)+len(added))
+	merged := make([]string, 0, len(existing)+len(added))
+	for _, value := range append(append([]string{}, existing...), added...) {
+		key := strings.ToLower(value)
+		if value == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		merged = append(merged, value)
+	}
+	return merged
+}
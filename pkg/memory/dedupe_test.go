@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/amem/mcp-server/pkg/config"
+	"github.com/amem/mcp-server/pkg/models"
+	"github.com/amem/mcp-server/pkg/services"
+	"go.uber.org/zap"
+)
+
+// newTestSystem builds a system on a local store with an embedding server that
+// maps content mentioning "retry" and everything else to orthogonal vectors
+func newTestSystem(t *testing.T) (*System, services.MemoryStore) {
+	t.Helper()
+
+	embeddings := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request services.SentenceTransformersRequest
+		json.NewDecoder(r.Body).Decode(&request)
+
+		var response services.SentenceTransformersResponse
+		for _, sentence := range request.Sentences {
+			if strings.Contains(sentence, "retry") {
+				response.Embeddings = append(response.Embeddings, []float32{1, 0})
+			} else {
+				response.Embeddings = append(response.Embeddings, []float32{0, 1})
+			}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(embeddings.Close)
+
+	logger := zap.NewNop()
+	embeddingService, err := services.NewEmbeddingService(config.EmbeddingConfig{
+		Service: "sentence-transformers",
+		Model:   "test-model",
+		URL:     embeddings.URL,
+	}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create embedding service: %v", err)
+	}
+
+	storageConfig := config.StorageConfig{Backend: "local", Path: filepath.Join(t.TempDir(), "memories.json")}
+	store := services.NewLocalStore(storageConfig, services.DistanceCosine, logger)
+	if err := store.Initialize(context.Background(), services.EmbeddingInfo{Model: "test-model", Dimension: 2}); err != nil {
+		t.Fatalf("Failed to initialize store: %v", err)
+	}
+
+	registry := services.NewLocalWorkspaceRegistry(storageConfig, logger)
+	if err := registry.Initialize(context.Background()); err != nil {
+		t.Fatalf("Failed to initialize workspace registry: %v", err)
+	}
+
+	workspaceService := services.NewWorkspaceService(store, registry, logger)
+	promptManager := services.NewPromptManager(config.PromptsConfig{Directory: "../../prompts"}, logger)
+	llmService := services.NewLiteLLMService(config.LiteLLMConfig{}, logger)
+
+	return NewSystem(logger, llmService, promptManager, store, embeddingService, workspaceService), store
+}
+
+func TestCreateMemorySkipsDuplicatesInTheSameWorkspace(t *testing.T) {
+	ctx := context.Background()
+	system, store := newTestSystem(t)
+
+	existing := &models.Memory{
+		ID:          "existing",
+		Content:     "wrap the HTTP call in a retry loop",
+		WorkspaceID: "project-a",
+		Tags:        []string{"go"},
+		Embedding:   []float32{1, 0},
+	}
+	if err := store.StoreMemory(ctx, existing); err != nil {
+		t.Fatalf("Failed to store memory: %v", err)
+	}
+
+	response, err := system.CreateMemory(ctx, models.StoreMemoryRequest{
+		Content:     "add a retry loop around the HTTP call",
+		WorkspaceID: "project-a",
+		Dedupe:      models.DedupeSkip,
+	})
+	if err != nil {
+		t.Fatalf("Failed to store duplicate: %v", err)
+	}
+
+	if response.Action != models.StoreActionSkipped || response.MemoryID != "existing" || response.Similarity < 0.99 {
+		t.Errorf("Expected the duplicate to be skipped in favour of the existing memory, got %+v", response)
+	}
+
+	// Storing into a workspace registers it
+	workspaces, err := system.workspaceService.ListWorkspaces(ctx)
+	if err != nil || len(workspaces) != 1 || workspaces[0].ID != "project-a" || workspaces[0].MemoryCount != 1 {
+		t.Errorf("Expected project-a to be registered with one memory, got %+v, %v", workspaces, err)
+	}
+
+	// Memories in other workspaces are never duplicates
+	duplicate, _, err := system.findDuplicate(ctx, []float32{1, 0}, "project-b", defaultDedupeThreshold)
+	if err != nil || duplicate != nil {
+		t.Errorf("Expected no duplicate in another workspace, got %v, %v", duplicate, err)
+	}
+
+	if _, err := system.CreateMemory(ctx, models.StoreMemoryRequest{
+		Content:     "retry",
+		WorkspaceID: "project-a",
+		Dedupe:      "replace",
+	}); err == nil {
+		t.Error("Expected an invalid dedupe mode to be rejected")
+	}
+}
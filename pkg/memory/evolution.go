@@ -7,9 +7,13 @@ import (
 	"time"
 
 	"github.com/amem/mcp-server/pkg/models"
+	"github.com/amem/mcp-server/pkg/services"
 	"go.uber.org/zap"
 )
 
+// evolutionPrompt is the template used to analyze the memory network
+const evolutionPrompt = "memory_evolution"
+
 // EvolutionManager handles memory network evolution
 type EvolutionManager struct {
 	system *System
@@ -125,7 +129,7 @@ func (e *EvolutionManager) getMemoriesToAnalyze(ctx context.Context, req models.
 		limit = 100 // Default
 	}
 
-	memories, _, err := e.system.chromaDB.SearchSimilar(ctx, queryEmbedding, limit, filters)
+	memories, _, err := e.system.store.SearchSimilar(ctx, queryEmbedding, limit, filters)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search memories: %w", err)
 	}
@@ -148,8 +152,9 @@ func (e *EvolutionManager) evolveBatch(ctx context.Context, memories []*models.M
 		return 0, 0, 0, 0, fmt.Errorf("failed to analyze memory network: %w", err)
 	}
 
-	// Step 3: Apply evolution actions
-	evolved := 0
+	// Step 3: Apply evolution actions. A memory whose context and tags both
+	// change counts as evolved once.
+	changed := make(map[string]bool)
 	linksCreated := 0
 	linksStrengthened := 0
 	contextsUpdated := 0
@@ -168,7 +173,7 @@ func (e *EvolutionManager) evolveBatch(ctx context.Context, memories []*models.M
 			continue
 		}
 		contextsUpdated++
-		evolved++
+		changed[memoryID] = true
 	}
 
 	// Apply tag updates
@@ -179,21 +184,29 @@ func (e *EvolutionManager) evolveBatch(ctx context.Context, memories []*models.M
 				zap.Error(err))
 			continue
 		}
-		evolved++
+		changed[memoryID] = true
 	}
 
 	// Create new connections
 	for _, link := range analysisResult.SuggestedConnections {
-		if err := e.createMemoryLink(ctx, link); err != nil {
+		result, err := e.createMemoryLink(ctx, link)
+		if err != nil {
 			e.logger.Warn("Failed to create memory link",
+				zap.String("source_id", link.SourceID),
 				zap.String("target_id", link.TargetID),
 				zap.Error(err))
 			continue
 		}
-		linksCreated++
+
+		switch result {
+		case linkCreated:
+			linksCreated++
+		case linkStrengthened:
+			linksStrengthened++
+		}
 	}
 
-	return evolved, linksCreated, linksStrengthened, contextsUpdated, nil
+	return len(changed), linksCreated, linksStrengthened, contextsUpdated, nil
 }
 
 // prepareAnalysisContext prepares the context for LLM analysis
@@ -227,34 +240,19 @@ func (e *EvolutionManager) prepareAnalysisContext(memories []*models.Memory) str
 
 // analyzeMemoryNetwork calls LLM to analyze the memory network
 func (e *EvolutionManager) analyzeMemoryNetwork(ctx context.Context, analysisContext string) (*models.EvolutionAnalysisResult, error) {
-	prompt := fmt.Sprintf(`Analyze the following memory network and suggest evolution actions:
-
-%s
-
-Your task is to identify patterns, redundancies, and opportunities for improvement in this memory network.
-Consider:
-1. Memories that should have improved context descriptions
-2. Memories that should be linked together
-3. Tags that should be updated for better categorization
-
-Respond with a JSON object in the following format:
-{
-  "should_evolve": true/false,
-  "actions": ["action1", "action2", ...],
-  "suggested_connections": [
-    {"target_id": "memory_id", "link_type": "pattern|solution|technology", "strength": 0.8, "reason": "reason for connection"}
-  ],
-  "context_updates": {
-    "memory_id": "improved context description"
-  },
-  "tag_updates": {
-    "memory_id": ["tag1", "tag2", "tag3"]
-  }
-}
+	prompt, err := e.system.promptManager.RenderPrompt(evolutionPrompt, services.PromptData{
+		Custom: map[string]interface{}{"AnalysisContext": analysisContext},
+	})
+	if err != nil {
+		return nil, err
+	}
 
-Only suggest changes if they would significantly improve the memory network.`, analysisContext)
+	modelConfig, err := e.system.promptManager.GetModelConfig(evolutionPrompt)
+	if err != nil {
+		return nil, err
+	}
 
-	response, err := e.system.llmService.CallWithRetry(ctx, prompt, true)
+	response, err := e.system.llmService.CallWithConfig(ctx, prompt, *modelConfig, true)
 	if err != nil {
 		return nil, fmt.Errorf("LLM call failed: %w", err)
 	}
@@ -269,38 +267,88 @@ Only suggest changes if they would significantly improve the memory network.`, a
 
 // updateMemoryContext updates a memory's context
 func (e *EvolutionManager) updateMemoryContext(ctx context.Context, memoryID, newContext string) error {
-	// In a real implementation, this would update the memory in ChromaDB
-	// For now, we'll just log it
-	e.logger.Info("Would update memory context",
+	defer e.system.locks.Lock(memoryID)()
+
+	memory, err := e.system.store.GetMemory(ctx, memoryID)
+	if err != nil {
+		return err
+	}
+
+	memory.Context = newContext
+	if err := e.system.saveMemory(ctx, memory, false); err != nil {
+		return err
+	}
+
+	e.logger.Debug("Updated memory context",
 		zap.String("memory_id", memoryID),
 		zap.String("new_context", newContext))
 
-	// Placeholder for actual implementation
 	return nil
 }
 
 // updateMemoryTags updates a memory's tags
 func (e *EvolutionManager) updateMemoryTags(ctx context.Context, memoryID string, newTags []string) error {
-	// In a real implementation, this would update the memory in ChromaDB
-	// For now, we'll just log it
-	e.logger.Info("Would update memory tags",
+	if len(newTags) == 0 {
+		return fmt.Errorf("refusing to clear all tags")
+	}
+
+	defer e.system.locks.Lock(memoryID)()
+
+	memory, err := e.system.store.GetMemory(ctx, memoryID)
+	if err != nil {
+		return err
+	}
+
+	memory.Tags = newTags
+	if err := e.system.saveMemory(ctx, memory, false); err != nil {
+		return err
+	}
+
+	e.logger.Debug("Updated memory tags",
 		zap.String("memory_id", memoryID),
 		zap.Strings("new_tags", newTags))
 
-	// Placeholder for actual implementation
 	return nil
 }
 
-// createMemoryLink creates a new link between memories
-func (e *EvolutionManager) createMemoryLink(ctx context.Context, link models.MemoryLink) error {
-	// In a real implementation, this would create a link in ChromaDB
-	// For now, we'll just log it
-	e.logger.Info("Would create memory link",
+// createMemoryLink adds a suggested link to its source memory, or strengthens
+// the existing link to the same target
+func (e *EvolutionManager) createMemoryLink(ctx context.Context, link models.MemoryLink) (linkResult, error) {
+	if link.SourceID == "" {
+		return linkUnchanged, fmt.Errorf("suggested link has no source memory")
+	}
+	if link.SourceID == link.TargetID {
+		return linkUnchanged, fmt.Errorf("memory cannot link to itself")
+	}
+
+	// Make sure the target exists before pointing at it
+	if _, err := e.system.store.GetMemory(ctx, link.TargetID); err != nil {
+		return linkUnchanged, err
+	}
+
+	result, err := e.system.addLink(ctx, link.SourceID, models.MemoryLink{
+		TargetID: link.TargetID,
+		LinkType: link.LinkType,
+		Strength: link.Strength,
+		Reason:   link.Reason,
+	})
+	if err != nil || result == linkUnchanged {
+		return linkUnchanged, err
+	}
+
+	if _, err := e.system.addBacklink(ctx, link.SourceID, link); err != nil {
+		e.logger.Warn("Failed to write back-link",
+			zap.String("source_id", link.SourceID),
+			zap.String("target_id", link.TargetID),
+			zap.Error(err))
+	}
+
+	e.logger.Debug("Applied memory link",
+		zap.String("source_id", link.SourceID),
 		zap.String("target_id", link.TargetID),
 		zap.String("link_type", link.LinkType),
 		zap.Float32("strength", link.Strength),
-		zap.String("reason", link.Reason))
+		zap.Bool("strengthened", result == linkStrengthened))
 
-	// Placeholder for actual implementation
-	return nil
+	return result, nil
 }
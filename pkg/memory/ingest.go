@@ -0,0 +1,202 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/amem/mcp-server/pkg/config"
+	"github.com/amem/mcp-server/pkg/gitrepo"
+	"github.com/amem/mcp-server/pkg/ingest"
+	"github.com/amem/mcp-server/pkg/models"
+	"go.uber.org/zap"
+)
+
+// ingestBatchSize is the number of chunks stored per batch during ingestion
+const ingestBatchSize = 50
+
+// SetIngestConfig confines ingestion to the configured directories. It must
+// be called before the system is used.
+func (s *System) SetIngestConfig(cfg config.IngestConfig) error {
+	s.ingestRoots = nil
+	for _, root := range cfg.AllowedRoots {
+		resolved, err := resolvePath(root)
+		if err != nil {
+			return fmt.Errorf("invalid ingest root %s: %w", root, err)
+		}
+		s.ingestRoots = append(s.ingestRoots, resolved)
+	}
+	return nil
+}
+
+// checkIngestRoot rejects a directory outside the allowed ingest roots, after
+// following symlinks so that a link inside a root cannot lead out of it
+func (s *System) checkIngestRoot(dir string) error {
+	if s.ingestRoots == nil {
+		return nil
+	}
+
+	resolved, err := resolvePath(dir)
+	if err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+	for _, root := range s.ingestRoots {
+		if rel, err := filepath.Rel(root, resolved); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is outside the directories ingestion is allowed in", dir)
+}
+
+// resolvePath returns the absolute path of dir with symlinks followed
+func resolvePath(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
+
+// IngestRepository stores the source files of a local directory as memories,
+// one per chunk, skipping files excluded by .gitignore. Each memory records
+// its file path and line range in metadata. Chunks already stored are
+// detected as duplicates, so ingesting the same directory again only adds
+// what changed. Only directories inside the allowed ingest roots, if any are
+// set, can be read.
+func (s *System) IngestRepository(ctx context.Context, req models.IngestRequest) (*models.IngestResponse, error) {
+	startTime := time.Now()
+
+	if req.Path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+
+	root, err := filepath.Abs(req.Path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path: %w", err)
+	}
+	if err := s.checkIngestRoot(root); err != nil {
+		return nil, err
+	}
+
+	workspaceID := req.WorkspaceID
+	if workspaceID == "" {
+		workspaceID = root
+	}
+	workspaceID, _, err = s.resolveStoreWorkspace(models.StoreMemoryRequest{WorkspaceID: workspaceID})
+	if err != nil {
+		return nil, err
+	}
+
+	// Chunks record the checkout they were read from, even in a named workspace
+	_, repo := s.workspaceService.ResolveWorkspace(root)
+
+	s.logger.Info("Ingesting repository",
+		zap.String("path", root),
+		zap.String("workspace_id", workspaceID),
+		zap.Bool("dry_run", req.DryRun))
+
+	result, err := ingest.Walk(ctx, root, ingest.Options{MaxChunkLines: req.MaxChunkLines})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", root, err)
+	}
+
+	response := &models.IngestResponse{
+		WorkspaceID:  workspaceID,
+		Files:        result.Files,
+		FilesIgnored: result.Ignored,
+		FilesSkipped: result.Skipped,
+		Chunks:       len(result.Chunks),
+		DryRun:       req.DryRun,
+	}
+
+	if !req.DryRun {
+		for start := 0; start < len(result.Chunks); start += ingestBatchSize {
+			end := start + ingestBatchSize
+			if end > len(result.Chunks) {
+				end = len(result.Chunks)
+			}
+
+			if err := s.ingestChunks(ctx, workspaceID, repo, result.Chunks[start:end], req.Concurrency, response); err != nil {
+				return nil, fmt.Errorf("ingestion stopped after %d of %d chunks: %w", start, len(result.Chunks), err)
+			}
+		}
+	}
+
+	response.DurationMs = time.Since(startTime).Milliseconds()
+
+	s.logger.Info("Repository ingested",
+		zap.String("workspace_id", workspaceID),
+		zap.Int("files", response.Files),
+		zap.Int("chunks", response.Chunks),
+		zap.Int("created", response.Created),
+		zap.Int("skipped", response.Skipped),
+		zap.Int("failed", response.Failed))
+
+	return response, nil
+}
+
+// ingestChunks stores one batch of chunks and adds the outcome to response
+func (s *System) ingestChunks(ctx context.Context, workspaceID string, repo *gitrepo.Repository, chunks []ingest.Chunk, concurrency int, response *models.IngestResponse) error {
+	requests := make([]models.StoreMemoryRequest, len(chunks))
+	for i, chunk := range chunks {
+		metadata := map[string]interface{}{
+			"source":     "ingest",
+			"file_path":  chunk.Path,
+			"start_line": chunk.StartLine,
+			"end_line":   chunk.EndLine,
+		}
+		if chunk.Symbol != "" {
+			metadata["symbol"] = chunk.Symbol
+		}
+		addGitMetadata(metadata, repo)
+
+		requests[i] = models.StoreMemoryRequest{
+			Content:     chunk.Content,
+			WorkspaceID: workspaceID,
+			CodeType:    chunk.Language,
+			Context:     chunkContext(chunk),
+			Metadata:    metadata,
+			// Unchanged chunks are skipped, so a re-ingest only adds what changed
+			Dedupe: models.DedupeSkip,
+		}
+	}
+
+	batch, err := s.CreateMemories(ctx, models.StoreMemoriesBatchRequest{
+		Memories:    requests,
+		Concurrency: concurrency,
+	})
+	if err != nil {
+		return err
+	}
+
+	response.Created += batch.Created
+	response.Skipped += batch.Skipped
+	response.Merged += batch.Merged
+	response.Failed += batch.Failed
+
+	for _, result := range batch.Results {
+		if result.Success {
+			continue
+		}
+		chunk := chunks[result.Index]
+		response.Failures = append(response.Failures, models.IngestFailure{
+			FilePath:  chunk.Path,
+			StartLine: chunk.StartLine,
+			EndLine:   chunk.EndLine,
+			Error:     result.Error,
+		})
+	}
+
+	return nil
+}
+
+// chunkContext describes where a chunk comes from for note construction
+func chunkContext(chunk ingest.Chunk) string {
+	context := fmt.Sprintf("Lines %d-%d of %s", chunk.StartLine, chunk.EndLine, chunk.Path)
+	if chunk.Symbol != "" {
+		context += fmt.Sprintf(" (%s)", chunk.Symbol)
+	}
+	return context
+}
@@ -0,0 +1,44 @@
+package memory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/amem/mcp-server/pkg/config"
+	"github.com/amem/mcp-server/pkg/models"
+)
+
+func TestIngestRepositoryStaysInAllowedRoots(t *testing.T) {
+	ctx := context.Background()
+	system, _ := newTestSystem(t)
+
+	dir := t.TempDir()
+	allowed := filepath.Join(dir, "projects")
+	outside := filepath.Join(dir, "secrets")
+	for _, path := range []string{filepath.Join(allowed, "api"), outside} {
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(allowed, "escape")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := system.SetIngestConfig(config.IngestConfig{AllowedRoots: []string{allowed}}); err != nil {
+		t.Fatalf("Failed to set ingest roots: %v", err)
+	}
+
+	if _, err := system.IngestRepository(ctx, models.IngestRequest{Path: filepath.Join(allowed, "api"), DryRun: true}); err != nil {
+		t.Errorf("Expected a directory inside the root to be ingested, got %v", err)
+	}
+
+	for _, path := range []string{outside, filepath.Join(allowed, "escape"), filepath.Join(allowed, "..")} {
+		_, err := system.IngestRepository(ctx, models.IngestRequest{Path: path, DryRun: true})
+		if err == nil || !strings.Contains(err.Error(), "outside") {
+			t.Errorf("Expected %s to be rejected, got %v", path, err)
+		}
+	}
+}
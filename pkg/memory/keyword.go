@@ -0,0 +1,397 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/amem/mcp-server/pkg/models"
+	"github.com/amem/mcp-server/pkg/services"
+)
+
+// BM25 tuning parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// rrfK dampens the contribution of lower ranks in reciprocal rank fusion
+const rrfK = 60
+
+// stopwords are common English words dropped from queries, so a match on
+// "the" or "how" neither ranks a memory nor explains why it matched
+var stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "but": true, "by": true, "can": true, "do": true, "does": true,
+	"for": true, "from": true, "how": true, "i": true, "if": true, "in": true,
+	"into": true, "is": true, "it": true, "its": true, "my": true, "no": true,
+	"not": true, "of": true, "on": true, "or": true, "our": true, "should": true,
+	"so": true, "that": true, "the": true, "their": true, "then": true,
+	"there": true, "these": true, "this": true, "to": true, "was": true,
+	"we": true, "what": true, "when": true, "where": true, "which": true,
+	"while": true, "who": true, "why": true, "will": true, "with": true,
+	"you": true, "your": true,
+}
+
+// scoredMemory pairs a memory with a score from one retrieval method
+type scoredMemory struct {
+	memory *models.Memory
+	score  float32
+}
+
+// keywordDoc is a memory tokenized for keyword search
+type keywordDoc struct {
+	memory    *models.Memory
+	termFreqs map[string]int
+	length    int
+}
+
+// newKeywordDoc tokenizes the content, keywords and tags of a memory
+func newKeywordDoc(memory *models.Memory) *keywordDoc {
+	tokens := tokenize(indexedText(memory))
+
+	freqs := make(map[string]int)
+	for _, token := range tokens {
+		freqs[token]++
+	}
+
+	return &keywordDoc{memory: memory, termFreqs: freqs, length: len(tokens)}
+}
+
+// keywordIndex is a BM25 index over the content, keywords and tags of a set of memories
+type keywordIndex struct {
+	docs []*keywordDoc
+}
+
+// newKeywordIndex builds a BM25 index over memories
+func newKeywordIndex(memories []*models.Memory) *keywordIndex {
+	docs := make([]*keywordDoc, len(memories))
+	for i, memory := range memories {
+		docs[i] = newKeywordDoc(memory)
+	}
+	return &keywordIndex{docs: docs}
+}
+
+// search returns memories matching at least one query term, best first.
+// Document frequencies are counted over the indexed documents at query time,
+// so an index over any subset of the cached documents scores consistently.
+func (idx *keywordIndex) search(query string, limit int) []scoredMemory {
+	terms := queryTerms(query)
+	n := float64(len(idx.docs))
+
+	totalLen := 0
+	docFreqs := make(map[string]int, len(terms))
+	for _, doc := range idx.docs {
+		totalLen += doc.length
+		for _, term := range terms {
+			if doc.termFreqs[term] > 0 {
+				docFreqs[term]++
+			}
+		}
+	}
+
+	var avgDocLen float64
+	if len(idx.docs) > 0 {
+		avgDocLen = float64(totalLen) / n
+	}
+
+	results := make([]scoredMemory, 0)
+	for _, doc := range idx.docs {
+		var score float64
+		for _, term := range terms {
+			tf := float64(doc.termFreqs[term])
+			if tf == 0 {
+				continue
+			}
+
+			df := float64(docFreqs[term])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			norm := tf + bm25K1*(1-bm25B+bm25B*float64(doc.length)/avgDocLen)
+			score += idf * tf * (bm25K1 + 1) / norm
+		}
+
+		if score > 0 {
+			results = append(results, scoredMemory{memory: doc.memory, score: float32(score)})
+		}
+	}
+
+	// Ties are broken by ID so the order does not depend on the index order
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		return results[i].memory.ID < results[j].memory.ID
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results
+}
+
+// keywordCache keeps every stored memory tokenized for keyword search. It is
+// loaded from the store on first use; afterwards only the memories changed
+// since the previous search are read again. Changes made by this process are
+// reported to invalidate; those made by other processes sharing the store, or
+// by the import and reindex commands, are found by comparing the metadata of
+// the searched memories with the cached copies.
+type keywordCache struct {
+	refreshMu sync.Mutex             // Serializes loads and refreshes
+	docs      map[string]*keywordDoc // Guarded by refreshMu
+
+	mu       sync.Mutex
+	tracking bool            // Whether changes are recorded, from the first load on
+	stale    map[string]bool // Memories changed since the last refresh
+}
+
+// invalidate records that a memory was created, updated, moved or deleted.
+// It is registered as a change listener and never blocks on the store.
+func (c *keywordCache) invalidate(memoryID, workspaceID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.tracking {
+		c.stale[memoryID] = true
+	}
+}
+
+// snapshot brings the cache up to date with the memories matching where and
+// returns the documents matching inScope, which must select the same memories
+func (c *keywordCache) snapshot(ctx context.Context, store services.MemoryStore, where map[string]interface{}, inScope func(*models.Memory) bool) ([]*keywordDoc, error) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	// Changes recorded from here on are picked up by the next refresh
+	c.mu.Lock()
+	c.tracking = true
+	stale := c.stale
+	c.stale = make(map[string]bool)
+	c.mu.Unlock()
+
+	if c.docs == nil {
+		memories, err := store.ListAllMemories(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		docs := make(map[string]*keywordDoc, len(memories))
+		for _, memory := range memories {
+			docs[memory.ID] = newKeywordDoc(memory)
+		}
+		c.docs = docs
+	} else {
+		if err := c.findExternalChanges(ctx, store, where, inScope, stale); err != nil {
+			c.restale(stale)
+			return nil, err
+		}
+		if err := c.refresh(ctx, store, stale); err != nil {
+			c.restale(stale)
+			return nil, err
+		}
+	}
+
+	docs := make([]*keywordDoc, 0, len(c.docs))
+	for _, doc := range c.docs {
+		if inScope(doc.memory) {
+			docs = append(docs, doc)
+		}
+	}
+
+	return docs, nil
+}
+
+// findExternalChanges adds to stale the searched memories that were stored,
+// updated, moved or deleted without a change notification. Only metadata is
+// read: a memory is stale when its update time or workspace differs from the
+// cached copy, and a cached memory in scope that the store no longer lists was
+// deleted or moved out.
+func (c *keywordCache) findExternalChanges(ctx context.Context, store services.MemoryStore, where map[string]interface{}, inScope func(*models.Memory) bool, stale map[string]bool) error {
+	listed := make(map[string]bool)
+	err := store.ScanMemories(ctx, where, func(memory *models.Memory) error {
+		listed[memory.ID] = true
+		doc, ok := c.docs[memory.ID]
+		if !ok || !doc.memory.UpdatedAt.Equal(memory.UpdatedAt) || doc.memory.WorkspaceID != memory.WorkspaceID {
+			stale[memory.ID] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for id, doc := range c.docs {
+		if !listed[id] && inScope(doc.memory) {
+			stale[id] = true
+		}
+	}
+
+	return nil
+}
+
+// refresh reads the stale memories again, dropping those the store no longer
+// returns
+func (c *keywordCache) refresh(ctx context.Context, store services.MemoryStore, stale map[string]bool) error {
+	if len(stale) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(stale))
+	for id := range stale {
+		ids = append(ids, id)
+	}
+
+	memories, err := store.GetMemories(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		delete(c.docs, id)
+	}
+	for _, memory := range memories {
+		memory.Embedding = nil
+		c.docs[memory.ID] = newKeywordDoc(memory)
+	}
+
+	return nil
+}
+
+// restale records memories again after a failed refresh, so the next search
+// retries them
+func (c *keywordCache) restale(ids map[string]bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id := range ids {
+		c.stale[id] = true
+	}
+}
+
+// matchingTerm returns the first query term found in a memory's indexed text, if any
+func matchingTerm(query string, memory *models.Memory) string {
+	present := make(map[string]bool)
+	for _, token := range tokenize(indexedText(memory)) {
+		present[token] = true
+	}
+
+	for _, term := range queryTerms(query) {
+		if present[term] {
+			return term
+		}
+	}
+	return ""
+}
+
+// indexedText returns the parts of a memory covered by keyword search
+func indexedText(memory *models.Memory) string {
+	return memory.Content + " " + strings.Join(memory.Keywords, " ") + " " + strings.Join(memory.Tags, " ")
+}
+
+// tokenize lowercases text and splits it into identifier-friendly tokens.
+// Identifiers containing '.', '/', '-' or ':' such as package paths and error
+// codes are kept whole and also split into their parts, so both "net/http" and
+// "http" match.
+func tokenize(text string) []string {
+	isSeparator := func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("_./-:", r)
+	}
+
+	var tokens []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), isSeparator) {
+		word = strings.Trim(word, "./-:")
+		if word == "" {
+			continue
+		}
+
+		tokens = append(tokens, word)
+
+		parts := strings.FieldsFunc(word, func(r rune) bool {
+			return strings.ContainsRune("./-:", r)
+		})
+		if len(parts) > 1 {
+			tokens = append(tokens, parts...)
+		}
+	}
+
+	return tokens
+}
+
+// queryTerms returns the distinct tokens of a query that are not stopwords
+func queryTerms(query string) []string {
+	terms := make([]string, 0)
+	for _, term := range uniqueTokens(tokenize(query)) {
+		if !stopwords[term] {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// uniqueTokens removes duplicate tokens while preserving order
+func uniqueTokens(tokens []string) []string {
+	seen := make(map[string]bool, len(tokens))
+	unique := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if !seen[token] {
+			seen[token] = true
+			unique = append(unique, token)
+		}
+	}
+	return unique
+}
+
+// fuseRankings combines ranked result lists with reciprocal rank fusion. Scores
+// are normalised so a memory ranked first by every list scores 1.0.
+func fuseRankings(rankings ...[]scoredMemory) []scoredMemory {
+	if len(rankings) == 0 {
+		return nil
+	}
+
+	fused := make(map[string]*scoredMemory)
+	order := make([]string, 0)
+	maxScore := float32(len(rankings)) / float32(rrfK+1)
+
+	for _, ranking := range rankings {
+		for rank, result := range ranking {
+			contribution := 1 / float32(rrfK+rank+1) / maxScore
+
+			if existing, ok := fused[result.memory.ID]; ok {
+				existing.score += contribution
+				continue
+			}
+
+			fused[result.memory.ID] = &scoredMemory{memory: result.memory, score: contribution}
+			order = append(order, result.memory.ID)
+		}
+	}
+
+	results := make([]scoredMemory, 0, len(order))
+	for _, id := range order {
+		results = append(results, *fused[id])
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].score > results[j].score
+	})
+
+	return results
+}
+
+// normalizeScores rescales rank scores so the best result scores 1.0
+func normalizeScores(results []scoredMemory) []scoredMemory {
+	if len(results) == 0 || results[0].score <= 0 {
+		return results
+	}
+
+	top := results[0].score
+	normalized := make([]scoredMemory, len(results))
+	for i, result := range results {
+		normalized[i] = scoredMemory{memory: result.memory, score: result.score / top}
+	}
+
+	return normalized
+}
@@ -0,0 +1,260 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/amem/mcp-server/pkg/models"
+	"github.com/amem/mcp-server/pkg/services"
+)
+
+func TestTokenizeKeepsIdentifiers(t *testing.T) {
+	tokens := tokenize("Fix net/http ERR_CONN_RESET in handleRequest()")
+
+	want := map[string]bool{"net/http": true, "net": true, "http": true, "err_conn_reset": true, "handlerequest": true}
+	for _, token := range tokens {
+		delete(want, token)
+	}
+
+	if len(want) > 0 {
+		t.Errorf("Expected tokens %v to be present in %v", want, tokens)
+	}
+}
+
+func TestKeywordIndexRanksExactIdentifier(t *testing.T) {
+	memories := []*models.Memory{
+		{ID: "generic", Content: "Handling HTTP errors when a request fails"},
+		{ID: "exact", Content: "ECONNRESET from net/http client", Keywords: []string{"ECONNRESET"}},
+		{ID: "unrelated", Content: "Sorting a slice in place"},
+	}
+
+	results := newKeywordIndex(memories).search("ECONNRESET", 10)
+
+	if len(results) != 1 || results[0].memory.ID != "exact" {
+		t.Fatalf("Expected only 'exact' to match, got %+v", results)
+	}
+}
+
+func TestFuseRankings(t *testing.T) {
+	a := &models.Memory{ID: "a"}
+	b := &models.Memory{ID: "b"}
+	c := &models.Memory{ID: "c"}
+
+	vector := []scoredMemory{{memory: a, score: 0.9}, {memory: b, score: 0.8}}
+	keyword := []scoredMemory{{memory: b, score: 5}, {memory: c, score: 3}}
+
+	fused := fuseRankings(vector, keyword)
+
+	if len(fused) != 3 || fused[0].memory.ID != "b" {
+		t.Fatalf("Expected 'b' to rank first when found by both searches, got %+v", fused)
+	}
+
+	if fused[0].score > 1 {
+		t.Errorf("Expected fused scores to be at most 1.0, got %f", fused[0].score)
+	}
+}
+
+func TestHybridSearchAppliesMinRelevanceToVectorMatches(t *testing.T) {
+	ctx := context.Background()
+	system, store := newTestSystem(t)
+
+	for _, memory := range []*models.Memory{
+		{ID: "similar", Content: "retry the request", WorkspaceID: "project", Embedding: []float32{1, 0}},
+		{ID: "keyword-only", Content: "ECONNRESET in the handler", WorkspaceID: "project", Embedding: []float32{0.6, 0.8}},
+		{ID: "vector-only", Content: "cache the token", WorkspaceID: "project", Embedding: []float32{0.6, 0.8}},
+	} {
+		if err := store.StoreMemory(ctx, memory); err != nil {
+			t.Fatalf("Failed to store memory: %v", err)
+		}
+	}
+
+	retrieve := func(query string, minRelevance float32) map[string]models.RetrievedMemory {
+		t.Helper()
+		response, err := system.RetrieveMemories(ctx, models.RetrieveMemoryRequest{
+			Query:        query,
+			WorkspaceID:  "project",
+			MaxResults:   5,
+			MinRelevance: minRelevance,
+			SearchMode:   models.SearchModeHybrid,
+		})
+		if err != nil {
+			t.Fatalf("Retrieve failed: %v", err)
+		}
+		results := make(map[string]models.RetrievedMemory)
+		for _, memory := range response.Memories {
+			results[memory.ID] = memory
+		}
+		return results
+	}
+
+	results := retrieve("retry ECONNRESET", 0.7)
+	if len(results) != 2 || results["similar"].ID == "" || results["keyword-only"].ID == "" {
+		t.Fatalf("Expected the dissimilar vector match to fall below min_relevance and the keyword match to stay, got %+v", results)
+	}
+	if similar := results["similar"]; similar.RelevanceScore != 1 || similar.RankScore != 1 {
+		t.Errorf("Expected similarity and fused rank 1, got %f and %f", similar.RelevanceScore, similar.RankScore)
+	}
+	if score := results["keyword-only"].RelevanceScore; score < 0.59 || score > 0.61 {
+		t.Errorf("Expected the keyword match to report its own similarity, got %f", score)
+	}
+
+	if results = retrieve("retry ECONNRESET", 0.5); len(results) != 3 {
+		t.Fatalf("Expected every memory with a lower threshold, got %+v", results)
+	}
+
+	if terms := queryTerms("how does the handler work"); len(terms) != 2 || terms[0] != "handler" {
+		t.Errorf("Expected stopwords to be dropped, got %v", terms)
+	}
+}
+
+func TestKeywordSearchKeepsDissimilarMatches(t *testing.T) {
+	ctx := context.Background()
+	system, store := newTestSystem(t)
+
+	// The query embeds as [0 1], orthogonal to the first memory; the second
+	// was embedded by another model and cannot be compared at all
+	for _, memory := range []*models.Memory{
+		{ID: "orthogonal", Content: "ECONNRESET in parseHeader", WorkspaceID: "project", Embedding: []float32{1, 0}},
+		{ID: "mismatched", Content: "parseHeader returns ECONNRESET", WorkspaceID: "project", Embedding: []float32{1, 0, 0}},
+		{ID: "unrelated", Content: "cache the token", WorkspaceID: "project", Embedding: []float32{0, 1}},
+	} {
+		if err := store.StoreMemory(ctx, memory); err != nil {
+			t.Fatalf("Failed to store memory: %v", err)
+		}
+	}
+
+	response, err := system.RetrieveMemories(ctx, models.RetrieveMemoryRequest{
+		Query:       "ECONNRESET",
+		WorkspaceID: "project",
+		SearchMode:  models.SearchModeKeyword,
+	})
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+
+	found := make(map[string]float32)
+	for _, memory := range response.Memories {
+		found[memory.ID] = memory.RelevanceScore
+	}
+	orthogonal, hasOrthogonal := found["orthogonal"]
+	mismatched, hasMismatched := found["mismatched"]
+	if len(found) != 2 || !hasOrthogonal || !hasMismatched {
+		t.Fatalf("Expected both keyword matches despite the default min_relevance, got %v", found)
+	}
+	if orthogonal != 0 || mismatched != 0 {
+		t.Errorf("Expected both matches to report no similarity, got %v", found)
+	}
+}
+
+// listCountingStore counts full listings of the store
+type listCountingStore struct {
+	services.MemoryStore
+	lists int
+}
+
+func (s *listCountingStore) ListAllMemories(ctx context.Context, where map[string]interface{}) ([]*models.Memory, error) {
+	s.lists++
+	return s.MemoryStore.ListAllMemories(ctx, where)
+}
+
+func TestKeywordSearchKeepsIndexUpToDate(t *testing.T) {
+	ctx := context.Background()
+	system, store := newTestSystem(t)
+	counting := &listCountingStore{MemoryStore: store}
+	system.store = counting
+
+	stored := &models.Memory{ID: "first", Content: "ECONNRESET in the handler", WorkspaceID: "project", Embedding: []float32{0, 1}}
+	if err := store.StoreMemory(ctx, stored); err != nil {
+		t.Fatalf("Failed to store memory: %v", err)
+	}
+
+	search := func() []string {
+		t.Helper()
+		results, err := system.keywordSearch(ctx, models.RetrieveMemoryRequest{Query: "ECONNRESET", MaxResults: 5}, map[string]float32{"project": 1}, map[string]interface{}{"workspace_id": "project"})
+		if err != nil {
+			t.Fatalf("Keyword search failed: %v", err)
+		}
+		ids := make([]string, len(results))
+		for i, result := range results {
+			ids[i] = result.memory.ID
+		}
+		return ids
+	}
+
+	if ids := search(); len(ids) != 1 || ids[0] != "first" {
+		t.Fatalf("Expected the stored memory to match, got %v", ids)
+	}
+
+	added := &models.Memory{ID: "second", Content: "ECONNRESET again", WorkspaceID: "project", Embedding: []float32{0, 1}}
+	if err := store.StoreMemory(ctx, added); err != nil {
+		t.Fatalf("Failed to store memory: %v", err)
+	}
+	system.notifyChange(added)
+	if _, err := system.DeleteMemory(ctx, "first"); err != nil {
+		t.Fatalf("Failed to delete memory: %v", err)
+	}
+
+	if ids := search(); len(ids) != 1 || ids[0] != "second" {
+		t.Errorf("Expected the index to follow the store, got %v", ids)
+	}
+	if counting.lists != 1 {
+		t.Errorf("Expected the store to be listed once, got %d", counting.lists)
+	}
+}
+
+func TestKeywordSearchNoticesChangesFromOtherProcesses(t *testing.T) {
+	ctx := context.Background()
+	system, store := newTestSystem(t)
+
+	createdAt := time.Now().Add(-time.Hour)
+	for _, memory := range []*models.Memory{
+		{ID: "edited", Content: "ECONNRESET in the handler", WorkspaceID: "project", Embedding: []float32{0, 1}, CreatedAt: createdAt, UpdatedAt: createdAt},
+		{ID: "deleted", Content: "ECONNRESET on shutdown", WorkspaceID: "project", Embedding: []float32{0, 1}, CreatedAt: createdAt, UpdatedAt: createdAt},
+	} {
+		if err := store.StoreMemory(ctx, memory); err != nil {
+			t.Fatalf("Failed to store memory: %v", err)
+		}
+	}
+
+	search := func() []string {
+		t.Helper()
+		response, err := system.RetrieveMemories(ctx, models.RetrieveMemoryRequest{Query: "ECONNRESET", WorkspaceID: "project", SearchMode: models.SearchModeKeyword})
+		if err != nil {
+			t.Fatalf("Retrieve failed: %v", err)
+		}
+		var ids []string
+		for _, memory := range response.Memories {
+			ids = append(ids, memory.ID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	if ids := search(); len(ids) != 2 {
+		t.Fatalf("Expected both memories to match, got %v", ids)
+	}
+
+	// Write to the store directly, as another server sharing it would, so no
+	// change is reported to the cache
+	edited, err := store.GetMemory(ctx, "edited")
+	if err != nil {
+		t.Fatalf("Failed to get memory: %v", err)
+	}
+	edited.Content = "timeout in the handler"
+	edited.UpdatedAt = time.Now()
+	if err := store.UpdateMemory(ctx, edited); err != nil {
+		t.Fatalf("Failed to update memory: %v", err)
+	}
+	if err := store.DeleteMemories(ctx, []string{"deleted"}); err != nil {
+		t.Fatalf("Failed to delete memory: %v", err)
+	}
+	if err := store.StoreMemory(ctx, &models.Memory{ID: "added", Content: "ECONNRESET again", WorkspaceID: "project", Embedding: []float32{0, 1}, CreatedAt: createdAt, UpdatedAt: createdAt}); err != nil {
+		t.Fatalf("Failed to store memory: %v", err)
+	}
+
+	if ids := search(); len(ids) != 1 || ids[0] != "added" {
+		t.Errorf("Expected the cache to follow the store, got %v", ids)
+	}
+}
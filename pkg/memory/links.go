@@ -0,0 +1,161 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/amem/mcp-server/pkg/models"
+	"go.uber.org/zap"
+)
+
+// linkThreshold is the similarity above which two memories are linked
+const linkThreshold = 0.7
+
+// linkResult describes what merging a link did to a memory's links
+type linkResult int
+
+const (
+	linkUnchanged linkResult = iota
+	linkCreated
+	linkStrengthened
+)
+
+// mergeLink adds link to links, or replaces the existing link to the same target
+// when the new one is stronger
+func mergeLink(links []models.MemoryLink, link models.MemoryLink) ([]models.MemoryLink, linkResult) {
+	for i, existing := range links {
+		if existing.TargetID != link.TargetID {
+			continue
+		}
+
+		if existing.Strength >= link.Strength {
+			return links, linkUnchanged
+		}
+
+		merged := make([]models.MemoryLink, len(links))
+		copy(merged, links)
+		merged[i] = link
+		return merged, linkStrengthened
+	}
+
+	return append(links, link), linkCreated
+}
+
+// addLink merges a link into a memory's links and saves the memory if they
+// changed, holding the memory's lock throughout
+func (s *System) addLink(ctx context.Context, memoryID string, link models.MemoryLink) (linkResult, error) {
+	defer s.locks.Lock(memoryID)()
+
+	memory, err := s.store.GetMemory(ctx, memoryID)
+	if err != nil {
+		return linkUnchanged, err
+	}
+
+	links, result := mergeLink(memory.Links, link)
+	if result == linkUnchanged {
+		return linkUnchanged, nil
+	}
+
+	memory.Links = links
+	if err := s.saveMemory(ctx, memory, false); err != nil {
+		return linkUnchanged, err
+	}
+
+	return result, nil
+}
+
+// addBacklink records the reverse of a link on its target memory so the network
+// can be walked in both directions, reporting what it did to the target's links
+func (s *System) addBacklink(ctx context.Context, sourceID string, link models.MemoryLink) (linkResult, error) {
+	result, err := s.addLink(ctx, link.TargetID, models.MemoryLink{
+		TargetID: sourceID,
+		LinkType: link.LinkType,
+		Strength: link.Strength,
+		Reason:   link.Reason,
+	})
+	if err != nil {
+		return linkUnchanged, fmt.Errorf("failed to link target back: %w", err)
+	}
+	return result, nil
+}
+
+// writeBacklinks adds back-links for every outgoing link of a newly stored memory
+func (s *System) writeBacklinks(ctx context.Context, memory *models.Memory) {
+	for _, link := range memory.Links {
+		if _, err := s.addBacklink(ctx, memory.ID, link); err != nil {
+			s.logger.Warn("Failed to write back-link",
+				zap.String("source_id", memory.ID),
+				zap.String("target_id", link.TargetID),
+				zap.Error(err))
+		}
+	}
+}
+
+// removeInboundLinks strips links pointing at deleted memories from every
+// other memory. Back-links are missing for memories stored before links were
+// written both ways or whose back-link write failed, so the links held by the
+// deleted memories cannot be trusted to name every source and the store's
+// metadata is scanned instead. It returns the number of links removed.
+func (s *System) removeInboundLinks(ctx context.Context, deleted map[string]bool) int {
+	var sources []string
+	err := s.store.ScanMemories(ctx, nil, func(memory *models.Memory) error {
+		if deleted[memory.ID] {
+			return nil
+		}
+		for _, link := range memory.Links {
+			if deleted[link.TargetID] {
+				sources = append(sources, memory.ID)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Warn("Failed to scan for inbound links", zap.Error(err))
+		return 0
+	}
+
+	removed := 0
+	for _, sourceID := range sources {
+		dropped, err := s.removeLinksTo(ctx, sourceID, deleted)
+		if err != nil {
+			s.logger.Warn("Failed to remove inbound links",
+				zap.String("memory_id", sourceID),
+				zap.Error(err))
+			continue
+		}
+		removed += dropped
+	}
+
+	return removed
+}
+
+// removeLinksTo strips a memory's links to deleted memories, holding the
+// memory's lock throughout, and returns how many it removed
+func (s *System) removeLinksTo(ctx context.Context, memoryID string, deleted map[string]bool) (int, error) {
+	defer s.locks.Lock(memoryID)()
+
+	memory, err := s.store.GetMemory(ctx, memoryID)
+	if err != nil {
+		return 0, err
+	}
+
+	kept := make([]models.MemoryLink, 0, len(memory.Links))
+	for _, link := range memory.Links {
+		if !deleted[link.TargetID] {
+			kept = append(kept, link)
+		}
+	}
+
+	dropped := len(memory.Links) - len(kept)
+	if dropped == 0 {
+		return 0, nil
+	}
+
+	memory.Links = kept
+	if err := s.saveMemory(ctx, memory, false); err != nil {
+		return 0, err
+	}
+
+	return dropped, nil
+}
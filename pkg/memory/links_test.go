@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/amem/mcp-server/pkg/models"
+)
+
+func TestDeleteMemoryRemovesLinksWithoutBacklinks(t *testing.T) {
+	ctx := context.Background()
+	system, store := newTestSystem(t)
+
+	// The target was stored before links were written both ways, so it does
+	// not know about the memory linking to it
+	for _, memory := range []*models.Memory{
+		{ID: "target", Content: "retry", WorkspaceID: "project-a", Embedding: []float32{1, 0}},
+		{ID: "source", Content: "backoff", WorkspaceID: "project-b", Embedding: []float32{0, 1}, Links: []models.MemoryLink{
+			{TargetID: "target", LinkType: "solution", Strength: 0.8},
+			{TargetID: "other", LinkType: "solution", Strength: 0.8},
+		}},
+	} {
+		if err := store.StoreMemory(ctx, memory); err != nil {
+			t.Fatalf("Failed to store memory: %v", err)
+		}
+	}
+
+	response, err := system.DeleteMemory(ctx, "target")
+	if err != nil {
+		t.Fatalf("Failed to delete memory: %v", err)
+	}
+	if response.LinksRemoved != 1 {
+		t.Errorf("Expected one inbound link to be removed, got %d", response.LinksRemoved)
+	}
+
+	source, err := store.GetMemory(ctx, "source")
+	if err != nil {
+		t.Fatalf("Failed to get memory: %v", err)
+	}
+	if len(source.Links) != 1 || source.Links[0].TargetID != "other" {
+		t.Errorf("Expected only the link to the deleted memory to be removed, got %+v", source.Links)
+	}
+}
+
+func TestConcurrentStoresKeepEveryBacklink(t *testing.T) {
+	ctx := context.Background()
+	system, store := newTestSystem(t)
+
+	if err := store.StoreMemory(ctx, &models.Memory{ID: "target", Content: "retry", WorkspaceID: "project", Embedding: []float32{1, 0}}); err != nil {
+		t.Fatalf("Failed to store memory: %v", err)
+	}
+
+	const n = 20
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			memory := &models.Memory{
+				ID:          fmt.Sprintf("source-%d", i),
+				Content:     "retry again",
+				WorkspaceID: "project",
+				Embedding:   []float32{1, 0},
+				Links:       []models.MemoryLink{{TargetID: "target", LinkType: "pattern", Strength: 0.9}},
+			}
+			if err := store.StoreMemory(ctx, memory); err != nil {
+				t.Errorf("Failed to store memory: %v", err)
+				return
+			}
+			system.writeBacklinks(ctx, memory)
+		}(i)
+	}
+	wg.Wait()
+
+	target, err := store.GetMemory(ctx, "target")
+	if err != nil {
+		t.Fatalf("Failed to get memory: %v", err)
+	}
+	if len(target.Links) != n {
+		t.Errorf("Expected %d back-links, got %d", n, len(target.Links))
+	}
+}
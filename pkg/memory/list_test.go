@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/amem/mcp-server/pkg/models"
+)
+
+func TestListMemoriesCursorSurvivesInserts(t *testing.T) {
+	ctx := context.Background()
+	system, store := newTestSystem(t)
+
+	// Two memories share a timestamp, so the ID has to break the tie
+	base := time.Now().Add(-time.Hour)
+	var memories []*models.Memory
+	for i, offset := range []int{0, 1, 1, 2, 3} {
+		createdAt := base.Add(time.Duration(offset) * time.Minute)
+		memories = append(memories, &models.Memory{
+			ID: fmt.Sprintf("m%d", i), Content: fmt.Sprintf("note %d", i), WorkspaceID: "api",
+			Tags: []string{"go"}, CreatedAt: createdAt, UpdatedAt: createdAt, Embedding: []float32{0, 1},
+		})
+	}
+	if err := store.StoreMemories(ctx, memories); err != nil {
+		t.Fatalf("Failed to store memories: %v", err)
+	}
+
+	first, err := system.ListMemories(ctx, models.ListMemoriesRequest{WorkspaceID: "api", Tags: []string{"go"}, Limit: 2})
+	if err != nil {
+		t.Fatalf("Failed to list memories: %v", err)
+	}
+	if len(first.Memories) != 2 || first.Memories[0].ID != "m4" || first.Memories[1].ID != "m3" || first.NextCursor == "" {
+		t.Fatalf("Unexpected first page %+v", first)
+	}
+	if first.Memories[0].Content != "note 4" || first.Memories[0].Embedding != nil {
+		t.Errorf("Expected content without embeddings, got %+v", first.Memories[0])
+	}
+
+	// A newer memory stored between pages must not shift the next page
+	newest := &models.Memory{ID: "m9", Content: "new", WorkspaceID: "api", Tags: []string{"go"},
+		CreatedAt: time.Now(), UpdatedAt: time.Now(), Embedding: []float32{0, 1}}
+	if err := store.StoreMemory(ctx, newest); err != nil {
+		t.Fatalf("Failed to store memory: %v", err)
+	}
+
+	var ids []string
+	cursor := first.NextCursor
+	for cursor != "" {
+		page, err := system.ListMemories(ctx, models.ListMemoriesRequest{WorkspaceID: "api", Tags: []string{"go"}, Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("Failed to list memories: %v", err)
+		}
+		for _, memory := range page.Memories {
+			ids = append(ids, memory.ID)
+		}
+		cursor = page.NextCursor
+	}
+	if fmt.Sprint(ids) != "[m2 m1 m0]" {
+		t.Errorf("Expected the remaining memories once each, got %v", ids)
+	}
+
+	if _, err := system.ListMemories(ctx, models.ListMemoriesRequest{Cursor: "bm90LWpzb24"}); err == nil {
+		t.Error("Expected an invalid cursor to fail")
+	}
+}
@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/amem/mcp-server/pkg/models"
+)
+
+// formatProperty is the input schema of the format argument shared by every tool
+func formatProperty() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "string",
+		"enum":        []string{models.OutputFormatMarkdown, models.OutputFormatJSON, models.OutputFormatCompact},
+		"description": "Format of the text content: 'markdown' for reading (default), 'json' for the structured result, or 'compact' for one line per item",
+		"default":     models.OutputFormatMarkdown,
+	}
+}
+
+// parseFormat reads the format argument, defaulting to markdown
+func parseFormat(args map[string]interface{}) (string, error) {
+	format, ok := args["format"].(string)
+	if !ok || format == "" {
+		return models.OutputFormatMarkdown, nil
+	}
+
+	switch format {
+	case models.OutputFormatMarkdown, models.OutputFormatJSON, models.OutputFormatCompact:
+		return format, nil
+	default:
+		return "", fmt.Errorf("'format' must be one of %s, %s or %s",
+			models.OutputFormatMarkdown, models.OutputFormatJSON, models.OutputFormatCompact)
+	}
+}
+
+// argumentErrorResult reports invalid tool arguments
+func argumentErrorResult(err error) *models.MCPToolResult {
+	return &models.MCPToolResult{
+		IsError: true,
+		Content: []models.MCPContent{{
+			Type: "text",
+			Text: fmt.Sprintf("Error: %v", err),
+		}},
+	}
+}
+
+// structuredResult builds a tool result carrying data as structured content,
+// with text content rendered in the requested format
+func structuredResult(format string, data interface{}, markdown, compact func() string) *models.MCPToolResult {
+	var text string
+	switch format {
+	case models.OutputFormatJSON:
+		encoded, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return &models.MCPToolResult{
+				IsError: true,
+				Content: []models.MCPContent{{
+					Type: "text",
+					Text: fmt.Sprintf("Error serializing response: %v", err),
+				}},
+			}
+		}
+		text = string(encoded)
+	case models.OutputFormatCompact:
+		text = compact()
+	default:
+		text = markdown()
+	}
+
+	return &models.MCPToolResult{
+		Content: []models.MCPContent{{
+			Type: "text",
+			Text: text,
+		}},
+		StructuredContent: data,
+	}
+}
+
+// withoutEmbedding returns a copy of a memory without its embedding, which is
+// large and of no use to clients
+func withoutEmbedding(memory models.Memory) models.Memory {
+	memory.Embedding = nil
+	return memory
+}
@@ -0,0 +1,279 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/amem/mcp-server/pkg/models"
+	"github.com/amem/mcp-server/pkg/services"
+	"go.uber.org/zap"
+)
+
+// defaultReindexBatchSize is the number of memories read and re-embedded at a time
+const defaultReindexBatchSize = 100
+
+// maxCatchUpPasses bounds how often a reindex re-reads the live collection for
+// memories written while it ran before giving up on switching
+const maxCatchUpPasses = 5
+
+// Reindex re-embeds every memory with the configured embedding model into a
+// staging collection and, if requested, switches to it. The staging collection
+// is named after the model, so rerunning after a failure resumes: memories
+// already staged and unchanged since are skipped. Memories written while the
+// reindex runs are caught up with before switching. progress, if not nil, is
+// called after each batch.
+func (s *System) Reindex(ctx context.Context, req models.ReindexRequest, progress func(models.ReindexResponse)) (*models.ReindexResponse, error) {
+	startTime := time.Now()
+
+	store, ok := s.store.(services.ReindexableStore)
+	if !ok {
+		return nil, fmt.Errorf("storage backend does not support reindexing")
+	}
+
+	batchSize := req.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultReindexBatchSize
+	}
+
+	info, err := s.embeddingService.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.ReindexResponse{
+		Staging:            stagingName(info),
+		EmbeddingModel:     info.Model,
+		EmbeddingDimension: info.Dimension,
+	}
+
+	staging, err := store.OpenStaging(ctx, response.Staging, info)
+	if err != nil {
+		return nil, err
+	}
+
+	// Memories staged by an earlier run, keyed by ID
+	existing, err := staging.ListAllMemories(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list staged memories: %w", err)
+	}
+	staged := make(map[string]time.Time, len(existing))
+	for _, memory := range existing {
+		staged[memory.ID] = memory.UpdatedAt
+	}
+
+	s.logger.Info("Reindex started",
+		zap.String("staging", response.Staging),
+		zap.String("embedding_model", info.Model),
+		zap.Int("embedding_dimension", info.Dimension),
+		zap.Int("already_staged", len(staged)))
+
+	for offset := 0; ; offset += batchSize {
+		page, err := s.store.ListMemories(ctx, nil, batchSize, offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read memories at offset %d: %w", offset, err)
+		}
+
+		pending := make([]*models.Memory, 0, len(page))
+		for _, memory := range page {
+			response.Scanned++
+
+			if updatedAt, ok := staged[memory.ID]; ok && !memory.UpdatedAt.After(updatedAt) {
+				response.Unchanged++
+				continue
+			}
+			pending = append(pending, memory)
+		}
+
+		if err := s.reembedInto(ctx, staging, pending, staged); err != nil {
+			return nil, fmt.Errorf("reindex stopped at offset %d, rerun to resume: %w", offset, err)
+		}
+		response.Reembedded += len(pending)
+
+		s.logger.Info("Reindex progress",
+			zap.Int("scanned", response.Scanned),
+			zap.Int("reembedded", response.Reembedded),
+			zap.Int("unchanged", response.Unchanged))
+		if progress != nil {
+			progress(*response)
+		}
+
+		if len(page) < batchSize {
+			break
+		}
+	}
+
+	// Memories written or deleted while the live collection was paged through
+	// are picked up by comparing it with staging again, until a pass finds
+	// nothing left to copy. The last pass runs right before the switch.
+	for pass := 1; ; pass++ {
+		reembedded, removed, err := s.catchUp(ctx, staging, staged, batchSize)
+		if err != nil {
+			return nil, fmt.Errorf("reindex catch-up failed, rerun to resume: %w", err)
+		}
+		response.Reembedded += reembedded
+		response.Removed += removed
+
+		if reembedded == 0 && removed == 0 {
+			break
+		}
+		if pass == maxCatchUpPasses {
+			return nil, fmt.Errorf("memories kept changing after %d catch-up passes, rerun when writes slow down", pass)
+		}
+
+		s.logger.Info("Reindex caught up with concurrent writes",
+			zap.Int("pass", pass),
+			zap.Int("reembedded", reembedded),
+			zap.Int("removed", removed))
+	}
+
+	if req.Switch {
+		backup, err := store.PromoteStaging(ctx, response.Staging)
+		if err != nil {
+			return nil, fmt.Errorf("failed to switch to reindexed collection: %w", err)
+		}
+		response.Backup = backup
+		response.Switched = true
+	}
+
+	response.DurationMs = int(time.Since(startTime).Milliseconds())
+
+	s.logger.Info("Reindex completed",
+		zap.String("staging", response.Staging),
+		zap.Int("reembedded", response.Reembedded),
+		zap.Int("removed", response.Removed),
+		zap.Bool("switched", response.Switched))
+
+	return response, nil
+}
+
+// catchUp makes staging match the live collection, re-embedding memories that
+// are missing from staging or changed since they were staged and removing
+// those deleted from the live collection. Only metadata is read to find them.
+func (s *System) catchUp(ctx context.Context, staging services.MemoryStore, staged map[string]time.Time, batchSize int) (int, int, error) {
+	live := make(map[string]bool, len(staged))
+	var changed []string
+	err := s.store.ScanMemories(ctx, nil, func(memory *models.Memory) error {
+		live[memory.ID] = true
+		if updatedAt, ok := staged[memory.ID]; !ok || !memory.UpdatedAt.Equal(updatedAt) {
+			changed = append(changed, memory.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to scan memories: %w", err)
+	}
+
+	reembedded := 0
+	for start := 0; start < len(changed); start += batchSize {
+		end := min(start+batchSize, len(changed))
+		memories, err := s.store.GetMemories(ctx, changed[start:end])
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to read changed memories: %w", err)
+		}
+		if err := s.reembedInto(ctx, staging, memories, staged); err != nil {
+			return 0, 0, err
+		}
+		reembedded += len(memories)
+	}
+
+	var removed []string
+	for id := range staged {
+		if !live[id] {
+			removed = append(removed, id)
+		}
+	}
+	if len(removed) > 0 {
+		if err := staging.DeleteMemories(ctx, removed); err != nil {
+			return 0, 0, fmt.Errorf("failed to remove deleted memories from staging: %w", err)
+		}
+		for _, id := range removed {
+			delete(staged, id)
+		}
+	}
+
+	return reembedded, len(removed), nil
+}
+
+// reembedInto embeds memories' content in one batch and writes them to a
+// store, adding the memories it does not hold yet and overwriting the rest in
+// one write each
+func (s *System) reembedInto(ctx context.Context, store services.MemoryStore, memories []*models.Memory, staged map[string]time.Time) error {
+	if len(memories) == 0 {
+		return nil
+	}
+
+	texts := make([]string, len(memories))
+	for i, memory := range memories {
+		texts[i] = memory.Content
+	}
+
+	embeddings, err := s.embeddingService.GenerateBatchEmbeddings(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("failed to generate embeddings: %w", err)
+	}
+
+	var added, updated []*models.Memory
+	for i, memory := range memories {
+		memory.Embedding = embeddings[i]
+		if _, ok := staged[memory.ID]; ok {
+			updated = append(updated, memory)
+		} else {
+			added = append(added, memory)
+		}
+	}
+
+	if len(added) > 0 {
+		if err := store.StoreMemories(ctx, added); err != nil {
+			return fmt.Errorf("failed to write memories: %w", err)
+		}
+	}
+	if len(updated) > 0 {
+		if err := store.UpdateMemories(ctx, updated); err != nil {
+			return fmt.Errorf("failed to overwrite memories: %w", err)
+		}
+	}
+
+	for _, memory := range memories {
+		staged[memory.ID] = memory.UpdatedAt
+	}
+
+	return nil
+}
+
+// FormatReindexResponse renders a reindex outcome for display
+func FormatReindexResponse(response *models.ReindexResponse) string {
+	text := fmt.Sprintf(`Reindex completed with %s (%d dimensions)
+
+- Memories Scanned: %d
+- Memories Re-embedded: %d
+- Already Current: %d
+- Removed From Staging: %d
+- Duration: %d ms
+`,
+		response.EmbeddingModel, response.EmbeddingDimension,
+		response.Scanned, response.Reembedded, response.Unchanged, response.Removed,
+		response.DurationMs)
+
+	if response.Switched {
+		text += fmt.Sprintf("\nSwitched to the reindexed collection. The previous data was kept as %s.\n", response.Backup)
+	} else {
+		text += fmt.Sprintf("\nThe reindexed data is staged as %s. Rerun the reindex with switching enabled to use it.\n", response.Staging)
+	}
+
+	return text
+}
+
+// stagingName derives a stable staging name from the embedding model, so that
+// rerunning a reindex for the same model resumes instead of starting over
+func stagingName(info services.EmbeddingInfo) string {
+	slug := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '-'
+	}, strings.ToLower(info.Model))
+
+	return fmt.Sprintf("reindex_%s_%d", strings.Trim(slug, "-"), info.Dimension)
+}
@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/amem/mcp-server/pkg/config"
+	"github.com/amem/mcp-server/pkg/models"
+	"github.com/amem/mcp-server/pkg/services"
+	"go.uber.org/zap"
+)
+
+func TestReindexCatchesUpWithConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	system, store := newTestSystem(t)
+
+	createdAt := time.Now().Add(-time.Hour)
+	for _, id := range []string{"a", "b", "c"} {
+		memory := &models.Memory{ID: id, Content: "retry " + id, WorkspaceID: "project", Embedding: []float32{0, 1}, CreatedAt: createdAt, UpdatedAt: createdAt}
+		if err := store.StoreMemory(ctx, memory); err != nil {
+			t.Fatalf("Failed to store memory: %v", err)
+		}
+	}
+
+	// Write to the live collection after the first batch has been staged. The
+	// deletion shifts "c" out of the second page.
+	written := false
+	response, err := system.Reindex(ctx, models.ReindexRequest{BatchSize: 2, Switch: true}, func(models.ReindexResponse) {
+		if written {
+			return
+		}
+		written = true
+
+		late := &models.Memory{ID: "late", Content: "retry late", WorkspaceID: "project", Embedding: []float32{0, 1}, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		if err := store.StoreMemory(ctx, late); err != nil {
+			t.Fatalf("Failed to store memory: %v", err)
+		}
+		edited, err := store.GetMemory(ctx, "a")
+		if err != nil {
+			t.Fatalf("Failed to get memory: %v", err)
+		}
+		edited.Content = "retry edited"
+		edited.UpdatedAt = time.Now()
+		if err := store.UpdateMemory(ctx, edited); err != nil {
+			t.Fatalf("Failed to update memory: %v", err)
+		}
+		if err := store.DeleteMemories(ctx, []string{"b"}); err != nil {
+			t.Fatalf("Failed to delete memory: %v", err)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Reindex failed: %v", err)
+	}
+	if !response.Switched || response.Removed != 1 {
+		t.Errorf("Expected a switch that removed the deleted memory, got %+v", response)
+	}
+
+	memories, err := store.GetMemories(ctx, []string{"a", "b", "c", "late"})
+	if err != nil {
+		t.Fatalf("Failed to get memories: %v", err)
+	}
+	contents := make(map[string]string)
+	for _, memory := range memories {
+		contents[memory.ID] = memory.Content
+		if len(memory.Embedding) != 2 || memory.Embedding[0] != 1 {
+			t.Errorf("Expected %s to be re-embedded, got %v", memory.ID, memory.Embedding)
+		}
+	}
+	if len(contents) != 3 || contents["a"] != "retry edited" || contents["late"] != "retry late" || contents["c"] != "retry c" {
+		t.Errorf("Expected the edited, shifted and late memories without the deleted one, got %v", contents)
+	}
+}
+
+func TestReindexToolRebuildsMismatchedStore(t *testing.T) {
+	ctx := context.Background()
+	system, _ := newTestSystem(t)
+
+	storageConfig := config.StorageConfig{Backend: "local", Path: filepath.Join(t.TempDir(), "memories.json")}
+	current := services.EmbeddingInfo{Model: "test-model", Dimension: 2}
+
+	old := services.NewLocalStore(storageConfig, services.DistanceCosine, zap.NewNop())
+	if err := old.Initialize(ctx, services.EmbeddingInfo{Model: "old-model", Dimension: 3}); err != nil {
+		t.Fatalf("Failed to initialize store: %v", err)
+	}
+	if err := old.StoreMemory(ctx, &models.Memory{ID: "a", Content: "retry", WorkspaceID: "project", Embedding: []float32{1, 0, 0}}); err != nil {
+		t.Fatalf("Failed to store memory: %v", err)
+	}
+
+	// The server falls back to opening the store without the embedding check
+	store := services.NewLocalStore(storageConfig, services.DistanceCosine, zap.NewNop())
+	if err := store.Initialize(ctx, current); !errors.Is(err, services.ErrEmbeddingMismatch) {
+		t.Fatalf("Expected an embedding mismatch, got %v", err)
+	}
+	if err := store.Initialize(ctx, services.EmbeddingInfo{}); err != nil {
+		t.Fatalf("Failed to open store without the embedding check: %v", err)
+	}
+	system.store = store
+
+	result, err := NewReindexMemoriesTool(system, zap.NewNop()).Execute(ctx, map[string]interface{}{})
+	if err != nil || result.IsError {
+		t.Fatalf("Reindex tool failed: %v, %+v", err, result)
+	}
+
+	reopened := services.NewLocalStore(storageConfig, services.DistanceCosine, zap.NewNop())
+	if err := reopened.Initialize(ctx, current); err != nil {
+		t.Fatalf("Expected the reindexed store to match the embedding model, got %v", err)
+	}
+	memory, err := reopened.GetMemory(ctx, "a")
+	if err != nil || len(memory.Embedding) != 2 {
+		t.Errorf("Expected the memory to be re-embedded, got %+v, %v", memory, err)
+	}
+}
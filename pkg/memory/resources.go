@@ -0,0 +1,177 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/amem/mcp-server/pkg/models"
+	"github.com/amem/mcp-server/pkg/services"
+	"go.uber.org/zap"
+)
+
+// Resource URI prefixes. Workspace IDs are often paths, so they are path-escaped.
+const (
+	workspaceURIPrefix = "amem://workspace/"
+	memoryURIPrefix    = "amem://memory/"
+)
+
+// WorkspaceResourceURI returns the resource URI of a workspace
+func WorkspaceResourceURI(workspaceID string) string {
+	return workspaceURIPrefix + url.PathEscape(workspaceID)
+}
+
+// MemoryResourceURI returns the resource URI of a memory
+func MemoryResourceURI(memoryID string) string {
+	return memoryURIPrefix + url.PathEscape(memoryID)
+}
+
+// ResourceProvider exposes workspaces and memories as MCP resources. Workspaces
+// are listed; memories are reached through their workspace or the URI template.
+type ResourceProvider struct {
+	system           *System
+	workspaceService *services.WorkspaceService
+	logger           *zap.Logger
+}
+
+// NewResourceProvider creates a new resource provider
+func NewResourceProvider(system *System, workspaceService *services.WorkspaceService, logger *zap.Logger) *ResourceProvider {
+	return &ResourceProvider{
+		system:           system,
+		workspaceService: workspaceService,
+		logger:           logger,
+	}
+}
+
+// ListResources lists one resource per workspace
+func (p *ResourceProvider) ListResources(ctx context.Context) ([]models.MCPResource, error) {
+	workspaces, err := p.workspaceService.ListWorkspaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]models.MCPResource, 0, len(workspaces))
+	for _, workspace := range workspaces {
+		resources = append(resources, models.MCPResource{
+			URI:         WorkspaceResourceURI(workspace.ID),
+			Name:        workspace.Name,
+			Description: fmt.Sprintf("%s (%d memories)", workspace.Description, workspace.MemoryCount),
+			MimeType:    "application/json",
+		})
+	}
+
+	return resources, nil
+}
+
+// ResourceTemplates describes the workspace and memory URIs
+func (p *ResourceProvider) ResourceTemplates() []models.MCPResourceTemplate {
+	return []models.MCPResourceTemplate{
+		{
+			URITemplate: workspaceURIPrefix + "{id}",
+			Name:        "Workspace",
+			Description: "A workspace with the IDs and summaries of its memories",
+			MimeType:    "application/json",
+		},
+		{
+			URITemplate: memoryURIPrefix + "{id}",
+			Name:        "Memory",
+			Description: "A single coding memory with its content, context, tags and links",
+			MimeType:    "application/json",
+		},
+	}
+}
+
+// workspaceResource is the JSON body of a workspace resource
+type workspaceResource struct {
+	models.Workspace
+	Memories []workspaceResourceEntry `json:"memories"`
+}
+
+// workspaceResourceEntry summarises one memory in a workspace resource
+type workspaceResourceEntry struct {
+	URI      string   `json:"uri"`
+	Context  string   `json:"context"`
+	CodeType string   `json:"code_type"`
+	Tags     []string `json:"tags"`
+}
+
+// ReadResource reads a workspace or memory resource
+func (p *ResourceProvider) ReadResource(ctx context.Context, uri string) ([]models.MCPResourceContents, error) {
+	var body interface{}
+
+	switch {
+	case strings.HasPrefix(uri, memoryURIPrefix):
+		memoryID, err := url.PathUnescape(strings.TrimPrefix(uri, memoryURIPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", models.ErrResourceNotFound, uri)
+		}
+
+		memory, err := p.system.GetMemory(ctx, memoryID)
+		if errors.Is(err, services.ErrMemoryNotFound) {
+			return nil, fmt.Errorf("%w: %s", models.ErrResourceNotFound, uri)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		memory.Embedding = nil
+		body = memory
+
+	case strings.HasPrefix(uri, workspaceURIPrefix):
+		workspaceID, err := url.PathUnescape(strings.TrimPrefix(uri, workspaceURIPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", models.ErrResourceNotFound, uri)
+		}
+
+		memories, err := p.system.store.ListAllMemories(ctx, map[string]interface{}{"workspace_id": workspaceID})
+		if err != nil {
+			return nil, err
+		}
+		if len(memories) == 0 {
+			// A registered workspace without memories reads as empty
+			exists, err := p.workspaceService.WorkspaceExists(ctx, workspaceID)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				return nil, fmt.Errorf("%w: %s", models.ErrResourceNotFound, uri)
+			}
+		}
+
+		workspace, err := p.workspaceService.GetWorkspaceInfo(ctx, workspaceID)
+		if err != nil {
+			return nil, err
+		}
+
+		resource := workspaceResource{
+			Workspace: *workspace,
+			Memories:  make([]workspaceResourceEntry, 0, len(memories)),
+		}
+		for _, memory := range memories {
+			resource.Memories = append(resource.Memories, workspaceResourceEntry{
+				URI:      MemoryResourceURI(memory.ID),
+				Context:  memory.Context,
+				CodeType: memory.CodeType,
+				Tags:     memory.Tags,
+			})
+		}
+		body = resource
+
+	default:
+		return nil, fmt.Errorf("%w: %s", models.ErrResourceNotFound, uri)
+	}
+
+	text, err := json.MarshalIndent(body, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode resource: %w", err)
+	}
+
+	return []models.MCPResourceContents{{
+		URI:      uri,
+		MimeType: "application/json",
+		Text:     string(text),
+	}}, nil
+}
@@ -0,0 +1,38 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/amem/mcp-server/pkg/models"
+	"go.uber.org/zap"
+)
+
+func TestReadResourceOfEmptyRegisteredWorkspace(t *testing.T) {
+	ctx := context.Background()
+	system, _ := newTestSystem(t)
+	provider := NewResourceProvider(system, system.workspaceService, zap.NewNop())
+
+	if _, err := system.workspaceService.CreateWorkspace(ctx, &models.WorkspaceRequest{Identifier: "empty"}); err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+
+	contents, err := provider.ReadResource(ctx, WorkspaceResourceURI("empty"))
+	if err != nil {
+		t.Fatalf("Expected an empty workspace resource, got %v", err)
+	}
+
+	var resource workspaceResource
+	if err := json.Unmarshal([]byte(contents[0].Text), &resource); err != nil {
+		t.Fatalf("Failed to decode resource: %v", err)
+	}
+	if resource.ID != "empty" || !resource.Registered || resource.Memories == nil || len(resource.Memories) != 0 {
+		t.Errorf("Expected the registered workspace with no memories, got %s", contents[0].Text)
+	}
+
+	if _, err := provider.ReadResource(ctx, WorkspaceResourceURI("unknown")); !errors.Is(err, models.ErrResourceNotFound) {
+		t.Errorf("Expected an unknown workspace to be not found, got %v", err)
+	}
+}
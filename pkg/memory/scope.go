@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/amem/mcp-server/pkg/models"
+)
+
+// inheritedWeight scales the scores of memories from an inherited workspace,
+// once per level of inheritance, unless the request sets a weight for it
+const inheritedWeight = 0.8
+
+// searchWeights resolves the workspaces a retrieval covers and the weight of
+// each. When searching every workspace only the requested weights are
+// returned; memories from any other workspace keep their score.
+func (s *System) searchWeights(ctx context.Context, req models.RetrieveMemoryRequest) (map[string]float32, error) {
+	overrides := make(map[string]float32, len(req.WorkspaceWeights))
+	for workspaceID, weight := range req.WorkspaceWeights {
+		if weight < 0 {
+			return nil, fmt.Errorf("invalid weight %v for workspace '%s': must not be negative", weight, workspaceID)
+		}
+		overrides[s.workspaceService.NormalizeWorkspaceID(workspaceID)] = weight
+	}
+
+	if req.AllWorkspaces {
+		return overrides, nil
+	}
+
+	// Determine workspace IDs (with backward compatibility)
+	requested := append([]string(nil), req.WorkspaceIDs...)
+	if req.WorkspaceID != "" {
+		requested = append(requested, req.WorkspaceID)
+	} else if req.ProjectFilter != "" {
+		// Backward compatibility: use project_filter as workspace_id
+		requested = append(requested, req.ProjectFilter)
+	}
+	if len(requested) == 0 {
+		// Use default workspace
+		requested = append(requested, s.workspaceService.GetDefaultWorkspaceID())
+	}
+
+	for i, workspaceID := range requested {
+		requested[i] = s.workspaceService.NormalizeWorkspaceID(workspaceID)
+	}
+
+	scope, err := s.workspaceService.SearchScope(ctx, requested, !req.SkipInherited)
+	if err != nil {
+		return nil, err
+	}
+
+	weights := make(map[string]float32, len(scope))
+	for workspaceID, depth := range scope {
+		weights[workspaceID] = float32(math.Pow(inheritedWeight, float64(depth)))
+		if weight, ok := overrides[workspaceID]; ok {
+			weights[workspaceID] = weight
+		}
+	}
+
+	return weights, nil
+}
+
+// workspaceCondition filters memories to a set of workspaces
+func workspaceCondition(workspaceIDs []string) map[string]interface{} {
+	if len(workspaceIDs) == 1 {
+		return map[string]interface{}{"workspace_id": workspaceIDs[0]}
+	}
+	return map[string]interface{}{
+		"workspace_id": map[string]interface{}{
+			"$in": workspaceIDs,
+		},
+	}
+}
+
+// applyWorkspaceWeights scales each score by the weight of the memory's
+// workspace and re-ranks. Workspaces without a weight count fully and those
+// weighted zero are dropped.
+func applyWorkspaceWeights(ranked []scoredMemory, weights map[string]float32) []scoredMemory {
+	weighted := make([]scoredMemory, 0, len(ranked))
+	for _, result := range ranked {
+		weight := workspaceWeight(weights, result.memory.WorkspaceID)
+		if weight == 0 {
+			continue
+		}
+		result.score *= weight
+		weighted = append(weighted, result)
+	}
+
+	sort.SliceStable(weighted, func(i, j int) bool {
+		return weighted[i].score > weighted[j].score
+	})
+
+	return weighted
+}
+
+// workspaceWeight returns the weight of a workspace, 1 when it has none
+func workspaceWeight(weights map[string]float32, workspaceID string) float32 {
+	if weight, ok := weights[workspaceID]; ok {
+		return weight
+	}
+	return 1
+}
@@ -0,0 +1,140 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/amem/mcp-server/pkg/models"
+)
+
+func TestRetrieveMemoriesSearchesInheritedWorkspaces(t *testing.T) {
+	ctx := context.Background()
+	system, store := newTestSystem(t)
+
+	for _, req := range []models.WorkspaceRequest{
+		{Identifier: "shared"},
+		{Identifier: "service-a", Parents: []string{"shared"}},
+		{Identifier: "service-b", Parents: []string{"shared"}},
+	} {
+		if _, err := system.workspaceService.CreateWorkspace(ctx, &req); err != nil {
+			t.Fatalf("Failed to create workspace %s: %v", req.Identifier, err)
+		}
+	}
+
+	if _, err := system.workspaceService.UpdateWorkspace(ctx, &models.WorkspaceUpdateRequest{
+		Identifier: "shared",
+		Parents:    []string{"service-a"},
+	}); err == nil {
+		t.Error("Expected a parent cycle to be rejected")
+	}
+
+	for _, memory := range []*models.Memory{
+		{ID: "a", Content: "retry with backoff", WorkspaceID: "service-a", Embedding: []float32{1, 0}},
+		{ID: "b", Content: "retry idempotent calls", WorkspaceID: "service-b", Embedding: []float32{1, 0}},
+		{ID: "shared", Content: "retry budget", WorkspaceID: "shared", Embedding: []float32{1, 0}},
+	} {
+		if err := store.StoreMemory(ctx, memory); err != nil {
+			t.Fatalf("Failed to store memory: %v", err)
+		}
+	}
+
+	retrieve := func(req models.RetrieveMemoryRequest) []string {
+		t.Helper()
+		req.Query = "retry"
+		req.SearchMode = models.SearchModeVector
+		response, err := system.RetrieveMemories(ctx, req)
+		if err != nil {
+			t.Fatalf("Retrieve failed: %v", err)
+		}
+		var ids []string
+		for _, memory := range response.Memories {
+			ids = append(ids, memory.ID)
+		}
+		return ids
+	}
+
+	cases := []struct {
+		name     string
+		req      models.RetrieveMemoryRequest
+		expected []string
+	}{
+		{"inherits parents", models.RetrieveMemoryRequest{WorkspaceID: "service-a"}, []string{"a", "shared"}},
+		{"skips parents", models.RetrieveMemoryRequest{WorkspaceID: "service-a", SkipInherited: true}, []string{"a"}},
+		{"weights reorder", models.RetrieveMemoryRequest{WorkspaceID: "service-a", WorkspaceWeights: map[string]float32{"service-a": 0.5}, MinRelevance: 0.4}, []string{"shared", "a"}},
+		{"several workspaces", models.RetrieveMemoryRequest{WorkspaceIDs: []string{"service-a", "service-b"}, SkipInherited: true}, []string{"a", "b"}},
+		{"every workspace", models.RetrieveMemoryRequest{AllWorkspaces: true, WorkspaceWeights: map[string]float32{"shared": 0}}, []string{"a", "b"}},
+	}
+	for _, c := range cases {
+		got := retrieve(c.req)
+		if len(got) != len(c.expected) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.expected[i] {
+				t.Errorf("%s: expected %v, got %v", c.name, c.expected, got)
+				break
+			}
+		}
+	}
+}
+
+func TestMinRelevanceAppliesToWeightedScore(t *testing.T) {
+	ctx := context.Background()
+	system, store := newTestSystem(t)
+
+	for _, req := range []models.WorkspaceRequest{
+		{Identifier: "shared"},
+		{Identifier: "service", Parents: []string{"shared"}},
+	} {
+		if _, err := system.workspaceService.CreateWorkspace(ctx, &req); err != nil {
+			t.Fatalf("Failed to create workspace %s: %v", req.Identifier, err)
+		}
+	}
+
+	// Both memories are 0.72 similar to the query, which the inherited
+	// workspace's weight of 0.8 lowers to 0.576
+	embedding := []float32{0.72, float32(math.Sqrt(1 - 0.72*0.72))}
+	for _, memory := range []*models.Memory{
+		{ID: "own", Content: "retry with backoff", WorkspaceID: "service", Embedding: embedding},
+		{ID: "inherited", Content: "retry budget", WorkspaceID: "shared", Embedding: embedding},
+	} {
+		if err := store.StoreMemory(ctx, memory); err != nil {
+			t.Fatalf("Failed to store memory: %v", err)
+		}
+	}
+
+	cases := []struct {
+		minRelevance float32
+		expected     map[string]float32
+	}{
+		{0.7, map[string]float32{"own": 0.72}},
+		{0.5, map[string]float32{"own": 0.72, "inherited": 0.576}},
+	}
+	for _, c := range cases {
+		response, err := system.RetrieveMemories(ctx, models.RetrieveMemoryRequest{
+			Query:        "retry",
+			WorkspaceID:  "service",
+			SearchMode:   models.SearchModeVector,
+			MinRelevance: c.minRelevance,
+		})
+		if err != nil {
+			t.Fatalf("Retrieve failed: %v", err)
+		}
+
+		if len(response.Memories) != len(c.expected) {
+			t.Errorf("min_relevance %.1f: expected %v, got %+v", c.minRelevance, c.expected, response.Memories)
+			continue
+		}
+		for _, memory := range response.Memories {
+			expected, ok := c.expected[memory.ID]
+			if !ok || math.Abs(float64(memory.RelevanceScore-expected)) > 0.001 {
+				t.Errorf("min_relevance %.1f: expected %s to score %.3f, got %.3f", c.minRelevance, memory.ID, expected, memory.RelevanceScore)
+			}
+			if memory.RelevanceScore < c.minRelevance {
+				t.Errorf("min_relevance %.1f: %s scored %.3f, below the threshold", c.minRelevance, memory.ID, memory.RelevanceScore)
+			}
+		}
+	}
+}
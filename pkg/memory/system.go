@@ -2,10 +2,13 @@ package memory
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 
+	"github.com/amem/mcp-server/pkg/gitrepo"
 	"github.com/amem/mcp-server/pkg/models"
 	"github.com/amem/mcp-server/pkg/services"
 	"github.com/google/uuid"
@@ -16,39 +19,61 @@ import (
 type System struct {
 	logger           *zap.Logger
 	llmService       *services.LiteLLMService
-	chromaDB         *services.ChromaDBService
+	promptManager    *services.PromptManager
+	store            services.MemoryStore
 	embeddingService *services.EmbeddingService
 	workspaceService *services.WorkspaceService
+	changeListeners  []ChangeListener
+	keywords         *keywordCache
+	locks            *services.MemoryLocks // Held around every read-modify-write of a memory
+	dedupeMode       string                // Default dedupe mode for new memories
+	dedupeThreshold  float32               // Default similarity at which a new memory is a duplicate
+	ingestRoots      []string              // Directories ingestion is confined to, any directory if nil
 }
 
+// ChangeListener is called after a memory is created, updated or deleted
+type ChangeListener func(memoryID, workspaceID string)
+
 // NewSystem creates a new memory system
-func NewSystem(logger *zap.Logger, llmService *services.LiteLLMService, chromaDB *services.ChromaDBService, embeddingService *services.EmbeddingService, workspaceService *services.WorkspaceService) *System {
-	return &System{
+func NewSystem(logger *zap.Logger, llmService *services.LiteLLMService, promptManager *services.PromptManager, store services.MemoryStore, embeddingService *services.EmbeddingService, workspaceService *services.WorkspaceService) *System {
+	s := &System{
 		logger:           logger,
 		llmService:       llmService,
-		chromaDB:         chromaDB,
+		promptManager:    promptManager,
+		store:            store,
 		embeddingService: embeddingService,
 		workspaceService: workspaceService,
+		locks:            workspaceService.Locks(),
+		keywords:         &keywordCache{},
+		dedupeMode:       defaultDedupeMode,
+		dedupeThreshold:  defaultDedupeThreshold,
 	}
+
+	// Workspace operations move memories without going through the system
+	workspaceService.AddChangeListener(s.keywords.invalidate)
+
+	return s
 }
 
-// CreateMemory creates a new memory from the given content
-func (s *System) CreateMemory(ctx context.Context, req models.StoreMemoryRequest) (*models.StoreMemoryResponse, error) {
-	// Determine workspace ID (with backward compatibility)
-	workspaceID := req.WorkspaceID
-	if workspaceID == "" && req.ProjectPath != "" {
-		// Backward compatibility: use project_path as workspace_id
-		workspaceID = req.ProjectPath
-	}
-	if workspaceID == "" {
-		// Use default workspace
-		workspaceID = s.workspaceService.GetDefaultWorkspaceID()
+// AddChangeListener registers a function to call whenever a memory changes.
+// Listeners must be added before the system is used and must not block.
+func (s *System) AddChangeListener(listener ChangeListener) {
+	s.changeListeners = append(s.changeListeners, listener)
+}
+
+// notifyChange tells every listener that a memory changed
+func (s *System) notifyChange(memory *models.Memory) {
+	s.keywords.invalidate(memory.ID, memory.WorkspaceID)
+	for _, listener := range s.changeListeners {
+		listener(memory.ID, memory.WorkspaceID)
 	}
+}
 
-	// Normalize and validate workspace ID
-	workspaceID = s.workspaceService.NormalizeWorkspaceID(workspaceID)
-	if err := s.workspaceService.ValidateWorkspaceID(workspaceID); err != nil {
-		return nil, fmt.Errorf("invalid workspace ID: %w", err)
+// CreateMemory creates a new memory from the given content
+func (s *System) CreateMemory(ctx context.Context, req models.StoreMemoryRequest) (*models.StoreMemoryResponse, error) {
+	workspaceID, repo, err := s.storeWorkspaceID(ctx, req)
+	if err != nil {
+		return nil, err
 	}
 
 	s.logger.Info("Creating memory",
@@ -56,22 +81,38 @@ func (s *System) CreateMemory(ctx context.Context, req models.StoreMemoryRequest
 		zap.String("project_path", req.ProjectPath),
 		zap.String("code_type", req.CodeType))
 
-	// Generate unique ID
-	memoryID := uuid.New().String()
-
-	// Step 1: Construct note using LLM
-	noteResult, err := s.constructNote(ctx, req)
+	dedupeMode, threshold, err := s.dedupeSettings(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to construct note: %w", err)
+		return nil, err
 	}
 
-	// Step 2: Generate embedding
+	// Step 1: Generate embedding
 	embedding, err := s.embeddingService.GenerateEmbedding(ctx, req.Content)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate embedding: %w", err)
 	}
 
-	// Step 3: Create memory object
+	// Step 2: Look for a near-identical memory in the same workspace
+	if dedupeMode != models.DedupeForce {
+		duplicate, similarity, err := s.findDuplicate(ctx, embedding, workspaceID, threshold)
+		if err != nil {
+			return nil, err
+		}
+		if duplicate != nil {
+			return s.handleDuplicate(ctx, dedupeMode, duplicate, similarity, req)
+		}
+	}
+
+	// Generate unique ID
+	memoryID := uuid.New().String()
+
+	// Step 3: Construct note using LLM
+	noteResult, err := s.constructNote(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct note: %w", err)
+	}
+
+	// Step 4: Create memory object
 	memory := &models.Memory{
 		ID:          memoryID,
 		Content:     req.Content,
@@ -84,10 +125,10 @@ func (s *System) CreateMemory(ctx context.Context, req models.StoreMemoryRequest
 		Embedding:   embedding,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
-		Metadata:    make(map[string]interface{}),
+		Metadata:    requestMetadata(req, repo),
 	}
 
-	// Step 4: Generate links to existing memories
+	// Step 5: Generate links to existing memories
 	links, err := s.generateLinks(ctx, memory)
 	if err != nil {
 		s.logger.Warn("Failed to generate links", zap.Error(err))
@@ -95,10 +136,14 @@ func (s *System) CreateMemory(ctx context.Context, req models.StoreMemoryRequest
 	}
 	memory.Links = links
 
-	// Step 5: Store in ChromaDB
-	if err := s.chromaDB.StoreMemory(ctx, memory); err != nil {
+	// Step 6: Store in the memory store
+	if err := s.store.StoreMemory(ctx, memory); err != nil {
 		return nil, fmt.Errorf("failed to store memory: %w", err)
 	}
+	s.notifyChange(memory)
+
+	// Step 7: Link the existing memories back to the new one
+	s.writeBacklinks(ctx, memory)
 
 	s.logger.Info("Memory created successfully",
 		zap.String("memory_id", memoryID),
@@ -110,28 +155,131 @@ func (s *System) CreateMemory(ctx context.Context, req models.StoreMemoryRequest
 		Tags:         noteResult.Tags,
 		LinksCreated: len(links),
 		EventEmitted: true,
+		Action:       models.StoreActionCreated,
 	}, nil
 }
 
-// RetrieveMemories retrieves relevant memories based on query
-func (s *System) RetrieveMemories(ctx context.Context, req models.RetrieveMemoryRequest) (*models.RetrieveMemoryResponse, error) {
+// storeWorkspaceID resolves the workspace a new memory is stored in and the
+// git repository it was resolved from, if any, and registers the workspace
+func (s *System) storeWorkspaceID(ctx context.Context, req models.StoreMemoryRequest) (string, *gitrepo.Repository, error) {
+	workspaceID, repo, err := s.resolveStoreWorkspace(req)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := s.workspaceService.RegisterMemoryWorkspace(ctx, workspaceID); err != nil {
+		return "", nil, err
+	}
+
+	return workspaceID, repo, nil
+}
+
+// resolveStoreWorkspace resolves the workspace a new memory would be stored
+// in without registering it
+func (s *System) resolveStoreWorkspace(req models.StoreMemoryRequest) (string, *gitrepo.Repository, error) {
 	// Determine workspace ID (with backward compatibility)
 	workspaceID := req.WorkspaceID
-	if workspaceID == "" && req.ProjectFilter != "" {
-		// Backward compatibility: use project_filter as workspace_id
-		workspaceID = req.ProjectFilter
+	if workspaceID == "" && req.ProjectPath != "" {
+		// Backward compatibility: use project_path as workspace_id
+		workspaceID = req.ProjectPath
 	}
 	if workspaceID == "" {
 		// Use default workspace
 		workspaceID = s.workspaceService.GetDefaultWorkspaceID()
 	}
 
-	// Normalize workspace ID
-	workspaceID = s.workspaceService.NormalizeWorkspaceID(workspaceID)
+	// Normalize and validate workspace ID
+	workspaceID, repo := s.workspaceService.ResolveWorkspace(workspaceID)
+	if err := s.workspaceService.ValidateWorkspaceID(workspaceID); err != nil {
+		return "", nil, fmt.Errorf("invalid workspace ID: %w", err)
+	}
+
+	return workspaceID, repo, nil
+}
+
+// requestMetadata copies the metadata of a store request for a new memory,
+// adding the branch and commit of the repository it came from
+func requestMetadata(req models.StoreMemoryRequest, repo *gitrepo.Repository) map[string]interface{} {
+	metadata := make(map[string]interface{}, len(req.Metadata)+2)
+	for k, v := range req.Metadata {
+		metadata[k] = v
+	}
+	addGitMetadata(metadata, repo)
+	return metadata
+}
+
+// addGitMetadata records the branch and commit checked out in a repository,
+// keeping values the caller already set
+func addGitMetadata(metadata map[string]interface{}, repo *gitrepo.Repository) {
+	if repo == nil {
+		return
+	}
+	for key, value := range map[string]string{
+		models.MetadataGitBranch: repo.Branch,
+		models.MetadataGitCommit: repo.Commit,
+	} {
+		if _, ok := metadata[key]; !ok && value != "" {
+			metadata[key] = value
+		}
+	}
+}
+
+// handleDuplicate returns the existing memory instead of storing a new one,
+// first merging the new content into it in merge mode
+func (s *System) handleDuplicate(ctx context.Context, mode string, duplicate *models.Memory, similarity float32, req models.StoreMemoryRequest) (*models.StoreMemoryResponse, error) {
+	s.logger.Info("Found duplicate memory",
+		zap.String("memory_id", duplicate.ID),
+		zap.String("mode", mode),
+		zap.Float32("similarity", similarity))
+
+	// Merging rewrites the existing memory, so nothing may change it meanwhile
+	if mode == models.DedupeMerge {
+		defer s.locks.Lock(duplicate.ID)()
+	}
+
+	// Search results may omit fields, so work on the full record
+	existing, err := s.GetMemory(ctx, duplicate.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	action := models.StoreActionSkipped
+	if mode == models.DedupeMerge {
+		if err := s.mergeMemory(ctx, existing, req); err != nil {
+			return nil, fmt.Errorf("failed to merge into memory %s: %w", existing.ID, err)
+		}
+		action = models.StoreActionMerged
+	}
+
+	return &models.StoreMemoryResponse{
+		MemoryID:     existing.ID,
+		Keywords:     existing.Keywords,
+		Tags:         existing.Tags,
+		EventEmitted: action == models.StoreActionMerged,
+		Action:       action,
+		DuplicateOf:  existing.ID,
+		Similarity:   similarity,
+	}, nil
+}
+
+// RetrieveMemories retrieves relevant memories based on query. The search
+// covers the requested workspaces and, unless skipped, the workspaces they
+// inherit from, whose scores are weighted down by distance.
+func (s *System) RetrieveMemories(ctx context.Context, req models.RetrieveMemoryRequest) (*models.RetrieveMemoryResponse, error) {
+	weights, err := s.searchWeights(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := make([]string, 0, len(weights))
+	for workspaceID := range weights {
+		scope = append(scope, workspaceID)
+	}
+	sort.Strings(scope)
 
 	s.logger.Info("Retrieving memories",
 		zap.String("query", req.Query),
-		zap.String("workspace_id", workspaceID),
+		zap.Strings("workspace_ids", scope),
+		zap.Bool("all_workspaces", req.AllWorkspaces),
 		zap.Int("max_results", req.MaxResults))
 
 	// Set defaults
@@ -141,40 +289,277 @@ func (s *System) RetrieveMemories(ctx context.Context, req models.RetrieveMemory
 	if req.MinRelevance <= 0 {
 		req.MinRelevance = 0.7
 	}
+	if req.SearchMode == "" {
+		req.SearchMode = models.SearchModeVector
+	}
+	if req.SearchMode != models.SearchModeVector && req.SearchMode != models.SearchModeKeyword && req.SearchMode != models.SearchModeHybrid {
+		return nil, fmt.Errorf("invalid search_mode %q: must be vector, keyword or hybrid", req.SearchMode)
+	}
+
+	// Step 1: Build filters with proper ChromaDB query structure
+	var conditions []map[string]interface{}
+
+	if !req.AllWorkspaces {
+		// Create workspace filter with OR logic for backward compatibility
+		workspaceFilter := []map[string]interface{}{
+			workspaceCondition(scope),
+		}
 
-	// Step 1: Generate query embedding
+		// Add project_path to OR clause if specified (backward compatibility)
+		if _, inScope := weights[req.ProjectFilter]; req.ProjectFilter != "" && !inScope {
+			workspaceFilter = append(workspaceFilter, map[string]interface{}{
+				"project_path": req.ProjectFilter,
+			})
+		}
+
+		// Add workspace condition
+		if len(workspaceFilter) > 1 {
+			conditions = append(conditions, map[string]interface{}{
+				"$or": workspaceFilter,
+			})
+		} else {
+			conditions = append(conditions, workspaceFilter[0])
+		}
+	}
+
+	// Add code type filter
+	if len(req.CodeTypes) > 0 {
+		conditions = append(conditions, map[string]interface{}{
+			"code_type": map[string]interface{}{
+				"$in": req.CodeTypes,
+			},
+		})
+	}
+
+	filters := combineFilters(conditions)
+
+	// Step 2: Rank candidates with the requested search mode(s). The query is
+	// embedded in every mode so each result gets a calibrated similarity.
 	queryEmbedding, err := s.embeddingService.GenerateEmbedding(ctx, req.Query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
 	}
 
-	// Step 2: Build filters with proper ChromaDB query structure
-	var conditions []map[string]interface{}
+	var vectorResults, keywordResults []scoredMemory
 
-	// Create workspace filter with OR logic for backward compatibility
-	workspaceFilter := []map[string]interface{}{
-		{"workspace_id": workspaceID},
+	if req.SearchMode != models.SearchModeKeyword {
+		vectorResults, err = s.vectorSearch(ctx, req, queryEmbedding, filters)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// Add project_path to OR clause if specified (backward compatibility)
-	if req.ProjectFilter != "" && req.ProjectFilter != workspaceID {
-		workspaceFilter = append(workspaceFilter, map[string]interface{}{
-			"project_path": req.ProjectFilter,
-		})
+	if req.SearchMode != models.SearchModeVector {
+		keywordResults, err = s.keywordSearch(ctx, req, weights, filters)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// Add workspace condition
-	if len(workspaceFilter) > 1 {
-		conditions = append(conditions, map[string]interface{}{
-			"$or": workspaceFilter,
-		})
-	} else {
+	// Step 3: Combine rankings
+	var ranked []scoredMemory
+	switch req.SearchMode {
+	case models.SearchModeVector:
+		ranked = vectorResults
+	case models.SearchModeKeyword:
+		ranked = normalizeScores(keywordResults)
+	default:
+		ranked = fuseRankings(vectorResults, keywordResults)
+	}
+
+	ranked = applyWorkspaceWeights(ranked, weights)
+
+	// Step 4: Compute the similarity of every result to the query, whichever
+	// list found them
+	similarities, err := s.similarities(ctx, queryEmbedding, ranked, vectorResults)
+	if err != nil {
+		return nil, err
+	}
+
+	vectorMatches := make(map[string]bool, len(vectorResults))
+	for _, result := range vectorResults {
+		vectorMatches[result.memory.ID] = true
+	}
+	keywordMatches := make(map[string]bool, len(keywordResults))
+	for _, result := range keywordResults {
+		keywordMatches[result.memory.ID] = true
+	}
+
+	// Step 5: Build results
+	retrievedMemories := make([]models.RetrievedMemory, 0, req.MaxResults)
+	for _, result := range ranked {
+		// The threshold applies to the reported score, so an inherited
+		// workspace's memory must be more similar to pass it. Keyword matches
+		// contain a query term, such as an identifier whose embedding need not
+		// be close to the query's, so BM25 ranks them instead.
+		relevance := similarities[result.memory.ID] * workspaceWeight(weights, result.memory.WorkspaceID)
+		if relevance < req.MinRelevance && !keywordMatches[result.memory.ID] {
+			continue
+		}
+
+		retrieved := models.RetrievedMemory{
+			Memory:         *result.memory,
+			RelevanceScore: relevance,
+			MatchReason:    s.generateMatchReason(req.Query, result.memory, vectorMatches[result.memory.ID]),
+		}
+		if req.SearchMode != models.SearchModeVector {
+			retrieved.RankScore = result.score
+		}
+		retrievedMemories = append(retrievedMemories, retrieved)
+
+		if len(retrievedMemories) >= req.MaxResults {
+			break
+		}
+	}
+
+	s.logger.Info("Memory retrieval completed",
+		zap.String("search_mode", req.SearchMode),
+		zap.Int("vector_candidates", len(vectorResults)),
+		zap.Int("keyword_candidates", len(keywordResults)),
+		zap.Int("total_found", len(retrievedMemories)))
+
+	return &models.RetrieveMemoryResponse{
+		Memories:   retrievedMemories,
+		TotalFound: len(retrievedMemories),
+	}, nil
+}
+
+// vectorSearch ranks memories by embedding similarity to the query
+func (s *System) vectorSearch(ctx context.Context, req models.RetrieveMemoryRequest, queryEmbedding []float32, filters map[string]interface{}) ([]scoredMemory, error) {
+	memories, distances, err := s.store.SearchSimilar(ctx, queryEmbedding, req.MaxResults*2, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search memories: %w", err)
+	}
+
+	results := make([]scoredMemory, 0, len(memories))
+	for i, memory := range memories {
+		if i >= len(distances) {
+			break
+		}
+
+		relevanceScore := services.Similarity(s.store.DistanceMetric(), distances[i])
+		results = append(results, scoredMemory{memory: memory, score: relevanceScore})
+	}
+
+	return results, nil
+}
+
+// similarities returns the calibrated similarity of each ranked memory to the
+// query. Vector results already carry theirs; memories found by keyword only
+// are compared using their stored embeddings.
+func (s *System) similarities(ctx context.Context, queryEmbedding []float32, ranked, vectorResults []scoredMemory) (map[string]float32, error) {
+	similarities := make(map[string]float32, len(ranked))
+	for _, result := range vectorResults {
+		similarities[result.memory.ID] = result.score
+	}
+
+	var missing []string
+	for _, result := range ranked {
+		if _, ok := similarities[result.memory.ID]; !ok {
+			missing = append(missing, result.memory.ID)
+		}
+	}
+	if len(missing) == 0 {
+		return similarities, nil
+	}
+
+	memories, err := s.store.GetMemories(ctx, missing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load embeddings of keyword matches: %w", err)
+	}
+
+	metric := s.store.DistanceMetric()
+	for _, memory := range memories {
+		if len(memory.Embedding) != len(queryEmbedding) {
+			continue
+		}
+		similarities[memory.ID] = services.Similarity(metric, services.VectorDistance(metric, queryEmbedding, memory.Embedding))
+	}
+
+	return similarities, nil
+}
+
+// keywordSearch ranks memories by BM25 over their content, keywords and tags.
+// The tokenized memories are cached, so a search reads the metadata of the
+// memories matching filters and the full records of those changed since the
+// last one. The cache applies the same scope as filters.
+func (s *System) keywordSearch(ctx context.Context, req models.RetrieveMemoryRequest, weights map[string]float32, filters map[string]interface{}) ([]scoredMemory, error) {
+	codeTypes := make(map[string]bool, len(req.CodeTypes))
+	for _, codeType := range req.CodeTypes {
+		codeTypes[codeType] = true
+	}
+
+	docs, err := s.keywords.snapshot(ctx, s.store, filters, func(memory *models.Memory) bool {
+		if len(codeTypes) > 0 && !codeTypes[memory.CodeType] {
+			return false
+		}
+		if req.AllWorkspaces {
+			return true
+		}
+		if _, ok := weights[memory.WorkspaceID]; ok {
+			return true
+		}
+		// Backward compatibility: project_filter also matches project_path
+		return req.ProjectFilter != "" && memory.ProjectPath == req.ProjectFilter
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load memories for keyword search: %w", err)
+	}
+
+	return (&keywordIndex{docs: docs}).search(req.Query, req.MaxResults*2), nil
+}
+
+// ListMemories pages through stored memories matching metadata filters.
+// ChromaDB's get endpoint cannot sort, so the sort keys of the matching
+// memories are read from their metadata alone, and content is fetched only
+// for the requested page. Cursors hold the sort key of the last memory
+// listed, so memories stored between pages do not shift later pages.
+func (s *System) ListMemories(ctx context.Context, req models.ListMemoriesRequest) (*models.ListMemoriesResponse, error) {
+	// Set defaults
+	if req.Limit <= 0 {
+		req.Limit = 20
+	}
+	if req.Limit > 100 {
+		req.Limit = 100
+	}
+	if req.SortBy == "" {
+		req.SortBy = "created_at"
+	}
+	if req.SortOrder == "" {
+		req.SortOrder = "desc"
+	}
+
+	if req.SortBy != "created_at" && req.SortBy != "updated_at" {
+		return nil, fmt.Errorf("invalid sort_by %q: must be created_at or updated_at", req.SortBy)
+	}
+	if req.SortOrder != "asc" && req.SortOrder != "desc" {
+		return nil, fmt.Errorf("invalid sort_order %q: must be asc or desc", req.SortOrder)
+	}
+	ascending := req.SortOrder == "asc"
+
+	var cursor *listCursor
+	offset := req.Offset
+	if req.Cursor != "" {
+		decoded, err := decodeCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = decoded
+		offset = cursor.Position
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	// Build filters
+	var conditions []map[string]interface{}
+
+	if req.WorkspaceID != "" {
 		conditions = append(conditions, map[string]interface{}{
-			"workspace_id": workspaceID,
+			"workspace_id": s.workspaceService.NormalizeWorkspaceID(req.WorkspaceID),
 		})
 	}
 
-	// Add code type filter
 	if len(req.CodeTypes) > 0 {
 		conditions = append(conditions, map[string]interface{}{
 			"code_type": map[string]interface{}{
@@ -183,89 +568,279 @@ func (s *System) RetrieveMemories(ctx context.Context, req models.RetrieveMemory
 		})
 	}
 
-	// Build final filter structure
-	var filters map[string]interface{}
-	if len(conditions) == 1 {
-		// Single condition - use it directly
-		filters = conditions[0]
-	} else if len(conditions) > 1 {
-		// Multiple conditions - wrap in $and
-		filters = map[string]interface{}{
-			"$and": conditions,
+	if !req.CreatedAfter.IsZero() {
+		conditions = append(conditions, map[string]interface{}{
+			"created_at": map[string]interface{}{
+				"$gte": req.CreatedAfter.Unix(),
+			},
+		})
+	}
+
+	if !req.CreatedBefore.IsZero() {
+		conditions = append(conditions, map[string]interface{}{
+			"created_at": map[string]interface{}{
+				"$lte": req.CreatedBefore.Unix(),
+			},
+		})
+	}
+
+	// Past the first page only memories at or beyond the cursor's time are read
+	scanConditions := conditions
+	if cursor != nil {
+		operator := "$lte"
+		if ascending {
+			operator = "$gte"
 		}
-	} else {
-		// No conditions
-		filters = make(map[string]interface{})
+		scanConditions = append(append([]map[string]interface{}(nil), conditions...), map[string]interface{}{
+			req.SortBy: map[string]interface{}{
+				operator: time.Unix(0, cursor.SortTime).Unix(),
+			},
+		})
 	}
 
-	// Step 3: Search similar memories
-	memories, distances, err := s.chromaDB.SearchSimilar(ctx, queryEmbedding, req.MaxResults*2, filters)
+	s.logger.Info("Listing memories",
+		zap.String("workspace_id", req.WorkspaceID),
+		zap.Int("limit", req.Limit),
+		zap.Int("offset", offset))
+
+	// Tags are stored as a joined string, which ChromaDB cannot filter on, so
+	// they are checked against the scanned metadata
+	var keys []listKey
+	err := s.store.ScanMemories(ctx, combineFilters(scanConditions), func(memory *models.Memory) error {
+		if len(req.Tags) > 0 && !services.HasAllTags(memory, req.Tags) {
+			return nil
+		}
+
+		key := listKey{id: memory.ID, sortTime: memory.CreatedAt.UnixNano()}
+		if req.SortBy == "updated_at" {
+			key.sortTime = memory.UpdatedAt.UnixNano()
+		}
+		if cursor == nil || key.follows(cursor.key(), ascending) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to search memories: %w", err)
+		return nil, fmt.Errorf("failed to list memories: %w", err)
 	}
 
-	// Step 4: Rank and filter results
-	retrievedMemories := make([]models.RetrievedMemory, 0, req.MaxResults)
-	for i, memory := range memories {
-		if i >= len(distances) {
-			break
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[j].follows(keys[i], ascending)
+	})
+
+	// A cursor's keys already start after the previous page
+	skip := offset
+	if cursor != nil {
+		skip = 0
+	}
+	if skip > len(keys) {
+		skip = len(keys)
+	}
+	end := skip + req.Limit
+	if end > len(keys) {
+		end = len(keys)
+	}
+	page := keys[skip:end]
+
+	totalCount := len(keys)
+	if cursor != nil {
+		totalCount = cursor.Position + len(keys)
+		if len(req.Tags) == 0 {
+			if totalCount, err = s.store.CountMemories(ctx, combineFilters(conditions)); err != nil {
+				return nil, fmt.Errorf("failed to count memories: %w", err)
+			}
 		}
+	}
 
-		// Convert distance to similarity score (inverse relationship for L2 distance)
-		relevanceScore := 1.0 / (1.0 + distances[i])
+	response := &models.ListMemoriesResponse{
+		Memories:   []models.Memory{},
+		TotalCount: totalCount,
+		Offset:     offset,
+	}
 
-		if relevanceScore < req.MinRelevance {
-			continue
+	if len(page) > 0 {
+		ids := make([]string, len(page))
+		for i, key := range page {
+			ids[i] = key.id
+		}
+
+		memories, err := s.store.GetMemories(ctx, ids)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list memories: %w", err)
+		}
+		byID := make(map[string]*models.Memory, len(memories))
+		for _, memory := range memories {
+			memory.Embedding = nil
+			byID[memory.ID] = memory
 		}
 
-		retrievedMemory := models.RetrievedMemory{
-			Memory:         *memory,
-			RelevanceScore: relevanceScore,
-			MatchReason:    s.generateMatchReason(req.Query, memory),
+		// Memories deleted since the scan are left out
+		for _, id := range ids {
+			if memory, ok := byID[id]; ok {
+				response.Memories = append(response.Memories, *memory)
+			}
 		}
+	}
+
+	if end < len(keys) {
+		last := page[len(page)-1]
+		response.NextCursor = encodeCursor(listCursor{
+			SortTime: last.sortTime,
+			ID:       last.id,
+			Position: offset + len(page),
+		})
+	}
 
-		retrievedMemories = append(retrievedMemories, retrievedMemory)
+	return response, nil
+}
 
-		if len(retrievedMemories) >= req.MaxResults {
-			break
+// GetMemory fetches a single memory by ID
+func (s *System) GetMemory(ctx context.Context, memoryID string) (*models.Memory, error) {
+	memory, err := s.store.GetMemory(ctx, memoryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get memory: %w", err)
+	}
+
+	return memory, nil
+}
+
+// UpdateMemory corrects an existing memory. Changing the content or code type
+// re-runs note construction, and changing the content re-embeds the memory.
+func (s *System) UpdateMemory(ctx context.Context, req models.UpdateMemoryRequest) (*models.UpdateMemoryResponse, error) {
+	defer s.locks.Lock(req.MemoryID)()
+
+	memory, err := s.GetMemory(ctx, req.MemoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Updating memory",
+		zap.String("memory_id", req.MemoryID))
+
+	contentChanged := req.Content != "" && req.Content != memory.Content
+	codeTypeChanged := req.CodeType != "" && req.CodeType != memory.CodeType
+
+	if contentChanged {
+		memory.Content = req.Content
+	}
+	if codeTypeChanged {
+		memory.CodeType = req.CodeType
+	}
+
+	if contentChanged || codeTypeChanged {
+		noteResult, err := s.constructNote(ctx, models.StoreMemoryRequest{
+			Content:     memory.Content,
+			ProjectPath: memory.ProjectPath,
+			WorkspaceID: memory.WorkspaceID,
+			CodeType:    memory.CodeType,
+			Context:     req.Context,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to construct note: %w", err)
 		}
+
+		memory.Context = noteResult.Context
+		memory.Keywords = noteResult.Keywords
+		memory.Tags = noteResult.Tags
 	}
 
-	s.logger.Info("Memory retrieval completed",
-		zap.Int("total_found", len(retrievedMemories)))
+	// Explicit corrections win over the regenerated note
+	if req.Context != "" {
+		memory.Context = req.Context
+	}
+	if len(req.Tags) > 0 {
+		memory.Tags = req.Tags
+	}
 
-	return &models.RetrieveMemoryResponse{
-		Memories:   retrievedMemories,
-		TotalFound: len(retrievedMemories),
+	if err := s.saveMemory(ctx, memory, contentChanged); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Memory updated successfully",
+		zap.String("memory_id", memory.ID),
+		zap.Bool("reembedded", contentChanged))
+
+	return &models.UpdateMemoryResponse{
+		MemoryID:   memory.ID,
+		Context:    memory.Context,
+		Keywords:   memory.Keywords,
+		Tags:       memory.Tags,
+		Reembedded: contentChanged,
+	}, nil
+}
+
+// DeleteMemory permanently removes a memory and the links other memories hold to it
+func (s *System) DeleteMemory(ctx context.Context, memoryID string) (*models.DeleteMemoryResponse, error) {
+	// The lock is released before other memories are unlinked, which takes theirs
+	unlock := s.locks.Lock(memoryID)
+	memory, err := s.GetMemory(ctx, memoryID)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+
+	s.logger.Info("Deleting memory",
+		zap.String("memory_id", memoryID))
+
+	err = s.store.DeleteMemories(ctx, []string{memoryID})
+	unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete memory: %w", err)
+	}
+	s.notifyChange(memory)
+
+	linksRemoved := s.removeInboundLinks(ctx, map[string]bool{memoryID: true})
+
+	s.logger.Info("Memory deleted successfully",
+		zap.String("memory_id", memoryID),
+		zap.Int("links_removed", linksRemoved))
+
+	return &models.DeleteMemoryResponse{
+		MemoryID:     memoryID,
+		LinksRemoved: linksRemoved,
 	}, nil
 }
 
+// saveMemory writes changes to an existing memory back to storage, re-embedding
+// its content when it has changed and bumping UpdatedAt
+func (s *System) saveMemory(ctx context.Context, memory *models.Memory, contentChanged bool) error {
+	if contentChanged || len(memory.Embedding) == 0 {
+		embedding, err := s.embeddingService.GenerateEmbedding(ctx, memory.Content)
+		if err != nil {
+			return fmt.Errorf("failed to generate embedding: %w", err)
+		}
+		memory.Embedding = embedding
+	}
+
+	memory.UpdatedAt = time.Now()
+
+	if err := s.store.UpdateMemory(ctx, memory); err != nil {
+		return fmt.Errorf("failed to update memory: %w", err)
+	}
+	s.notifyChange(memory)
+
+	return nil
+}
+
 // constructNote uses LLM to analyze content and extract structured information
 func (s *System) constructNote(ctx context.Context, req models.StoreMemoryRequest) (*models.NoteConstructionResult, error) {
-	prompt := fmt.Sprintf(`Generate a structured analysis of the following coding content by:
-1. Identifying the most salient keywords (focus on technical terms, functions, concepts)
-2. Extracting core programming themes and contextual elements
-3. Creating relevant categorical tags for coding classification
-
-For coding context, consider:
-- Programming language and frameworks used
-- Problem domain (web dev, algorithms, data structures, etc.)
-- Solution patterns and techniques
-- Error types and debugging context
-- Libraries and dependencies mentioned
-
-Format the response as a JSON object:
-{
-  "keywords": [// 3-7 specific technical keywords, ordered by importance],
-  "context": // one sentence summarizing the coding problem/solution/concept,
-  "tags": [// 3-6 broad categories: language, domain, pattern type, difficulty]
-}
+	promptName := s.promptManager.NoteConstructionPrompt()
+	prompt, err := s.promptManager.RenderPrompt(promptName, services.PromptData{
+		Content:     req.Content,
+		ProjectPath: req.ProjectPath,
+		CodeType:    req.CodeType,
+		Context:     req.Context,
+	})
+	if err != nil {
+		return nil, err
+	}
 
-Content for analysis: %s
-Project Path: %s
-Code Type: %s`, req.Content, req.ProjectPath, req.CodeType)
+	modelConfig, err := s.promptManager.GetModelConfig(promptName)
+	if err != nil {
+		return nil, err
+	}
 
-	response, err := s.llmService.CallWithRetry(ctx, prompt, true)
+	response, err := s.llmService.CallWithConfig(ctx, prompt, *modelConfig, true)
 	if err != nil {
 		return nil, fmt.Errorf("LLM call failed: %w", err)
 	}
@@ -292,7 +867,7 @@ Code Type: %s`, req.Content, req.ProjectPath, req.CodeType)
 // generateLinks creates links between the new memory and existing similar memories
 func (s *System) generateLinks(ctx context.Context, memory *models.Memory) ([]models.MemoryLink, error) {
 	// Search for similar memories
-	similarMemories, distances, err := s.chromaDB.SearchSimilar(ctx, memory.Embedding, 10, nil)
+	similarMemories, distances, err := s.store.SearchSimilar(ctx, memory.Embedding, 10, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -307,10 +882,9 @@ func (s *System) generateLinks(ctx context.Context, memory *models.Memory) ([]mo
 			break
 		}
 
-		// Convert distance to similarity
-		similarity := 1.0 - distances[i]
+		similarity := services.Similarity(s.store.DistanceMetric(), distances[i])
 
-		if similarity > 0.7 { // Threshold for creating links
+		if similarity > linkThreshold {
 			linkType := s.determineLinkType(memory, similarMemory)
 			reason := s.generateLinkReason(memory, similarMemory, similarity)
 
@@ -353,31 +927,78 @@ func (s *System) generateLinkReason(memory1, memory2 *models.Memory, similarity
 }
 
 // generateMatchReason generates a reason why a memory matched the query
-func (s *System) generateMatchReason(query string, memory *models.Memory) string {
-	// Simple keyword matching for now
-	for _, keyword := range memory.Keywords {
-		if contains(query, keyword) {
-			return fmt.Sprintf("Keyword match: %s", keyword)
+func (s *System) generateMatchReason(query string, memory *models.Memory, vectorMatch bool) string {
+	term := matchingTerm(query, memory)
+
+	switch {
+	case term != "" && vectorMatch:
+		return fmt.Sprintf("Keyword and content similarity match: %s", term)
+	case term != "":
+		return fmt.Sprintf("Keyword match: %s", term)
+	default:
+		return "Content similarity match"
+	}
+}
+
+// combineFilters joins ChromaDB where conditions, wrapping several in $and
+func combineFilters(conditions []map[string]interface{}) map[string]interface{} {
+	switch len(conditions) {
+	case 0:
+		return make(map[string]interface{})
+	case 1:
+		return conditions[0]
+	default:
+		return map[string]interface{}{
+			"$and": conditions,
 		}
 	}
-	return "Content similarity match"
 }
 
-// contains checks if a string contains a substring (case-insensitive)
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) &&
-		(s == substr ||
-			(len(s) > len(substr) &&
-				(s[:len(substr)] == substr ||
-					s[len(s)-len(substr):] == substr ||
-					findSubstring(s, substr))))
+// listKey is the position of a memory in a sorted list
+type listKey struct {
+	id       string
+	sortTime int64 // Unix nanoseconds of the sorted timestamp
 }
 
-func findSubstring(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
-		}
+// follows reports whether k comes after other in the list. Memories with the
+// same timestamp are ordered by ID so every memory has one place.
+func (k listKey) follows(other listKey, ascending bool) bool {
+	if k.sortTime != other.sortTime {
+		return (k.sortTime > other.sortTime) == ascending
+	}
+	return k.id != other.id && (k.id > other.id) == ascending
+}
+
+// listCursor is the content of a pagination cursor: the sort key of the last
+// memory listed and how many memories were listed before the next page
+type listCursor struct {
+	SortTime int64  `json:"t"`
+	ID       string `json:"id"`
+	Position int    `json:"n"`
+}
+
+// key returns the list key of the memory the cursor points at
+func (c *listCursor) key() listKey {
+	return listKey{id: c.ID, sortTime: c.SortTime}
+}
+
+// encodeCursor turns the end of a page into an opaque pagination cursor
+func encodeCursor(cursor listCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor turns a pagination cursor back into the end of a page
+func decodeCursor(cursor string) (*listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
 	}
-	return false
+
+	var decoded listCursor
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.ID == "" {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	return &decoded, nil
 }
@@ -3,7 +3,10 @@ package memory
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/amem/mcp-server/pkg/ingest"
 	"github.com/amem/mcp-server/pkg/models"
 	"go.uber.org/zap"
 )
@@ -31,41 +34,33 @@ func (t *StoreCodingMemoryTool) Description() string {
 }
 
 func (t *StoreCodingMemoryTool) InputSchema() map[string]interface{} {
+	properties := storeMemoryProperties()
+	properties["format"] = formatProperty()
+
 	return map[string]interface{}{
-		"type": "object",
-		"properties": map[string]interface{}{
-			"content": map[string]interface{}{
-				"type":        "string",
-				"description": "The code content or coding context to store",
-			},
-			"workspace_id": map[string]interface{}{
-				"type":        "string",
-				"description": "Workspace identifier (path or name) for organizing memories",
-			},
-			"project_path": map[string]interface{}{
-				"type":        "string",
-				"description": "Optional project path for context (deprecated: use workspace_id)",
-			},
-			"code_type": map[string]interface{}{
-				"type":        "string",
-				"description": "Programming language or code type (e.g., 'javascript', 'python', 'go')",
-			},
-			"context": map[string]interface{}{
-				"type":        "string",
-				"description": "Additional context about the code",
-			},
-		},
-		"required": []string{"content"},
+		"type":       "object",
+		"properties": properties,
+		"required":   []string{"content"},
 	}
 }
 
+func (t *StoreCodingMemoryTool) OutputSchema() map[string]interface{} {
+	return models.JSONSchemaFor(models.StoreMemoryResponse{})
+}
+
 func (t *StoreCodingMemoryTool) Execute(ctx context.Context, args map[string]interface{}) (*models.MCPToolResult, error) {
+	format, err := parseFormat(args)
+	if err != nil {
+		return argumentErrorResult(err), nil
+	}
+
 	// Parse arguments
 	var req models.StoreMemoryRequest
+	if err := models.DecodeArguments(args, &req); err != nil {
+		return argumentErrorResult(err), nil
+	}
 
-	if content, ok := args["content"].(string); ok {
-		req.Content = content
-	} else {
+	if req.Content == "" {
 		return &models.MCPToolResult{
 			IsError: true,
 			Content: []models.MCPContent{{
@@ -75,22 +70,6 @@ func (t *StoreCodingMemoryTool) Execute(ctx context.Context, args map[string]int
 		}, nil
 	}
 
-	if workspaceID, ok := args["workspace_id"].(string); ok {
-		req.WorkspaceID = workspaceID
-	}
-
-	if projectPath, ok := args["project_path"].(string); ok {
-		req.ProjectPath = projectPath
-	}
-
-	if codeType, ok := args["code_type"].(string); ok {
-		req.CodeType = codeType
-	}
-
-	if context, ok := args["context"].(string); ok {
-		req.Context = context
-	}
-
 	// Execute memory creation
 	response, err := t.system.CreateMemory(ctx, req)
 	if err != nil {
@@ -104,8 +83,17 @@ func (t *StoreCodingMemoryTool) Execute(ctx context.Context, args map[string]int
 		}, nil
 	}
 
-	// Format response
-	resultText := fmt.Sprintf(`Memory stored successfully!
+	markdown := func() string {
+		switch response.Action {
+		case models.StoreActionSkipped:
+			return fmt.Sprintf("An existing memory is %.1f%% similar, so nothing was stored.\n\nMemory ID: %s\nKeywords: %v\nTags: %v\n\nStore with dedupe 'merge' to fold this content into it, or 'force' to store it separately.",
+				response.Similarity*100, response.MemoryID, response.Keywords, response.Tags)
+		case models.StoreActionMerged:
+			return fmt.Sprintf("Merged into an existing memory that was %.1f%% similar.\n\nMemory ID: %s\nKeywords: %v\nTags: %v",
+				response.Similarity*100, response.MemoryID, response.Keywords, response.Tags)
+		}
+
+		return fmt.Sprintf(`Memory stored successfully!
 
 Memory ID: %s
 Keywords: %v
@@ -113,17 +101,181 @@ Tags: %v
 Links Created: %d
 
 The memory has been analyzed and stored with AI-generated keywords and tags. It's now available for future retrieval and will be linked to related memories.`,
-		response.MemoryID,
-		response.Keywords,
-		response.Tags,
-		response.LinksCreated)
+			response.MemoryID,
+			response.Keywords,
+			response.Tags,
+			response.LinksCreated)
+	}
+	compact := func() string {
+		if response.Action != models.StoreActionCreated {
+			return fmt.Sprintf("%s %s similarity=%.3f", response.Action, response.MemoryID, response.Similarity)
+		}
+		return fmt.Sprintf("stored %s links=%d tags=%s",
+			response.MemoryID, response.LinksCreated, strings.Join(response.Tags, ","))
+	}
+
+	return structuredResult(format, response, markdown, compact), nil
+}
+
+// StoreCodingMemoriesBatchTool implements the store_coding_memories_batch MCP tool
+type StoreCodingMemoriesBatchTool struct {
+	system *System
+	logger *zap.Logger
+}
+
+// NewStoreCodingMemoriesBatchTool creates a new batch store tool
+func NewStoreCodingMemoriesBatchTool(system *System, logger *zap.Logger) *StoreCodingMemoriesBatchTool {
+	return &StoreCodingMemoriesBatchTool{
+		system: system,
+		logger: logger,
+	}
+}
+
+func (t *StoreCodingMemoriesBatchTool) Name() string {
+	return models.ToolStoreCodingMemoriesBatch
+}
+
+func (t *StoreCodingMemoriesBatchTool) Description() string {
+	return "Store many coding memories in one call, with batched embeddings and parallel note construction. Each memory succeeds or fails on its own"
+}
+
+func (t *StoreCodingMemoriesBatchTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"memories": map[string]interface{}{
+				"type":        "array",
+				"description": "Memories to store, each taking the same fields as store_coding_memory",
+				"items": map[string]interface{}{
+					"type":       "object",
+					"properties": storeMemoryProperties(),
+					"required":   []string{"content"},
+				},
+			},
+			"concurrency": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum number of notes constructed by the LLM at once (default: %d)", defaultBatchConcurrency),
+				"default":     defaultBatchConcurrency,
+				"minimum":     1,
+				"maximum":     32,
+			},
+			"format": formatProperty(),
+		},
+		"required": []string{"memories"},
+	}
+}
 
-	return &models.MCPToolResult{
-		Content: []models.MCPContent{{
-			Type: "text",
-			Text: resultText,
-		}},
-	}, nil
+func (t *StoreCodingMemoriesBatchTool) OutputSchema() map[string]interface{} {
+	return models.JSONSchemaFor(models.StoreMemoriesBatchResponse{})
+}
+
+func (t *StoreCodingMemoriesBatchTool) Execute(ctx context.Context, args map[string]interface{}) (*models.MCPToolResult, error) {
+	format, err := parseFormat(args)
+	if err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	// Parse arguments
+	var req models.StoreMemoriesBatchRequest
+	if err := models.DecodeArguments(args, &req); err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	if len(req.Memories) == 0 {
+		return &models.MCPToolResult{
+			IsError: true,
+			Content: []models.MCPContent{{
+				Type: "text",
+				Text: "Error: 'memories' parameter is required and must be a non-empty array",
+			}},
+		}, nil
+	}
+
+	response, err := t.system.CreateMemories(ctx, req)
+	if err != nil {
+		t.logger.Error("Failed to store memories", zap.Error(err))
+		return &models.MCPToolResult{
+			IsError: true,
+			Content: []models.MCPContent{{
+				Type: "text",
+				Text: fmt.Sprintf("Failed to store memories: %v", err),
+			}},
+		}, nil
+	}
+
+	markdown := func() string {
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("Stored %d of %d memories in %dms: %d created, %d skipped, %d merged, %d failed.\n\n",
+			len(response.Results)-response.Failed, len(response.Results), response.DurationMs,
+			response.Created, response.Skipped, response.Merged, response.Failed))
+
+		for _, result := range response.Results {
+			if !result.Success {
+				sb.WriteString(fmt.Sprintf("%d. Failed: %s\n", result.Index+1, result.Error))
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("%d. %s %s", result.Index+1, result.Response.Action, result.Response.MemoryID))
+			if result.Response.Action == models.StoreActionCreated {
+				sb.WriteString(fmt.Sprintf(" (%d links)", result.Response.LinksCreated))
+			} else {
+				sb.WriteString(fmt.Sprintf(" (%.1f%% similar)", result.Response.Similarity*100))
+			}
+			sb.WriteString("\n")
+		}
+		return sb.String()
+	}
+	compact := func() string {
+		lines := []string{fmt.Sprintf("created=%d skipped=%d merged=%d failed=%d",
+			response.Created, response.Skipped, response.Merged, response.Failed)}
+		for _, result := range response.Results {
+			if !result.Success {
+				lines = append(lines, fmt.Sprintf("%d failed %s", result.Index, result.Error))
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("%d %s %s", result.Index, result.Response.Action, result.Response.MemoryID))
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	return structuredResult(format, response, markdown, compact), nil
+}
+
+// storeMemoryProperties is the input schema of a single memory to store
+func storeMemoryProperties() map[string]interface{} {
+	return map[string]interface{}{
+		"content": map[string]interface{}{
+			"type":        "string",
+			"description": "The code content or coding context to store",
+			"minLength":   1,
+		},
+		"workspace_id": map[string]interface{}{
+			"type":        "string",
+			"description": "Workspace identifier (path or name) for organizing memories",
+		},
+		"project_path": map[string]interface{}{
+			"type":        "string",
+			"description": "Optional project path for context (deprecated: use workspace_id)",
+		},
+		"code_type": map[string]interface{}{
+			"type":        "string",
+			"description": "Programming language or code type (e.g., 'javascript', 'python', 'go')",
+		},
+		"context": map[string]interface{}{
+			"type":        "string",
+			"description": "Additional context about the code",
+		},
+		"dedupe": map[string]interface{}{
+			"type":        "string",
+			"enum":        []string{models.DedupeSkip, models.DedupeMerge, models.DedupeForce},
+			"description": "What to do when a near-identical memory exists in the same workspace: 'skip' returns its ID, 'merge' merges this content into it, 'force' stores a new memory anyway (default: server setting)",
+		},
+		"similarity_threshold": map[string]interface{}{
+			"type":        "number",
+			"description": "Similarity (0.0-1.0) at which an existing memory counts as a duplicate (default: server setting)",
+			"minimum":     0,
+			"maximum":     1,
+		},
+	}
 }
 
 // RetrieveRelevantMemoriesTool implements the retrieve_relevant_memories MCP tool
@@ -145,7 +297,7 @@ func (t *RetrieveRelevantMemoriesTool) Name() string {
 }
 
 func (t *RetrieveRelevantMemoriesTool) Description() string {
-	return "Retrieve relevant coding memories based on a query using vector similarity search"
+	return "Retrieve relevant coding memories based on a query using vector similarity, keyword (BM25) or hybrid search"
 }
 
 func (t *RetrieveRelevantMemoriesTool) InputSchema() map[string]interface{} {
@@ -155,15 +307,38 @@ func (t *RetrieveRelevantMemoriesTool) InputSchema() map[string]interface{} {
 			"query": map[string]interface{}{
 				"type":        "string",
 				"description": "The search query (code snippet, problem description, or keywords)",
+				"minLength":   1,
 			},
 			"workspace_id": map[string]interface{}{
 				"type":        "string",
 				"description": "Workspace identifier to filter results (optional)",
 			},
+			"workspace_ids": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Search several workspaces at once, together with workspace_id if given",
+			},
+			"all_workspaces": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Search every workspace",
+				"default":     false,
+			},
+			"skip_inherited": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Do not search the parent workspaces the searched workspaces inherit from",
+				"default":     false,
+			},
+			"workspace_weights": map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": map[string]interface{}{"type": "number", "minimum": 0},
+				"description":          "Score multiplier per workspace. Inherited workspaces default to 0.8 per level of inheritance and others to 1; 0 excludes a workspace",
+			},
 			"max_results": map[string]interface{}{
 				"type":        "integer",
 				"description": "Maximum number of results to return (default: 5)",
 				"default":     5,
+				"minimum":     1,
+				"maximum":     100,
 			},
 			"project_filter": map[string]interface{}{
 				"type":        "string",
@@ -176,21 +351,43 @@ func (t *RetrieveRelevantMemoriesTool) InputSchema() map[string]interface{} {
 			},
 			"min_relevance": map[string]interface{}{
 				"type":        "number",
-				"description": "Minimum relevance score (0.0-1.0, default: 0.7)",
+				"description": "Minimum relevance score, the similarity to the query multiplied by the workspace weight (0.0-1.0, default: 0.7), applied after ranking. Keyword matches are kept whatever their similarity",
 				"default":     0.7,
+				"minimum":     0,
+				"maximum":     1,
+			},
+			"search_mode": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{models.SearchModeVector, models.SearchModeKeyword, models.SearchModeHybrid},
+				"description": "How to match memories: 'vector' (semantic; default), 'keyword' (exact identifiers, BM25) or 'hybrid' (both, fused by rank). Keyword and hybrid search an in-memory index that re-reads only the memories changed since the last search",
+				"default":     models.SearchModeVector,
 			},
+			"format": formatProperty(),
 		},
 		"required": []string{"query"},
 	}
 }
 
+func (t *RetrieveRelevantMemoriesTool) OutputSchema() map[string]interface{} {
+	return models.JSONSchemaFor(models.RetrieveMemoryResponse{})
+}
+
 func (t *RetrieveRelevantMemoriesTool) Execute(ctx context.Context, args map[string]interface{}) (*models.MCPToolResult, error) {
+	format, err := parseFormat(args)
+	if err != nil {
+		return argumentErrorResult(err), nil
+	}
+
 	// Parse arguments
-	var req models.RetrieveMemoryRequest
+	req := models.RetrieveMemoryRequest{
+		MaxResults:   5,
+		MinRelevance: 0.7,
+	}
+	if err := models.DecodeArguments(args, &req); err != nil {
+		return argumentErrorResult(err), nil
+	}
 
-	if query, ok := args["query"].(string); ok {
-		req.Query = query
-	} else {
+	if req.Query == "" {
 		return &models.MCPToolResult{
 			IsError: true,
 			Content: []models.MCPContent{{
@@ -200,36 +397,6 @@ func (t *RetrieveRelevantMemoriesTool) Execute(ctx context.Context, args map[str
 		}, nil
 	}
 
-	if workspaceID, ok := args["workspace_id"].(string); ok {
-		req.WorkspaceID = workspaceID
-	}
-
-	if maxResults, ok := args["max_results"].(float64); ok {
-		req.MaxResults = int(maxResults)
-	} else {
-		req.MaxResults = 5
-	}
-
-	if projectFilter, ok := args["project_filter"].(string); ok {
-		req.ProjectFilter = projectFilter
-	}
-
-	if codeTypesInterface, ok := args["code_types"].([]interface{}); ok {
-		codeTypes := make([]string, len(codeTypesInterface))
-		for i, ct := range codeTypesInterface {
-			if ctStr, ok := ct.(string); ok {
-				codeTypes[i] = ctStr
-			}
-		}
-		req.CodeTypes = codeTypes
-	}
-
-	if minRelevance, ok := args["min_relevance"].(float64); ok {
-		req.MinRelevance = float32(minRelevance)
-	} else {
-		req.MinRelevance = 0.7
-	}
-
 	// Execute memory retrieval
 	response, err := t.system.RetrieveMemories(ctx, req)
 	if err != nil {
@@ -243,31 +410,35 @@ func (t *RetrieveRelevantMemoriesTool) Execute(ctx context.Context, args map[str
 		}, nil
 	}
 
-	// Format response
-	if len(response.Memories) == 0 {
-		return &models.MCPToolResult{
-			Content: []models.MCPContent{{
-				Type: "text",
-				Text: "No relevant memories found for your query. Try adjusting your search terms or lowering the relevance threshold.",
-			}},
-		}, nil
+	for i := range response.Memories {
+		response.Memories[i].Memory = withoutEmbedding(response.Memories[i].Memory)
 	}
 
-	resultText := fmt.Sprintf("Found %d relevant memories:\n\n", response.TotalFound)
+	markdown := func() string {
+		if len(response.Memories) == 0 {
+			return "No relevant memories found for your query. Try adjusting your search terms or lowering the relevance threshold."
+		}
 
-	for i, memory := range response.Memories {
-		resultText += fmt.Sprintf("**Memory %d** (Relevance: %.1f%%)\nID: %s\nContext: %s\nKeywords: %v\nTags: %v\nProject: %s\nCode Type: %s\nMatch Reason: %s\n\nContent:\n```\n%s\n```\n\n---\n\n",
-			i+1, memory.RelevanceScore*100, memory.ID, memory.Context,
-			memory.Keywords, memory.Tags, memory.ProjectPath,
-			memory.CodeType, memory.MatchReason, memory.Content)
+		resultText := fmt.Sprintf("Found %d relevant memories:\n\n", response.TotalFound)
+
+		for i, memory := range response.Memories {
+			resultText += fmt.Sprintf("**Memory %d** (Relevance: %.1f%%)\nID: %s\nContext: %s\nKeywords: %v\nTags: %v\nProject: %s\nCode Type: %s\nMatch Reason: %s\n\nContent:\n```\n%s\n```\n\n---\n\n",
+				i+1, memory.RelevanceScore*100, memory.ID, memory.Context,
+				memory.Keywords, memory.Tags, memory.ProjectPath,
+				memory.CodeType, memory.MatchReason, memory.Content)
+		}
+
+		return resultText
+	}
+	compact := func() string {
+		var lines []string
+		for _, memory := range response.Memories {
+			lines = append(lines, fmt.Sprintf("%s %.3f [%s] %s", memory.ID, memory.RelevanceScore, memory.CodeType, memory.Context))
+		}
+		return strings.Join(lines, "\n")
 	}
 
-	return &models.MCPToolResult{
-		Content: []models.MCPContent{{
-			Type: "text",
-			Text: resultText,
-		}},
-	}, nil
+	return structuredResult(format, response, markdown, compact), nil
 }
 
 // EvolveMemoryNetworkTool implements the evolve_memory_network MCP tool
@@ -298,11 +469,13 @@ func (t *EvolveMemoryNetworkTool) InputSchema() map[string]interface{} {
 		"properties": map[string]interface{}{
 			"trigger_type": map[string]interface{}{
 				"type":        "string",
+				"enum":        []string{"manual", "scheduled", "event"},
 				"description": "Type of trigger: 'manual', 'scheduled', or 'event'",
 				"default":     "manual",
 			},
 			"scope": map[string]interface{}{
 				"type":        "string",
+				"enum":        []string{"recent", "all", "project"},
 				"description": "Scope of evolution: 'recent', 'all', or 'project'",
 				"default":     "recent",
 			},
@@ -310,36 +483,35 @@ func (t *EvolveMemoryNetworkTool) InputSchema() map[string]interface{} {
 				"type":        "integer",
 				"description": "Maximum number of memories to analyze (default: 100)",
 				"default":     100,
+				"minimum":     1,
 			},
 			"project_path": map[string]interface{}{
 				"type":        "string",
 				"description": "Project path when scope is 'project'",
 			},
+			"format": formatProperty(),
 		},
 	}
 }
 
-func (t *EvolveMemoryNetworkTool) Execute(ctx context.Context, args map[string]interface{}) (*models.MCPToolResult, error) {
-	// Parse arguments
-	var req models.EvolveNetworkRequest
-
-	req.TriggerType = "manual"
-	if tt, ok := args["trigger_type"].(string); ok {
-		req.TriggerType = tt
-	}
+func (t *EvolveMemoryNetworkTool) OutputSchema() map[string]interface{} {
+	return models.JSONSchemaFor(models.EvolveNetworkResponse{})
+}
 
-	req.Scope = "recent"
-	if s, ok := args["scope"].(string); ok {
-		req.Scope = s
+func (t *EvolveMemoryNetworkTool) Execute(ctx context.Context, args map[string]interface{}) (*models.MCPToolResult, error) {
+	format, err := parseFormat(args)
+	if err != nil {
+		return argumentErrorResult(err), nil
 	}
 
-	req.MaxMemories = 100
-	if mm, ok := args["max_memories"].(float64); ok {
-		req.MaxMemories = int(mm)
+	// Parse arguments
+	req := models.EvolveNetworkRequest{
+		TriggerType: "manual",
+		Scope:       "recent",
+		MaxMemories: 100,
 	}
-
-	if projectPath, ok := args["project_path"].(string); ok {
-		req.ProjectPath = projectPath
+	if err := models.DecodeArguments(args, &req); err != nil {
+		return argumentErrorResult(err), nil
 	}
 
 	t.logger.Info("Evolution triggered",
@@ -360,8 +532,8 @@ func (t *EvolveMemoryNetworkTool) Execute(ctx context.Context, args map[string]i
 		}, nil
 	}
 
-	// Format response
-	resultText := fmt.Sprintf(`Memory network evolution completed!
+	markdown := func() string {
+		return fmt.Sprintf(`Memory network evolution completed!
 
 Results:
 - Memories Analyzed: %d
@@ -372,17 +544,618 @@ Results:
 - Duration: %d ms
 
 The memory network has been analyzed and optimized. New connections have been identified and memory contexts have been improved based on AI analysis.`,
-		response.MemoriesAnalyzed,
-		response.MemoriesEvolved,
-		response.LinksCreated,
-		response.LinksStrengthened,
-		response.ContextsUpdated,
-		response.DurationMs)
-
-	return &models.MCPToolResult{
-		Content: []models.MCPContent{{
-			Type: "text",
-			Text: resultText,
-		}},
-	}, nil
+			response.MemoriesAnalyzed,
+			response.MemoriesEvolved,
+			response.LinksCreated,
+			response.LinksStrengthened,
+			response.ContextsUpdated,
+			response.DurationMs)
+	}
+	compact := func() string {
+		return fmt.Sprintf("analyzed=%d evolved=%d links_created=%d links_strengthened=%d contexts_updated=%d duration_ms=%d",
+			response.MemoriesAnalyzed, response.MemoriesEvolved, response.LinksCreated,
+			response.LinksStrengthened, response.ContextsUpdated, response.DurationMs)
+	}
+
+	return structuredResult(format, response, markdown, compact), nil
+}
+
+// GetMemoryTool implements the get_memory MCP tool
+type GetMemoryTool struct {
+	system *System
+	logger *zap.Logger
+}
+
+// NewGetMemoryTool creates a new get memory tool
+func NewGetMemoryTool(system *System, logger *zap.Logger) *GetMemoryTool {
+	return &GetMemoryTool{
+		system: system,
+		logger: logger,
+	}
+}
+
+func (t *GetMemoryTool) Name() string {
+	return models.ToolGetMemory
+}
+
+func (t *GetMemoryTool) Description() string {
+	return "Fetch a single coding memory by its ID, including its keywords, tags and links"
+}
+
+func (t *GetMemoryTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"memory_id": map[string]interface{}{
+				"type":        "string",
+				"description": "ID of the memory to fetch",
+				"minLength":   1,
+			},
+			"format": formatProperty(),
+		},
+		"required": []string{"memory_id"},
+	}
+}
+
+func (t *GetMemoryTool) OutputSchema() map[string]interface{} {
+	return models.JSONSchemaFor(models.Memory{})
+}
+
+func (t *GetMemoryTool) Execute(ctx context.Context, args map[string]interface{}) (*models.MCPToolResult, error) {
+	format, err := parseFormat(args)
+	if err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	memoryID, ok := args["memory_id"].(string)
+	if !ok || memoryID == "" {
+		return &models.MCPToolResult{
+			IsError: true,
+			Content: []models.MCPContent{{
+				Type: "text",
+				Text: "Error: 'memory_id' parameter is required and must be a string",
+			}},
+		}, nil
+	}
+
+	memory, err := t.system.GetMemory(ctx, memoryID)
+	if err != nil {
+		t.logger.Error("Failed to get memory", zap.Error(err))
+		return &models.MCPToolResult{
+			IsError: true,
+			Content: []models.MCPContent{{
+				Type: "text",
+				Text: fmt.Sprintf("Failed to get memory: %v", err),
+			}},
+		}, nil
+	}
+
+	result := withoutEmbedding(*memory)
+	markdown := func() string {
+		return formatMemory(&result)
+	}
+	compact := func() string {
+		return fmt.Sprintf("%s [%s] %s", result.ID, result.CodeType, result.Context)
+	}
+
+	return structuredResult(format, result, markdown, compact), nil
+}
+
+// UpdateMemoryTool implements the update_memory MCP tool
+type UpdateMemoryTool struct {
+	system *System
+	logger *zap.Logger
+}
+
+// NewUpdateMemoryTool creates a new update memory tool
+func NewUpdateMemoryTool(system *System, logger *zap.Logger) *UpdateMemoryTool {
+	return &UpdateMemoryTool{
+		system: system,
+		logger: logger,
+	}
+}
+
+func (t *UpdateMemoryTool) Name() string {
+	return models.ToolUpdateMemory
+}
+
+func (t *UpdateMemoryTool) Description() string {
+	return "Correct an existing coding memory. Changing the content re-runs AI analysis and re-embeds the memory"
+}
+
+func (t *UpdateMemoryTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"memory_id": map[string]interface{}{
+				"type":        "string",
+				"description": "ID of the memory to update",
+				"minLength":   1,
+			},
+			"content": map[string]interface{}{
+				"type":        "string",
+				"description": "Replacement code content or coding context",
+			},
+			"code_type": map[string]interface{}{
+				"type":        "string",
+				"description": "Replacement programming language or code type",
+			},
+			"context": map[string]interface{}{
+				"type":        "string",
+				"description": "Replacement one-sentence context, overriding the AI-generated one",
+			},
+			"tags": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Replacement tags, overriding the AI-generated ones",
+			},
+			"format": formatProperty(),
+		},
+		"required": []string{"memory_id"},
+	}
+}
+
+func (t *UpdateMemoryTool) OutputSchema() map[string]interface{} {
+	return models.JSONSchemaFor(models.UpdateMemoryResponse{})
+}
+
+func (t *UpdateMemoryTool) Execute(ctx context.Context, args map[string]interface{}) (*models.MCPToolResult, error) {
+	format, err := parseFormat(args)
+	if err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	// Parse arguments
+	var req models.UpdateMemoryRequest
+	if err := models.DecodeArguments(args, &req); err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	if req.MemoryID == "" {
+		return &models.MCPToolResult{
+			IsError: true,
+			Content: []models.MCPContent{{
+				Type: "text",
+				Text: "Error: 'memory_id' parameter is required and must be a string",
+			}},
+		}, nil
+	}
+
+	// Execute memory update
+	response, err := t.system.UpdateMemory(ctx, req)
+	if err != nil {
+		t.logger.Error("Failed to update memory", zap.Error(err))
+		return &models.MCPToolResult{
+			IsError: true,
+			Content: []models.MCPContent{{
+				Type: "text",
+				Text: fmt.Sprintf("Failed to update memory: %v", err),
+			}},
+		}, nil
+	}
+
+	markdown := func() string {
+		return fmt.Sprintf(`Memory updated successfully!
+
+Memory ID: %s
+Context: %s
+Keywords: %v
+Tags: %v
+Re-embedded: %t`,
+			response.MemoryID,
+			response.Context,
+			response.Keywords,
+			response.Tags,
+			response.Reembedded)
+	}
+	compact := func() string {
+		return fmt.Sprintf("updated %s reembedded=%t", response.MemoryID, response.Reembedded)
+	}
+
+	return structuredResult(format, response, markdown, compact), nil
+}
+
+// DeleteMemoryTool implements the delete_memory MCP tool
+type DeleteMemoryTool struct {
+	system *System
+	logger *zap.Logger
+}
+
+// NewDeleteMemoryTool creates a new delete memory tool
+func NewDeleteMemoryTool(system *System, logger *zap.Logger) *DeleteMemoryTool {
+	return &DeleteMemoryTool{
+		system: system,
+		logger: logger,
+	}
+}
+
+func (t *DeleteMemoryTool) Name() string {
+	return models.ToolDeleteMemory
+}
+
+func (t *DeleteMemoryTool) Description() string {
+	return "Permanently delete a coding memory by ID and remove every link pointing to it"
+}
+
+func (t *DeleteMemoryTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"memory_id": map[string]interface{}{
+				"type":        "string",
+				"description": "ID of the memory to delete",
+				"minLength":   1,
+			},
+			"format": formatProperty(),
+		},
+		"required": []string{"memory_id"},
+	}
+}
+
+func (t *DeleteMemoryTool) OutputSchema() map[string]interface{} {
+	return models.JSONSchemaFor(models.DeleteMemoryResponse{})
+}
+
+func (t *DeleteMemoryTool) Execute(ctx context.Context, args map[string]interface{}) (*models.MCPToolResult, error) {
+	format, err := parseFormat(args)
+	if err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	memoryID, ok := args["memory_id"].(string)
+	if !ok || memoryID == "" {
+		return &models.MCPToolResult{
+			IsError: true,
+			Content: []models.MCPContent{{
+				Type: "text",
+				Text: "Error: 'memory_id' parameter is required and must be a string",
+			}},
+		}, nil
+	}
+
+	response, err := t.system.DeleteMemory(ctx, memoryID)
+	if err != nil {
+		t.logger.Error("Failed to delete memory", zap.Error(err))
+		return &models.MCPToolResult{
+			IsError: true,
+			Content: []models.MCPContent{{
+				Type: "text",
+				Text: fmt.Sprintf("Failed to delete memory: %v", err),
+			}},
+		}, nil
+	}
+
+	markdown := func() string {
+		return fmt.Sprintf("Memory %s deleted. Removed %d inbound links.",
+			response.MemoryID, response.LinksRemoved)
+	}
+	compact := func() string {
+		return fmt.Sprintf("deleted %s links_removed=%d", response.MemoryID, response.LinksRemoved)
+	}
+
+	return structuredResult(format, response, markdown, compact), nil
+}
+
+// parseTimeArg parses an optional RFC 3339 time argument, returning the zero time when it is absent
+func parseTimeArg(args map[string]interface{}, name string) (time.Time, error) {
+	value, ok := args[name].(string)
+	if !ok || value == "" {
+		return time.Time{}, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("'%s' must be an RFC 3339 time: %w", name, err)
+	}
+
+	return parsed, nil
+}
+
+// formatMemory renders a single memory for display
+func formatMemory(memory *models.Memory) string {
+	text := fmt.Sprintf("**Memory** %s\nContext: %s\nKeywords: %v\nTags: %v\nWorkspace: %s\nCode Type: %s\nCreated: %s\nUpdated: %s\n",
+		memory.ID, memory.Context, memory.Keywords, memory.Tags, memory.WorkspaceID,
+		memory.CodeType, memory.CreatedAt.Format(time.RFC3339), memory.UpdatedAt.Format(time.RFC3339))
+
+	if len(memory.Links) > 0 {
+		text += "Links:\n"
+		for _, link := range memory.Links {
+			text += fmt.Sprintf("- %s (%s, %.2f): %s\n", link.TargetID, link.LinkType, link.Strength, link.Reason)
+		}
+	}
+
+	text += fmt.Sprintf("\nContent:\n```\n%s\n```\n", memory.Content)
+
+	return text
+}
+
+// ListMemoriesTool implements the list_memories MCP tool
+type ListMemoriesTool struct {
+	system *System
+	logger *zap.Logger
+}
+
+// NewListMemoriesTool creates a new list memories tool
+func NewListMemoriesTool(system *System, logger *zap.Logger) *ListMemoriesTool {
+	return &ListMemoriesTool{
+		system: system,
+		logger: logger,
+	}
+}
+
+func (t *ListMemoriesTool) Name() string {
+	return models.ToolListMemories
+}
+
+func (t *ListMemoriesTool) Description() string {
+	return "List stored coding memories page by page, filtered by workspace, code type, tags and creation time"
+}
+
+func (t *ListMemoriesTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"workspace_id": map[string]interface{}{
+				"type":        "string",
+				"description": "Workspace identifier to list (optional, lists every workspace when omitted)",
+			},
+			"code_types": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Optional array of code types to filter by",
+			},
+			"tags": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Optional array of tags; memories must carry all of them",
+			},
+			"created_after": map[string]interface{}{
+				"type":        "string",
+				"description": "Only list memories created at or after this RFC 3339 time",
+			},
+			"created_before": map[string]interface{}{
+				"type":        "string",
+				"description": "Only list memories created at or before this RFC 3339 time",
+			},
+			"sort_by": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"created_at", "updated_at"},
+				"description": "Field to sort by (default: created_at)",
+				"default":     "created_at",
+			},
+			"sort_order": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"asc", "desc"},
+				"description": "Sort direction (default: desc)",
+				"default":     "desc",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum number of memories per page (default: 20, max: 100)",
+				"default":     20,
+				"minimum":     1,
+				"maximum":     100,
+			},
+			"offset": map[string]interface{}{
+				"type":        "integer",
+				"description": "Number of memories to skip (ignored when cursor is given)",
+				"minimum":     0,
+			},
+			"cursor": map[string]interface{}{
+				"type":        "string",
+				"description": "Cursor from a previous page's next_cursor",
+			},
+			"format": formatProperty(),
+		},
+	}
+}
+
+func (t *ListMemoriesTool) OutputSchema() map[string]interface{} {
+	return models.JSONSchemaFor(models.ListMemoriesResponse{})
+}
+
+func (t *ListMemoriesTool) Execute(ctx context.Context, args map[string]interface{}) (*models.MCPToolResult, error) {
+	format, err := parseFormat(args)
+	if err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	// Parse arguments
+	var req models.ListMemoriesRequest
+
+	// Times are parsed separately for a clearer error than encoding/json's
+	if req.CreatedAfter, err = parseTimeArg(args, "created_after"); err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	if req.CreatedBefore, err = parseTimeArg(args, "created_before"); err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	fields := make(map[string]interface{}, len(args))
+	for name, value := range args {
+		if name != "created_after" && name != "created_before" {
+			fields[name] = value
+		}
+	}
+	if err := models.DecodeArguments(fields, &req); err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	// Execute listing
+	response, err := t.system.ListMemories(ctx, req)
+	if err != nil {
+		t.logger.Error("Failed to list memories", zap.Error(err))
+		return &models.MCPToolResult{
+			IsError: true,
+			Content: []models.MCPContent{{
+				Type: "text",
+				Text: fmt.Sprintf("Failed to list memories: %v", err),
+			}},
+		}, nil
+	}
+
+	for i := range response.Memories {
+		response.Memories[i] = withoutEmbedding(response.Memories[i])
+	}
+
+	markdown := func() string {
+		if len(response.Memories) == 0 {
+			return fmt.Sprintf("No memories found (%d matching in total).", response.TotalCount)
+		}
+
+		resultText := fmt.Sprintf("Showing memories %d-%d of %d:\n\n",
+			response.Offset+1, response.Offset+len(response.Memories), response.TotalCount)
+
+		for _, memory := range response.Memories {
+			resultText += fmt.Sprintf("- %s [%s] %s (created %s, updated %s)\n  Tags: %v\n",
+				memory.ID, memory.CodeType, memory.Context,
+				memory.CreatedAt.Format(time.RFC3339), memory.UpdatedAt.Format(time.RFC3339),
+				memory.Tags)
+		}
+
+		if response.NextCursor != "" {
+			resultText += fmt.Sprintf("\nNext cursor: %s\n", response.NextCursor)
+		}
+
+		return resultText
+	}
+	compact := func() string {
+		lines := []string{fmt.Sprintf("total=%d offset=%d next_cursor=%s", response.TotalCount, response.Offset, response.NextCursor)}
+		for _, memory := range response.Memories {
+			lines = append(lines, fmt.Sprintf("%s [%s] %s", memory.ID, memory.CodeType, memory.Context))
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	return structuredResult(format, response, markdown, compact), nil
+}
+
+// IngestRepositoryTool implements the ingest_repository MCP tool
+type IngestRepositoryTool struct {
+	system *System
+	logger *zap.Logger
+}
+
+// NewIngestRepositoryTool creates a new ingest repository tool
+func NewIngestRepositoryTool(system *System, logger *zap.Logger) *IngestRepositoryTool {
+	return &IngestRepositoryTool{
+		system: system,
+		logger: logger,
+	}
+}
+
+func (t *IngestRepositoryTool) Name() string {
+	return models.ToolIngestRepository
+}
+
+func (t *IngestRepositoryTool) Description() string {
+	return "Seed a workspace from a local repository: store its source files as memories, split at functions and other definitions, skipping files excluded by .gitignore. Re-ingesting only adds chunks that changed"
+}
+
+func (t *IngestRepositoryTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Directory on the server to ingest",
+				"minLength":   1,
+			},
+			"workspace_id": map[string]interface{}{
+				"type":        "string",
+				"description": "Workspace to store the memories in (default: the directory's absolute path)",
+			},
+			"max_chunk_lines": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Longest chunk in lines; longer definitions are split (default: %d)", ingest.DefaultMaxChunkLines),
+				"default":     ingest.DefaultMaxChunkLines,
+				"minimum":     10,
+				"maximum":     1000,
+			},
+			"concurrency": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum number of notes constructed by the LLM at once (default: %d)", defaultBatchConcurrency),
+				"default":     defaultBatchConcurrency,
+				"minimum":     1,
+				"maximum":     32,
+			},
+			"dry_run": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Count the files and chunks that would be stored without storing them (default: false)",
+				"default":     false,
+			},
+			"format": formatProperty(),
+		},
+		"required": []string{"path"},
+	}
+}
+
+func (t *IngestRepositoryTool) OutputSchema() map[string]interface{} {
+	return models.JSONSchemaFor(models.IngestResponse{})
+}
+
+func (t *IngestRepositoryTool) Execute(ctx context.Context, args map[string]interface{}) (*models.MCPToolResult, error) {
+	format, err := parseFormat(args)
+	if err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	var req models.IngestRequest
+	if err := models.DecodeArguments(args, &req); err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	response, err := t.system.IngestRepository(ctx, req)
+	if err != nil {
+		t.logger.Error("Ingestion failed", zap.Error(err))
+		return &models.MCPToolResult{
+			IsError: true,
+			Content: []models.MCPContent{{
+				Type: "text",
+				Text: fmt.Sprintf("Ingestion failed: %v", err),
+			}},
+		}, nil
+	}
+
+	markdown := func() string {
+		return FormatIngestResponse(response)
+	}
+	compact := func() string {
+		return fmt.Sprintf("files=%d chunks=%d created=%d skipped=%d merged=%d failed=%d dry_run=%t",
+			response.Files, response.Chunks, response.Created, response.Skipped, response.Merged, response.Failed, response.DryRun)
+	}
+
+	return structuredResult(format, response, markdown, compact), nil
+}
+
+// FormatIngestResponse renders an ingestion outcome for display
+func FormatIngestResponse(response *models.IngestResponse) string {
+	var sb strings.Builder
+	if response.DryRun {
+		sb.WriteString(fmt.Sprintf("Dry run for workspace %s: nothing was stored\n\n", response.WorkspaceID))
+	} else {
+		sb.WriteString(fmt.Sprintf("Ingested into workspace %s\n\n", response.WorkspaceID))
+	}
+
+	sb.WriteString(fmt.Sprintf("- Files Chunked: %d\n", response.Files))
+	sb.WriteString(fmt.Sprintf("- Files Ignored by .gitignore: %d\n", response.FilesIgnored))
+	sb.WriteString(fmt.Sprintf("- Files Skipped (unknown type, binary or too large): %d\n", response.FilesSkipped))
+	sb.WriteString(fmt.Sprintf("- Chunks: %d\n", response.Chunks))
+	if !response.DryRun {
+		sb.WriteString(fmt.Sprintf("- Memories Created: %d\n", response.Created))
+		sb.WriteString(fmt.Sprintf("- Already Stored: %d\n", response.Skipped))
+		sb.WriteString(fmt.Sprintf("- Merged: %d\n", response.Merged))
+		sb.WriteString(fmt.Sprintf("- Failed: %d\n", response.Failed))
+	}
+	sb.WriteString(fmt.Sprintf("- Duration: %d ms\n", response.DurationMs))
+
+	if len(response.Failures) > 0 {
+		sb.WriteString("\nFailures:\n")
+		for _, failure := range response.Failures {
+			sb.WriteString(fmt.Sprintf("- %s:%d-%d: %s\n", failure.FilePath, failure.StartLine, failure.EndLine, failure.Error))
+		}
+	}
+
+	return sb.String()
 }
@@ -0,0 +1,418 @@
+package memory
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/amem/mcp-server/pkg/models"
+	"github.com/amem/mcp-server/pkg/services"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// transferBatchSize is the number of memories fetched, embedded or written at a
+// time during an export or import
+const transferBatchSize = 100
+
+// ExportMemories writes memories to w as JSONL: an ExportHeader line followed
+// by one memory per line, oldest first. Embeddings are only written when
+// requested, since they are large and tied to the embedding model.
+func (s *System) ExportMemories(ctx context.Context, req models.ExportRequest, w io.Writer) (*models.ExportResponse, error) {
+	startTime := time.Now()
+
+	var where map[string]interface{}
+	workspaceID := ""
+	if req.WorkspaceID != "" {
+		workspaceID = s.workspaceService.NormalizeWorkspaceID(req.WorkspaceID)
+		where = map[string]interface{}{"workspace_id": workspaceID}
+	}
+
+	memories, err := s.store.ListAllMemories(ctx, where)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list memories: %w", err)
+	}
+
+	// Keep exports stable so that two exports of the same data can be diffed
+	sort.Slice(memories, func(i, j int) bool {
+		if !memories[i].CreatedAt.Equal(memories[j].CreatedAt) {
+			return memories[i].CreatedAt.Before(memories[j].CreatedAt)
+		}
+		return memories[i].ID < memories[j].ID
+	})
+
+	header := models.ExportHeader{
+		Format:      models.ExportFormat,
+		Version:     models.ExportFormatVersion,
+		ExportedAt:  time.Now().UTC(),
+		WorkspaceID: workspaceID,
+		Count:       len(memories),
+	}
+	if req.IncludeEmbeddings {
+		info, err := s.embeddingService.Info(ctx)
+		if err != nil {
+			return nil, err
+		}
+		header.EmbeddingModel = info.Model
+		header.EmbeddingDimension = info.Dimension
+	}
+
+	buffered := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buffered)
+	if err := encoder.Encode(header); err != nil {
+		return nil, fmt.Errorf("failed to write export header: %w", err)
+	}
+
+	for start := 0; start < len(memories); start += transferBatchSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		end := start + transferBatchSize
+		if end > len(memories) {
+			end = len(memories)
+		}
+		batch := memories[start:end]
+
+		if req.IncludeEmbeddings {
+			if err := s.loadEmbeddings(ctx, batch); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, memory := range batch {
+			if err := encoder.Encode(memory); err != nil {
+				return nil, fmt.Errorf("failed to write memory %s: %w", memory.ID, err)
+			}
+		}
+	}
+
+	if err := buffered.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to write export: %w", err)
+	}
+
+	response := &models.ExportResponse{
+		WorkspaceID:        workspaceID,
+		Exported:           len(memories),
+		IncludesEmbeddings: req.IncludeEmbeddings,
+		DurationMs:         time.Since(startTime).Milliseconds(),
+	}
+
+	s.logger.Info("Memories exported",
+		zap.String("workspace_id", workspaceID),
+		zap.Int("exported", response.Exported),
+		zap.Bool("embeddings", response.IncludesEmbeddings))
+
+	return response, nil
+}
+
+// loadEmbeddings fills in the embeddings of memories listed without them
+func (s *System) loadEmbeddings(ctx context.Context, memories []*models.Memory) error {
+	ids := make([]string, len(memories))
+	for i, memory := range memories {
+		ids[i] = memory.ID
+	}
+
+	full, err := s.store.GetMemories(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("failed to fetch embeddings: %w", err)
+	}
+
+	embeddings := make(map[string][]float32, len(full))
+	for _, memory := range full {
+		embeddings[memory.ID] = memory.Embedding
+	}
+	for _, memory := range memories {
+		memory.Embedding = embeddings[memory.ID]
+	}
+
+	return nil
+}
+
+// ImportMemories reads an export written by ExportMemories and stores its
+// memories, resolving IDs that are already stored with req.OnConflict.
+// Exported embeddings are reused when they come from the configured model;
+// other memories are re-embedded. Conflicts are resolved and embeddings
+// generated before anything is written, so invalid input or an unreachable
+// embedding service fails the import without writing any memory. A write that
+// fails later keeps the memories written before it; the response counting
+// them is returned along with the error.
+func (s *System) ImportMemories(ctx context.Context, req models.ImportRequest, r io.Reader) (*models.ImportResponse, error) {
+	startTime := time.Now()
+
+	policy := req.OnConflict
+	if policy == "" {
+		policy = models.ImportConflictSkip
+	}
+	switch policy {
+	case models.ImportConflictSkip, models.ImportConflictOverwrite, models.ImportConflictNewID, models.ImportConflictFail:
+	default:
+		return nil, fmt.Errorf("invalid conflict policy %q: must be skip, overwrite, new_id or fail", policy)
+	}
+
+	workspaceID := ""
+	if req.WorkspaceID != "" {
+		workspaceID = s.workspaceService.NormalizeWorkspaceID(req.WorkspaceID)
+		if err := s.workspaceService.ValidateWorkspaceID(workspaceID); err != nil {
+			return nil, fmt.Errorf("invalid workspace ID: %w", err)
+		}
+	}
+
+	header, memories, err := readExport(r)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.ImportResponse{Read: len(memories)}
+
+	// Step 1: Resolve IDs that are already stored
+	stored, err := s.storedIDs(ctx, memories)
+	if err != nil {
+		return nil, err
+	}
+
+	renamed := make(map[string]string)
+	var created, overwritten []*models.Memory
+	for _, memory := range memories {
+		if !stored[memory.ID] {
+			created = append(created, memory)
+			continue
+		}
+
+		switch policy {
+		case models.ImportConflictSkip:
+			response.Skipped++
+		case models.ImportConflictOverwrite:
+			overwritten = append(overwritten, memory)
+		case models.ImportConflictNewID:
+			newID := uuid.New().String()
+			renamed[memory.ID] = newID
+			memory.ID = newID
+			created = append(created, memory)
+		case models.ImportConflictFail:
+			return nil, fmt.Errorf("memory %s already exists", memory.ID)
+		}
+	}
+	response.Renamed = len(renamed)
+
+	// Links between imported memories follow them to their new IDs
+	imported := append(append([]*models.Memory{}, created...), overwritten...)
+	for _, memory := range imported {
+		for i := range memory.Links {
+			if newID, ok := renamed[memory.Links[i].TargetID]; ok {
+				memory.Links[i].TargetID = newID
+			}
+			if newID, ok := renamed[memory.Links[i].SourceID]; ok {
+				memory.Links[i].SourceID = newID
+			}
+		}
+
+		if workspaceID != "" {
+			memory.WorkspaceID = workspaceID
+		}
+		if memory.Metadata == nil {
+			memory.Metadata = make(map[string]interface{})
+		}
+	}
+
+	// Step 2: Embed the memories whose exported embedding cannot be used
+	response.Reembedded, err = s.embedImported(ctx, header, imported, req.Reembed)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 3: Register the workspaces and write the memories
+	for _, memory := range imported {
+		if err := s.workspaceService.RegisterMemoryWorkspace(ctx, memory.WorkspaceID); err != nil {
+			return nil, err
+		}
+	}
+
+	written, err := s.writeImported(ctx, created, overwritten, response)
+	for _, memory := range written {
+		s.notifyChange(memory)
+	}
+	if err != nil {
+		response.DurationMs = time.Since(startTime).Milliseconds()
+		return response, err
+	}
+
+	response.DurationMs = time.Since(startTime).Milliseconds()
+
+	s.logger.Info("Memories imported",
+		zap.Int("read", response.Read),
+		zap.Int("imported", response.Imported),
+		zap.Int("overwritten", response.Overwritten),
+		zap.Int("renamed", response.Renamed),
+		zap.Int("skipped", response.Skipped),
+		zap.Int("reembedded", response.Reembedded))
+
+	return response, nil
+}
+
+// writeImported stores the new memories in batches and then replaces the
+// overwritten ones, counting them in response. It returns the memories
+// written, which are all of them unless a write fails.
+func (s *System) writeImported(ctx context.Context, created, overwritten []*models.Memory, response *models.ImportResponse) ([]*models.Memory, error) {
+	written := make([]*models.Memory, 0, len(created)+len(overwritten))
+
+	for start := 0; start < len(created); start += transferBatchSize {
+		end := start + transferBatchSize
+		if end > len(created) {
+			end = len(created)
+		}
+
+		if err := s.store.StoreMemories(ctx, created[start:end]); err != nil {
+			var partial *services.PartialWriteError
+			if errors.As(err, &partial) {
+				written = append(written, created[start:start+partial.Written]...)
+				response.Imported += partial.Written
+				err = partial.Err
+			}
+			return written, fmt.Errorf("failed to store memories after importing %d: %w", response.Imported, err)
+		}
+		written = append(written, created[start:end]...)
+		response.Imported += end - start
+	}
+
+	// The lock keeps a concurrent change to a stored memory from landing over
+	// its imported replacement
+	for _, memory := range overwritten {
+		unlock := s.locks.Lock(memory.ID)
+		err := s.store.UpsertMemory(ctx, memory)
+		unlock()
+		if err != nil {
+			return written, fmt.Errorf("failed to overwrite memory %s after importing %d: %w", memory.ID, response.Imported, err)
+		}
+		written = append(written, memory)
+		response.Imported++
+		response.Overwritten++
+	}
+
+	return written, nil
+}
+
+// readExport decodes an export's header and memories. The header is optional
+// so that hand-written JSONL files of memories can be imported too.
+func readExport(r io.Reader) (*models.ExportHeader, []*models.Memory, error) {
+	decoder := json.NewDecoder(bufio.NewReader(r))
+	header := &models.ExportHeader{}
+	var memories []*models.Memory
+	seen := make(map[string]bool)
+
+	for record := 1; ; record++ {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, nil, fmt.Errorf("record %d: invalid JSON: %w", record, err)
+		}
+
+		if record == 1 {
+			var probe struct {
+				Format string `json:"format"`
+			}
+			if json.Unmarshal(raw, &probe) == nil && probe.Format != "" {
+				if err := json.Unmarshal(raw, header); err != nil {
+					return nil, nil, fmt.Errorf("invalid export header: %w", err)
+				}
+				if header.Format != models.ExportFormat || header.Version > models.ExportFormatVersion {
+					return nil, nil, fmt.Errorf("unsupported export format %s version %d", header.Format, header.Version)
+				}
+				continue
+			}
+		}
+
+		var memory models.Memory
+		if err := json.Unmarshal(raw, &memory); err != nil {
+			return nil, nil, fmt.Errorf("record %d: invalid memory: %w", record, err)
+		}
+		if memory.ID == "" || memory.Content == "" {
+			return nil, nil, fmt.Errorf("record %d: memory must have an id and content", record)
+		}
+		if seen[memory.ID] {
+			return nil, nil, fmt.Errorf("record %d: memory %s appears more than once", record, memory.ID)
+		}
+		seen[memory.ID] = true
+
+		memories = append(memories, &memory)
+	}
+
+	return header, memories, nil
+}
+
+// storedIDs returns which of the memories' IDs are already stored
+func (s *System) storedIDs(ctx context.Context, memories []*models.Memory) (map[string]bool, error) {
+	stored := make(map[string]bool)
+	for start := 0; start < len(memories); start += transferBatchSize {
+		end := start + transferBatchSize
+		if end > len(memories) {
+			end = len(memories)
+		}
+
+		ids := make([]string, 0, end-start)
+		for _, memory := range memories[start:end] {
+			ids = append(ids, memory.ID)
+		}
+
+		existing, err := s.store.GetMemories(ctx, ids)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check for existing memories: %w", err)
+		}
+		for _, memory := range existing {
+			stored[memory.ID] = true
+		}
+	}
+
+	return stored, nil
+}
+
+// embedImported generates embeddings for imported memories that have none
+// from the configured model, or for all of them when reembed is set, and
+// returns how many were embedded
+func (s *System) embedImported(ctx context.Context, header *models.ExportHeader, memories []*models.Memory, reembed bool) (int, error) {
+	if len(memories) == 0 {
+		return 0, nil
+	}
+
+	info, err := s.embeddingService.Info(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sameModel := header.EmbeddingModel == "" || header.EmbeddingModel == info.Model
+
+	var pending []*models.Memory
+	for _, memory := range memories {
+		if reembed || !sameModel || len(memory.Embedding) != info.Dimension {
+			pending = append(pending, memory)
+		}
+	}
+
+	for start := 0; start < len(pending); start += transferBatchSize {
+		end := start + transferBatchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+
+		texts := make([]string, 0, end-start)
+		for _, memory := range pending[start:end] {
+			texts = append(texts, memory.Content)
+		}
+
+		embeddings, err := s.embeddingService.GenerateBatchEmbeddings(ctx, texts)
+		if err != nil {
+			return 0, fmt.Errorf("failed to generate embeddings: %w", err)
+		}
+		for i, memory := range pending[start:end] {
+			memory.Embedding = embeddings[i]
+		}
+	}
+
+	return len(pending), nil
+}
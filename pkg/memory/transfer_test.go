@@ -0,0 +1,176 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/amem/mcp-server/pkg/models"
+	"github.com/amem/mcp-server/pkg/services"
+	"go.uber.org/zap"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	source, sourceStore := newTestSystem(t)
+
+	for _, memory := range []*models.Memory{
+		{ID: "m1", Content: "retry with backoff", WorkspaceID: "a", Embedding: []float32{1, 0},
+			Links: []models.MemoryLink{{TargetID: "m2", LinkType: "pattern", Strength: 0.8}}},
+		{ID: "m2", Content: "cache the token", WorkspaceID: "a", Embedding: []float32{0, 1},
+			Metadata: map[string]interface{}{"source": "review"}},
+		{ID: "m3", Content: "elsewhere", WorkspaceID: "b", Embedding: []float32{0, 1}},
+	} {
+		if err := sourceStore.StoreMemory(ctx, memory); err != nil {
+			t.Fatalf("Failed to store memory: %v", err)
+		}
+	}
+
+	var export bytes.Buffer
+	exported, err := source.ExportMemories(ctx, models.ExportRequest{WorkspaceID: "a", IncludeEmbeddings: true}, &export)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if exported.Exported != 2 {
+		t.Fatalf("Expected 2 memories exported from workspace a, got %d", exported.Exported)
+	}
+
+	lines := strings.Split(strings.TrimSpace(export.String()), "\n")
+	var header models.ExportHeader
+	if len(lines) != 3 || json.Unmarshal([]byte(lines[0]), &header) != nil || header.Format != models.ExportFormat || header.EmbeddingDimension != 2 {
+		t.Fatalf("Expected a header and 2 memories, got %q", export.String())
+	}
+
+	target, targetStore := newTestSystem(t)
+	imported, err := target.ImportMemories(ctx, models.ImportRequest{}, strings.NewReader(export.String()))
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if imported.Imported != 2 || imported.Reembedded != 0 {
+		t.Errorf("Expected 2 memories imported with their own embeddings, got %+v", imported)
+	}
+
+	m2, err := targetStore.GetMemory(ctx, "m2")
+	if err != nil || m2.Metadata["source"] != "review" || len(m2.Embedding) != 2 {
+		t.Errorf("Expected m2 with its metadata and embedding, got %+v, %v", m2, err)
+	}
+
+	// Importing again under new IDs keeps the link between the copies
+	renamed, err := target.ImportMemories(ctx, models.ImportRequest{OnConflict: models.ImportConflictNewID, Reembed: true},
+		strings.NewReader(export.String()))
+	if err != nil {
+		t.Fatalf("Import with new IDs failed: %v", err)
+	}
+	if renamed.Renamed != 2 || renamed.Reembedded != 2 {
+		t.Errorf("Expected 2 re-embedded memories under new IDs, got %+v", renamed)
+	}
+
+	all, err := targetStore.ListAllMemories(ctx, nil)
+	if err != nil || len(all) != 4 {
+		t.Fatalf("Expected 4 memories after both imports, got %d, %v", len(all), err)
+	}
+	for _, memory := range all {
+		if memory.Content != "retry with backoff" || memory.ID == "m1" {
+			continue
+		}
+		if len(memory.Links) != 1 || memory.Links[0].TargetID == "m2" {
+			t.Errorf("Expected the copy of m1 to link to the copy of m2, got %+v", memory.Links)
+		}
+	}
+
+	if _, err := target.ImportMemories(ctx, models.ImportRequest{OnConflict: models.ImportConflictFail},
+		strings.NewReader(export.String())); err == nil {
+		t.Error("Expected conflicting IDs to fail the import")
+	}
+}
+
+func TestExportImportToolsPassDataInline(t *testing.T) {
+	ctx := context.Background()
+	source, sourceStore := newTestSystem(t)
+	if err := sourceStore.StoreMemory(ctx, &models.Memory{ID: "m1", Content: "retry with backoff", WorkspaceID: "a", Embedding: []float32{1, 0}}); err != nil {
+		t.Fatalf("Failed to store memory: %v", err)
+	}
+
+	result, err := NewExportMemoriesTool(source, zap.NewNop()).Execute(ctx, map[string]interface{}{
+		"include_embeddings": true,
+		"path":               "/tmp/ignored.jsonl",
+	})
+	if err != nil || result.IsError {
+		t.Fatalf("Export failed: %+v, %v", result, err)
+	}
+	exported := result.StructuredContent.(*models.ExportResponse)
+	if exported.Exported != 1 || exported.Path != "" || !strings.Contains(exported.Data, `"id":"m1"`) {
+		t.Fatalf("Expected the export inline, got %+v", exported)
+	}
+	if text := result.Content[0].Text; strings.Contains(text, `"id":"m1"`) {
+		t.Errorf("Expected the text content to summarize the export only, got %q", text)
+	}
+
+	data := &cappedBuilder{limit: 64}
+	if _, err := source.ExportMemories(ctx, models.ExportRequest{IncludeEmbeddings: true}, data); !errors.Is(err, errExportTooLarge) {
+		t.Errorf("Expected an export past the cap to fail, got %v", err)
+	}
+
+	target, targetStore := newTestSystem(t)
+	result, err = NewImportMemoriesTool(target, zap.NewNop()).Execute(ctx, map[string]interface{}{"data": exported.Data})
+	if err != nil || result.IsError {
+		t.Fatalf("Import failed: %+v, %v", result, err)
+	}
+	if _, err := targetStore.GetMemory(ctx, "m1"); err != nil {
+		t.Errorf("Expected m1 to be imported: %v", err)
+	}
+}
+
+// failingWriteStore writes only the first memory of a batch and fails overwrites
+type failingWriteStore struct {
+	services.MemoryStore
+}
+
+func (s *failingWriteStore) StoreMemories(ctx context.Context, memories []*models.Memory) error {
+	if err := s.MemoryStore.StoreMemories(ctx, memories[:1]); err != nil {
+		return err
+	}
+	return &services.PartialWriteError{Written: 1, Err: errors.New("collection unavailable")}
+}
+
+func (s *failingWriteStore) UpsertMemory(ctx context.Context, memory *models.Memory) error {
+	return errors.New("collection unavailable")
+}
+
+func TestImportReportsMemoriesWrittenBeforeAFailure(t *testing.T) {
+	ctx := context.Background()
+	system, store := newTestSystem(t)
+	system.store = &failingWriteStore{MemoryStore: store}
+
+	var changed []string
+	system.AddChangeListener(func(memoryID, workspaceID string) {
+		changed = append(changed, memoryID)
+	})
+
+	export := `{"id":"m1","content":"retry with backoff","workspace_id":"a"}
+{"id":"m2","content":"cache the token","workspace_id":"a"}
+`
+	response, err := system.ImportMemories(ctx, models.ImportRequest{}, strings.NewReader(export))
+	if err == nil || !strings.Contains(err.Error(), "collection unavailable") {
+		t.Fatalf("Expected the write failure, got %v", err)
+	}
+	if response == nil || response.Read != 2 || response.Imported != 1 {
+		t.Fatalf("Expected one of two memories reported imported, got %+v", response)
+	}
+	if len(changed) != 1 || changed[0] != "m1" {
+		t.Errorf("Expected a change notification for the written memory only, got %v", changed)
+	}
+	if _, err := store.GetMemory(ctx, "m1"); err != nil {
+		t.Errorf("Expected m1 to be kept: %v", err)
+	}
+
+	// An overwrite that fails keeps the count of the memories written so far
+	response, err = system.ImportMemories(ctx, models.ImportRequest{OnConflict: models.ImportConflictOverwrite},
+		strings.NewReader(`{"id":"m1","content":"retry with jitter","workspace_id":"a"}`+"\n"))
+	if err == nil || response == nil || response.Imported != 0 || response.Overwritten != 0 {
+		t.Errorf("Expected the failed overwrite to be reported, got %+v, %v", response, err)
+	}
+}
@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/amem/mcp-server/pkg/models"
+	"go.uber.org/zap"
+)
+
+// DeleteWorkspace removes a workspace from the registry. A workspace that
+// still holds memories is refused unless the request also deletes them, in
+// which case links into the workspace from other workspaces are removed too.
+func (s *System) DeleteWorkspace(ctx context.Context, req models.WorkspaceDeleteRequest) (*models.WorkspaceDeleteResponse, error) {
+	if req.Identifier == "" {
+		return nil, fmt.Errorf("identifier is required")
+	}
+	workspaceID := s.workspaceService.NormalizeWorkspaceID(req.Identifier)
+
+	memories, err := s.store.ListAllMemories(ctx, map[string]interface{}{"workspace_id": workspaceID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspace memories: %w", err)
+	}
+
+	if len(memories) > 0 && !req.DeleteMemories {
+		return nil, fmt.Errorf("workspace '%s' holds %d memories; set delete_memories to delete them with it", workspaceID, len(memories))
+	}
+
+	response := &models.WorkspaceDeleteResponse{WorkspaceID: workspaceID}
+
+	// Links to the memories deleted so far are removed even if a later batch fails
+	deleted := make(map[string]bool, len(memories))
+	for start := 0; start < len(memories); start += transferBatchSize {
+		end := start + transferBatchSize
+		if end > len(memories) {
+			end = len(memories)
+		}
+		batch := memories[start:end]
+
+		ids := make([]string, len(batch))
+		for i, memory := range batch {
+			ids[i] = memory.ID
+		}
+		if err := s.store.DeleteMemories(ctx, ids); err != nil {
+			if len(deleted) > 0 {
+				s.removeInboundLinks(ctx, deleted)
+			}
+			return nil, fmt.Errorf("failed after deleting %d of %d memories: %w", start, len(memories), err)
+		}
+
+		for _, memory := range batch {
+			deleted[memory.ID] = true
+			s.notifyChange(memory)
+		}
+		response.MemoriesDeleted += len(batch)
+	}
+
+	if len(memories) > 0 {
+		s.removeInboundLinks(ctx, deleted)
+	}
+
+	response.WasRegistered, err = s.workspaceService.UnregisterWorkspace(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !response.WasRegistered && response.MemoriesDeleted == 0 {
+		return nil, fmt.Errorf("workspace '%s' does not exist", workspaceID)
+	}
+
+	s.logger.Info("Workspace deleted",
+		zap.String("workspace_id", workspaceID),
+		zap.Bool("was_registered", response.WasRegistered),
+		zap.Int("memories_deleted", response.MemoriesDeleted))
+
+	return response, nil
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/amem/mcp-server/pkg/models"
 	"github.com/amem/mcp-server/pkg/services"
@@ -44,21 +46,39 @@ func (t *WorkspaceInitTool) InputSchema() map[string]interface{} {
 				"type":        "string",
 				"description": "Human-readable name for the workspace (optional)",
 			},
+			"owner": map[string]interface{}{
+				"type":        "string",
+				"description": "Owner of the workspace (optional)",
+			},
+			"settings": map[string]interface{}{
+				"type":        "object",
+				"description": "Free-form workspace settings stored in the registry (optional)",
+			},
+			"parents": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Workspaces whose memories retrieval from this workspace inherits, e.g. a shared org-wide workspace (optional)",
+			},
+			"format": formatProperty(),
 		},
 		"required": []string{},
 	}
 }
 
-func (t *WorkspaceInitTool) Execute(ctx context.Context, args map[string]interface{}) (*models.MCPToolResult, error) {
-	// Parse arguments
-	var req models.WorkspaceRequest
+func (t *WorkspaceInitTool) OutputSchema() map[string]interface{} {
+	return models.JSONSchemaFor(models.WorkspaceResponse{})
+}
 
-	if identifier, ok := args["identifier"].(string); ok {
-		req.Identifier = identifier
+func (t *WorkspaceInitTool) Execute(ctx context.Context, args map[string]interface{}) (*models.MCPToolResult, error) {
+	format, err := parseFormat(args)
+	if err != nil {
+		return argumentErrorResult(err), nil
 	}
 
-	if name, ok := args["name"].(string); ok {
-		req.Name = name
+	// Parse arguments
+	var req models.WorkspaceRequest
+	if err := models.DecodeArguments(args, &req); err != nil {
+		return argumentErrorResult(err), nil
 	}
 
 	// Initialize workspace
@@ -84,28 +104,12 @@ func (t *WorkspaceInitTool) Execute(ctx context.Context, args map[string]interfa
 		action = "Created"
 	}
 
-	// Serialize response to JSON
-	responseJSON, err := json.MarshalIndent(response, "", "  ")
-	if err != nil {
-		return &models.MCPToolResult{
-			IsError: true,
-			Content: []models.MCPContent{{
-				Type: "text",
-				Text: fmt.Sprintf("Error serializing response: %v", err),
-			}},
-		}, nil
+	markdown := func() string {
+		return fmt.Sprintf("%s workspace '%s' (%s)\n\nWorkspace Details:\n```json\n%s\n```",
+			action, workspace.Name, workspace.ID, workspaceJSON(response))
 	}
 
-	return &models.MCPToolResult{
-		IsError: false,
-		Content: []models.MCPContent{
-			{
-				Type: "text",
-				Text: fmt.Sprintf("%s workspace '%s' (%s)\n\nWorkspace Details:\n```json\n%s\n```",
-					action, workspace.Name, workspace.ID, string(responseJSON)),
-			},
-		},
-	}, nil
+	return structuredResult(format, response, markdown, func() string { return compactWorkspace(response) }), nil
 }
 
 // WorkspaceCreateTool implements explicit workspace creation
@@ -146,18 +150,42 @@ func (t *WorkspaceCreateTool) InputSchema() map[string]interface{} {
 				"type":        "string",
 				"description": "Description of the workspace (optional)",
 			},
+			"owner": map[string]interface{}{
+				"type":        "string",
+				"description": "Owner of the workspace (optional)",
+			},
+			"settings": map[string]interface{}{
+				"type":        "object",
+				"description": "Free-form workspace settings stored in the registry (optional)",
+			},
+			"parents": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Workspaces whose memories retrieval from this workspace inherits, e.g. a shared org-wide workspace (optional)",
+			},
+			"format": formatProperty(),
 		},
 		"required": []string{"identifier"},
 	}
 }
 
+func (t *WorkspaceCreateTool) OutputSchema() map[string]interface{} {
+	return models.JSONSchemaFor(models.WorkspaceResponse{})
+}
+
 func (t *WorkspaceCreateTool) Execute(ctx context.Context, args map[string]interface{}) (*models.MCPToolResult, error) {
+	format, err := parseFormat(args)
+	if err != nil {
+		return argumentErrorResult(err), nil
+	}
+
 	// Parse arguments
 	var req models.WorkspaceRequest
+	if err := models.DecodeArguments(args, &req); err != nil {
+		return argumentErrorResult(err), nil
+	}
 
-	if identifier, ok := args["identifier"].(string); ok {
-		req.Identifier = identifier
-	} else {
+	if req.Identifier == "" {
 		return &models.MCPToolResult{
 			IsError: true,
 			Content: []models.MCPContent{{
@@ -167,14 +195,6 @@ func (t *WorkspaceCreateTool) Execute(ctx context.Context, args map[string]inter
 		}, nil
 	}
 
-	if name, ok := args["name"].(string); ok {
-		req.Name = name
-	}
-
-	if description, ok := args["description"].(string); ok {
-		req.Description = description
-	}
-
 	// Create workspace
 	workspace, err := t.workspaceService.CreateWorkspace(ctx, &req)
 	if err != nil {
@@ -193,28 +213,12 @@ func (t *WorkspaceCreateTool) Execute(ctx context.Context, args map[string]inter
 		Created:   true,
 	}
 
-	// Serialize response to JSON
-	responseJSON, err := json.MarshalIndent(response, "", "  ")
-	if err != nil {
-		return &models.MCPToolResult{
-			IsError: true,
-			Content: []models.MCPContent{{
-				Type: "text",
-				Text: fmt.Sprintf("Error serializing response: %v", err),
-			}},
-		}, nil
+	markdown := func() string {
+		return fmt.Sprintf("Created workspace '%s' (%s)\n\nWorkspace Details:\n```json\n%s\n```",
+			workspace.Name, workspace.ID, workspaceJSON(response))
 	}
 
-	return &models.MCPToolResult{
-		IsError: false,
-		Content: []models.MCPContent{
-			{
-				Type: "text",
-				Text: fmt.Sprintf("Created workspace '%s' (%s)\n\nWorkspace Details:\n```json\n%s\n```",
-					workspace.Name, workspace.ID, string(responseJSON)),
-			},
-		},
-	}, nil
+	return structuredResult(format, response, markdown, func() string { return compactWorkspace(response) }), nil
 }
 
 // WorkspaceRetrieveTool implements explicit workspace retrieval
@@ -247,18 +251,29 @@ func (t *WorkspaceRetrieveTool) InputSchema() map[string]interface{} {
 				"type":        "string",
 				"description": "Path or name of the workspace to retrieve (required)",
 			},
+			"format": formatProperty(),
 		},
 		"required": []string{"identifier"},
 	}
 }
 
+func (t *WorkspaceRetrieveTool) OutputSchema() map[string]interface{} {
+	return models.JSONSchemaFor(models.WorkspaceResponse{})
+}
+
 func (t *WorkspaceRetrieveTool) Execute(ctx context.Context, args map[string]interface{}) (*models.MCPToolResult, error) {
+	format, err := parseFormat(args)
+	if err != nil {
+		return argumentErrorResult(err), nil
+	}
+
 	// Parse arguments
 	var req models.WorkspaceRequest
+	if err := models.DecodeArguments(args, &req); err != nil {
+		return argumentErrorResult(err), nil
+	}
 
-	if identifier, ok := args["identifier"].(string); ok {
-		req.Identifier = identifier
-	} else {
+	if req.Identifier == "" {
 		return &models.MCPToolResult{
 			IsError: true,
 			Content: []models.MCPContent{{
@@ -311,26 +326,678 @@ func (t *WorkspaceRetrieveTool) Execute(ctx context.Context, args map[string]int
 		Created:   false,
 	}
 
-	// Serialize response to JSON
-	responseJSON, err := json.MarshalIndent(response, "", "  ")
+	markdown := func() string {
+		return fmt.Sprintf("Retrieved workspace '%s' (%s) with %d memories\n\nWorkspace Details:\n```json\n%s\n```",
+			workspace.Name, workspace.ID, workspace.MemoryCount, workspaceJSON(response))
+	}
+
+	return structuredResult(format, response, markdown, func() string { return compactWorkspace(response) }), nil
+}
+
+// WorkspaceListTool lists registered workspaces and workspaces holding memories
+type WorkspaceListTool struct {
+	workspaceService *services.WorkspaceService
+	logger           *zap.Logger
+}
+
+// NewWorkspaceListTool creates a new workspace list tool
+func NewWorkspaceListTool(workspaceService *services.WorkspaceService, logger *zap.Logger) *WorkspaceListTool {
+	return &WorkspaceListTool{
+		workspaceService: workspaceService,
+		logger:           logger,
+	}
+}
+
+func (t *WorkspaceListTool) Name() string {
+	return "workspace_list"
+}
+
+func (t *WorkspaceListTool) Description() string {
+	return "List all workspaces with their registered details and memory counts, including workspaces that only exist through their memories."
+}
+
+func (t *WorkspaceListTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"format": formatProperty(),
+		},
+		"required": []string{},
+	}
+}
+
+func (t *WorkspaceListTool) OutputSchema() map[string]interface{} {
+	return models.JSONSchemaFor(models.WorkspaceListResponse{})
+}
+
+func (t *WorkspaceListTool) Execute(ctx context.Context, args map[string]interface{}) (*models.MCPToolResult, error) {
+	format, err := parseFormat(args)
+	if err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	workspaces, err := t.workspaceService.ListWorkspaces(ctx)
 	if err != nil {
 		return &models.MCPToolResult{
 			IsError: true,
 			Content: []models.MCPContent{{
 				Type: "text",
-				Text: fmt.Sprintf("Error serializing response: %v", err),
+				Text: fmt.Sprintf("Error listing workspaces: %v", err),
 			}},
 		}, nil
 	}
 
-	return &models.MCPToolResult{
-		IsError: false,
-		Content: []models.MCPContent{
-			{
+	response := models.WorkspaceListResponse{
+		Workspaces: make([]models.Workspace, 0, len(workspaces)),
+		TotalCount: len(workspaces),
+	}
+	for _, workspace := range workspaces {
+		response.Workspaces = append(response.Workspaces, *workspace)
+	}
+
+	markdown := func() string {
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("Found %d workspaces\n\n", response.TotalCount))
+		for _, workspace := range response.Workspaces {
+			sb.WriteString(fmt.Sprintf("- **%s** (%s): %d memories", workspace.Name, workspace.ID, workspace.MemoryCount))
+			if workspace.Owner != "" {
+				sb.WriteString(fmt.Sprintf(", owner %s", workspace.Owner))
+			}
+			if !workspace.Registered {
+				sb.WriteString(", not registered")
+			}
+			sb.WriteString("\n")
+		}
+		return sb.String()
+	}
+
+	compact := func() string {
+		lines := make([]string, 0, len(response.Workspaces))
+		for _, workspace := range response.Workspaces {
+			lines = append(lines, fmt.Sprintf("%s name=%q memories=%d registered=%t",
+				workspace.ID, workspace.Name, workspace.MemoryCount, workspace.Registered))
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	return structuredResult(format, response, markdown, compact), nil
+}
+
+// WorkspaceStatsTool reports exact statistics for the memories of a workspace
+type WorkspaceStatsTool struct {
+	workspaceService *services.WorkspaceService
+	logger           *zap.Logger
+}
+
+// NewWorkspaceStatsTool creates a new workspace stats tool
+func NewWorkspaceStatsTool(workspaceService *services.WorkspaceService, logger *zap.Logger) *WorkspaceStatsTool {
+	return &WorkspaceStatsTool{
+		workspaceService: workspaceService,
+		logger:           logger,
+	}
+}
+
+func (t *WorkspaceStatsTool) Name() string {
+	return "workspace_stats"
+}
+
+func (t *WorkspaceStatsTool) Description() string {
+	return "Exact statistics for a workspace: memory count, breakdowns by code type, tag and age, link counts and the most common keywords."
+}
+
+func (t *WorkspaceStatsTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"workspace_id": map[string]interface{}{
+				"type":        "string",
+				"description": "Path or name of the workspace (default: current working directory)",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "Number of tags and keywords to rank (default: 10)",
+				"default":     10,
+				"minimum":     1,
+				"maximum":     100,
+			},
+			"format": formatProperty(),
+		},
+		"required": []string{},
+	}
+}
+
+func (t *WorkspaceStatsTool) OutputSchema() map[string]interface{} {
+	return models.JSONSchemaFor(models.WorkspaceStats{})
+}
+
+func (t *WorkspaceStatsTool) Execute(ctx context.Context, args map[string]interface{}) (*models.MCPToolResult, error) {
+	format, err := parseFormat(args)
+	if err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	var req models.WorkspaceStatsRequest
+	if err := models.DecodeArguments(args, &req); err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	stats, err := t.workspaceService.WorkspaceStats(ctx, &req)
+	if err != nil {
+		return &models.MCPToolResult{
+			IsError: true,
+			Content: []models.MCPContent{{
 				Type: "text",
-				Text: fmt.Sprintf("Retrieved workspace '%s' (%s) with %d memories\n\nWorkspace Details:\n```json\n%s\n```",
-					workspace.Name, workspace.ID, workspace.MemoryCount, string(responseJSON)),
+				Text: fmt.Sprintf("Error computing workspace stats: %v", err),
+			}},
+		}, nil
+	}
+
+	markdown := func() string {
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("Workspace '%s' holds %d of %d memories\n\n", stats.WorkspaceID, stats.MemoryCount, stats.TotalMemories))
+
+		sb.WriteString("**Code types**: ")
+		sb.WriteString(formatCounts(stats.CodeTypes))
+		sb.WriteString(fmt.Sprintf("\n**Tags** (%d distinct, %d untagged memories): %s\n", stats.DistinctTags, stats.Untagged, formatTerms(stats.Tags)))
+		sb.WriteString(fmt.Sprintf("**Top keywords**: %s\n", formatTerms(stats.TopKeywords)))
+
+		ages := make([]string, 0, len(stats.Ages))
+		for _, bucket := range stats.Ages {
+			ages = append(ages, fmt.Sprintf("%s %d", bucket.Bucket, bucket.Count))
+		}
+		sb.WriteString(fmt.Sprintf("**Age**: %s\n", strings.Join(ages, ", ")))
+
+		sb.WriteString(fmt.Sprintf("**Links**: %d from %d memories, %d to other workspaces (%s)\n",
+			stats.Links.Total, stats.Links.LinkedMemories, stats.Links.External, formatCounts(stats.Links.ByType)))
+		return sb.String()
+	}
+
+	compact := func() string {
+		return fmt.Sprintf("%s memories=%d total=%d code_types=%d tags=%d untagged=%d links=%d external_links=%d",
+			stats.WorkspaceID, stats.MemoryCount, stats.TotalMemories, len(stats.CodeTypes),
+			stats.DistinctTags, stats.Untagged, stats.Links.Total, stats.Links.External)
+	}
+
+	return structuredResult(format, stats, markdown, compact), nil
+}
+
+// formatCounts lists counts by key, largest first
+func formatCounts(counts map[string]int) string {
+	terms := make([]models.TermCount, 0, len(counts))
+	for key, count := range counts {
+		terms = append(terms, models.TermCount{Term: key, Count: count})
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].Count != terms[j].Count {
+			return terms[i].Count > terms[j].Count
+		}
+		return terms[i].Term < terms[j].Term
+	})
+	return formatTerms(terms)
+}
+
+// formatTerms lists ranked terms as "term (count)"
+func formatTerms(terms []models.TermCount) string {
+	if len(terms) == 0 {
+		return "none"
+	}
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = fmt.Sprintf("%s (%d)", term.Term, term.Count)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// WorkspaceUpdateTool changes the registered details of a workspace
+type WorkspaceUpdateTool struct {
+	workspaceService *services.WorkspaceService
+	logger           *zap.Logger
+}
+
+// NewWorkspaceUpdateTool creates a new workspace update tool
+func NewWorkspaceUpdateTool(workspaceService *services.WorkspaceService, logger *zap.Logger) *WorkspaceUpdateTool {
+	return &WorkspaceUpdateTool{
+		workspaceService: workspaceService,
+		logger:           logger,
+	}
+}
+
+func (t *WorkspaceUpdateTool) Name() string {
+	return "workspace_update"
+}
+
+func (t *WorkspaceUpdateTool) Description() string {
+	return "Update the name, description, owner, settings or parents of a workspace. Omitted fields are left unchanged; settings are merged and a setting set to null is removed."
+}
+
+func (t *WorkspaceUpdateTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"identifier": map[string]interface{}{
+				"type":        "string",
+				"description": "Path or name of the workspace to update (required)",
+			},
+			"name": map[string]interface{}{
+				"type":        "string",
+				"description": "New human-readable name",
 			},
+			"description": map[string]interface{}{
+				"type":        "string",
+				"description": "New description",
+			},
+			"owner": map[string]interface{}{
+				"type":        "string",
+				"description": "New owner",
+			},
+			"settings": map[string]interface{}{
+				"type":        "object",
+				"description": "Settings to merge into the existing ones; null removes a setting",
+			},
+			"parents": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Replaces the workspaces this workspace inherits from; an empty list removes them all",
+			},
+			"format": formatProperty(),
 		},
-	}, nil
+		"required": []string{"identifier"},
+	}
+}
+
+func (t *WorkspaceUpdateTool) OutputSchema() map[string]interface{} {
+	return models.JSONSchemaFor(models.WorkspaceResponse{})
+}
+
+func (t *WorkspaceUpdateTool) Execute(ctx context.Context, args map[string]interface{}) (*models.MCPToolResult, error) {
+	format, err := parseFormat(args)
+	if err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	var req models.WorkspaceUpdateRequest
+	if err := models.DecodeArguments(args, &req); err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	workspace, err := t.workspaceService.UpdateWorkspace(ctx, &req)
+	if err != nil {
+		return &models.MCPToolResult{
+			IsError: true,
+			Content: []models.MCPContent{{
+				Type: "text",
+				Text: fmt.Sprintf("Error updating workspace: %v", err),
+			}},
+		}, nil
+	}
+
+	response := models.WorkspaceResponse{
+		Workspace: *workspace,
+		Created:   false,
+	}
+
+	markdown := func() string {
+		return fmt.Sprintf("Updated workspace '%s' (%s)\n\nWorkspace Details:\n```json\n%s\n```",
+			workspace.Name, workspace.ID, workspaceJSON(response))
+	}
+
+	return structuredResult(format, response, markdown, func() string { return compactWorkspace(response) }), nil
+}
+
+// WorkspaceDeleteTool removes a workspace and optionally its memories
+type WorkspaceDeleteTool struct {
+	system *System
+	logger *zap.Logger
+}
+
+// NewWorkspaceDeleteTool creates a new workspace delete tool
+func NewWorkspaceDeleteTool(system *System, logger *zap.Logger) *WorkspaceDeleteTool {
+	return &WorkspaceDeleteTool{
+		system: system,
+		logger: logger,
+	}
+}
+
+func (t *WorkspaceDeleteTool) Name() string {
+	return "workspace_delete"
+}
+
+func (t *WorkspaceDeleteTool) Description() string {
+	return "Delete a workspace from the registry. Fails if the workspace still holds memories unless delete_memories is set, which permanently deletes them too."
+}
+
+func (t *WorkspaceDeleteTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"identifier": map[string]interface{}{
+				"type":        "string",
+				"description": "Path or name of the workspace to delete (required)",
+			},
+			"delete_memories": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Also delete every memory in the workspace",
+				"default":     false,
+			},
+			"format": formatProperty(),
+		},
+		"required": []string{"identifier"},
+	}
+}
+
+func (t *WorkspaceDeleteTool) OutputSchema() map[string]interface{} {
+	return models.JSONSchemaFor(models.WorkspaceDeleteResponse{})
+}
+
+func (t *WorkspaceDeleteTool) Execute(ctx context.Context, args map[string]interface{}) (*models.MCPToolResult, error) {
+	format, err := parseFormat(args)
+	if err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	var req models.WorkspaceDeleteRequest
+	if err := models.DecodeArguments(args, &req); err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	response, err := t.system.DeleteWorkspace(ctx, req)
+	if err != nil {
+		return &models.MCPToolResult{
+			IsError: true,
+			Content: []models.MCPContent{{
+				Type: "text",
+				Text: fmt.Sprintf("Error deleting workspace: %v", err),
+			}},
+		}, nil
+	}
+
+	markdown := func() string {
+		return fmt.Sprintf("Deleted workspace %s and %d memories", response.WorkspaceID, response.MemoriesDeleted)
+	}
+
+	compact := func() string {
+		return fmt.Sprintf("%s deleted memories=%d registered=%t",
+			response.WorkspaceID, response.MemoriesDeleted, response.WasRegistered)
+	}
+
+	return structuredResult(format, response, markdown, compact), nil
+}
+
+// WorkspaceRenameTool gives a workspace a new identifier
+type WorkspaceRenameTool struct {
+	workspaceService *services.WorkspaceService
+	logger           *zap.Logger
+}
+
+// NewWorkspaceRenameTool creates a new workspace rename tool
+func NewWorkspaceRenameTool(workspaceService *services.WorkspaceService, logger *zap.Logger) *WorkspaceRenameTool {
+	return &WorkspaceRenameTool{
+		workspaceService: workspaceService,
+		logger:           logger,
+	}
+}
+
+func (t *WorkspaceRenameTool) Name() string {
+	return "workspace_rename"
+}
+
+func (t *WorkspaceRenameTool) Description() string {
+	return "Rename a workspace, e.g. after a repository moves. Every memory and the registry record move to the new identifier, which must not be in use."
+}
+
+func (t *WorkspaceRenameTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"identifier": map[string]interface{}{
+				"type":        "string",
+				"description": "Current path or name of the workspace (required)",
+			},
+			"new_identifier": map[string]interface{}{
+				"type":        "string",
+				"description": "New path or name for the workspace (required)",
+			},
+			"format": formatProperty(),
+		},
+		"required": []string{"identifier", "new_identifier"},
+	}
+}
+
+func (t *WorkspaceRenameTool) OutputSchema() map[string]interface{} {
+	return models.JSONSchemaFor(models.WorkspaceMoveResponse{})
+}
+
+func (t *WorkspaceRenameTool) Execute(ctx context.Context, args map[string]interface{}) (*models.MCPToolResult, error) {
+	format, err := parseFormat(args)
+	if err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	var req models.WorkspaceRenameRequest
+	if err := models.DecodeArguments(args, &req); err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	response, err := t.workspaceService.RenameWorkspace(ctx, &req)
+	if err != nil {
+		return &models.MCPToolResult{
+			IsError: true,
+			Content: []models.MCPContent{{
+				Type: "text",
+				Text: fmt.Sprintf("Error renaming workspace: %v", err),
+			}},
+		}, nil
+	}
+
+	return moveResult(format, "Renamed workspace", response), nil
+}
+
+// WorkspaceMergeTool folds one workspace into another
+type WorkspaceMergeTool struct {
+	workspaceService *services.WorkspaceService
+	logger           *zap.Logger
+}
+
+// NewWorkspaceMergeTool creates a new workspace merge tool
+func NewWorkspaceMergeTool(workspaceService *services.WorkspaceService, logger *zap.Logger) *WorkspaceMergeTool {
+	return &WorkspaceMergeTool{
+		workspaceService: workspaceService,
+		logger:           logger,
+	}
+}
+
+func (t *WorkspaceMergeTool) Name() string {
+	return "workspace_merge"
+}
+
+func (t *WorkspaceMergeTool) Description() string {
+	return "Merge one workspace into another. Source memories that duplicate a target memory are folded into it instead of being moved, and the source workspace is unregistered."
+}
+
+func (t *WorkspaceMergeTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"source": map[string]interface{}{
+				"type":        "string",
+				"description": "Workspace whose memories are moved (required)",
+			},
+			"target": map[string]interface{}{
+				"type":        "string",
+				"description": "Workspace receiving the memories (required)",
+			},
+			"similarity_threshold": map[string]interface{}{
+				"type":        "number",
+				"description": "Minimum similarity (0.0-1.0) for a source memory to duplicate a target memory; defaults to the configured dedupe threshold",
+				"minimum":     0,
+				"maximum":     1,
+			},
+			"format": formatProperty(),
+		},
+		"required": []string{"source", "target"},
+	}
+}
+
+func (t *WorkspaceMergeTool) OutputSchema() map[string]interface{} {
+	return models.JSONSchemaFor(models.WorkspaceMoveResponse{})
+}
+
+func (t *WorkspaceMergeTool) Execute(ctx context.Context, args map[string]interface{}) (*models.MCPToolResult, error) {
+	format, err := parseFormat(args)
+	if err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	var req models.WorkspaceMergeRequest
+	if err := models.DecodeArguments(args, &req); err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	response, err := t.workspaceService.MergeWorkspaces(ctx, &req)
+	if err != nil {
+		return &models.MCPToolResult{
+			IsError: true,
+			Content: []models.MCPContent{{
+				Type: "text",
+				Text: fmt.Sprintf("Error merging workspaces: %v", err),
+			}},
+		}, nil
+	}
+
+	return moveResult(format, "Merged workspace", response), nil
+}
+
+// MoveMemoriesTool moves selected memories to another workspace
+type MoveMemoriesTool struct {
+	workspaceService *services.WorkspaceService
+	logger           *zap.Logger
+}
+
+// NewMoveMemoriesTool creates a new move memories tool
+func NewMoveMemoriesTool(workspaceService *services.WorkspaceService, logger *zap.Logger) *MoveMemoriesTool {
+	return &MoveMemoriesTool{
+		workspaceService: workspaceService,
+		logger:           logger,
+	}
+}
+
+func (t *MoveMemoriesTool) Name() string {
+	return "move_memories"
+}
+
+func (t *MoveMemoriesTool) Description() string {
+	return "Move memories to another workspace, selected either by ID or by source workspace with optional code type and tag filters, e.g. when splitting a monorepo."
+}
+
+func (t *MoveMemoriesTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"memory_ids": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "IDs of the memories to move",
+			},
+			"workspace_id": map[string]interface{}{
+				"type":        "string",
+				"description": "Move the memories of this workspace that match code_types and tags instead of listing IDs",
+			},
+			"code_types": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Only move memories of these code types (with workspace_id)",
+			},
+			"tags": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Only move memories carrying every one of these tags (with workspace_id)",
+			},
+			"target_workspace_id": map[string]interface{}{
+				"type":        "string",
+				"description": "Workspace to move the memories into (required)",
+			},
+			"format": formatProperty(),
+		},
+		"required": []string{"target_workspace_id"},
+	}
+}
+
+func (t *MoveMemoriesTool) OutputSchema() map[string]interface{} {
+	return models.JSONSchemaFor(models.WorkspaceMoveResponse{})
+}
+
+func (t *MoveMemoriesTool) Execute(ctx context.Context, args map[string]interface{}) (*models.MCPToolResult, error) {
+	format, err := parseFormat(args)
+	if err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	var req models.MoveMemoriesRequest
+	if err := models.DecodeArguments(args, &req); err != nil {
+		return argumentErrorResult(err), nil
+	}
+
+	response, err := t.workspaceService.MoveMemories(ctx, &req)
+	if err != nil {
+		return &models.MCPToolResult{
+			IsError: true,
+			Content: []models.MCPContent{{
+				Type: "text",
+				Text: fmt.Sprintf("Error moving memories: %v", err),
+			}},
+		}, nil
+	}
+
+	return moveResult(format, "Moved memories", response), nil
+}
+
+// moveResult renders the outcome of a rename, merge or move
+func moveResult(format, action string, response *models.WorkspaceMoveResponse) *models.MCPToolResult {
+	markdown := func() string {
+		var sb strings.Builder
+		if response.SourceWorkspaceID != "" {
+			sb.WriteString(fmt.Sprintf("%s %s into %s\n\n", action, response.SourceWorkspaceID, response.TargetWorkspaceID))
+		} else {
+			sb.WriteString(fmt.Sprintf("%s into %s\n\n", action, response.TargetWorkspaceID))
+		}
+		sb.WriteString(fmt.Sprintf("- Matched: %d\n", response.Matched))
+		sb.WriteString(fmt.Sprintf("- Moved: %d\n", response.Moved))
+		if response.Deduplicated > 0 {
+			sb.WriteString(fmt.Sprintf("- Folded into duplicates: %d\n", response.Deduplicated))
+		}
+		if response.Unchanged > 0 {
+			sb.WriteString(fmt.Sprintf("- Already in target: %d\n", response.Unchanged))
+		}
+		if response.LinksRewritten > 0 {
+			sb.WriteString(fmt.Sprintf("- Links repointed: %d\n", response.LinksRewritten))
+		}
+		if len(response.NotFound) > 0 {
+			sb.WriteString(fmt.Sprintf("- Not found: %s\n", strings.Join(response.NotFound, ", ")))
+		}
+		sb.WriteString(fmt.Sprintf("- Duration: %dms\n", response.DurationMs))
+		return sb.String()
+	}
+
+	compact := func() string {
+		return fmt.Sprintf("target=%s matched=%d moved=%d deduplicated=%d unchanged=%d not_found=%d",
+			response.TargetWorkspaceID, response.Matched, response.Moved, response.Deduplicated,
+			response.Unchanged, len(response.NotFound))
+	}
+
+	return structuredResult(format, response, markdown, compact)
+}
+
+// workspaceJSON renders a workspace response as indented JSON
+func workspaceJSON(response models.WorkspaceResponse) string {
+	responseJSON, err := json.MarshalIndent(response, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("Error serializing response: %v", err)
+	}
+	return string(responseJSON)
+}
+
+// compactWorkspace renders a workspace response on one line
+func compactWorkspace(response models.WorkspaceResponse) string {
+	return fmt.Sprintf("%s name=%q memories=%d created=%t",
+		response.Workspace.ID, response.Workspace.Name, response.Workspace.MemoryCount, response.Created)
 }
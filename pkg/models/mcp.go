@@ -1,5 +1,7 @@
 package models
 
+import "errors"
+
 // MCPRequest represents a JSON-RPC 2.0 request
 type MCPRequest struct {
 	JSONRPC string      `json:"jsonrpc"`
@@ -46,9 +48,10 @@ type MCPError struct {
 
 // MCPTool represents an MCP tool definition
 type MCPTool struct {
-	Name        string                 `json:"name"`
-	Description string                 `json:"description"`
-	InputSchema map[string]interface{} `json:"inputSchema"`
+	Name         string                 `json:"name"`
+	Description  string                 `json:"description"`
+	InputSchema  map[string]interface{} `json:"inputSchema"`
+	OutputSchema map[string]interface{} `json:"outputSchema,omitempty"` // Schema of StructuredContent
 }
 
 // MCPToolCall represents a tool call request
@@ -59,10 +62,69 @@ type MCPToolCall struct {
 
 // MCPToolResult represents a tool call result
 type MCPToolResult struct {
-	Content []MCPContent `json:"content"`
-	IsError bool         `json:"isError,omitempty"`
+	Content           []MCPContent `json:"content"`
+	StructuredContent interface{}  `json:"structuredContent,omitempty"` // Matches the tool's OutputSchema
+	IsError           bool         `json:"isError,omitempty"`
+}
+
+// MCPResource describes a resource returned by resources/list
+type MCPResource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// MCPResourceTemplate describes a family of resources by URI template
+type MCPResourceTemplate struct {
+	URITemplate string `json:"uriTemplate"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// MCPResourceContents is the content of a resource returned by resources/read
+type MCPResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text"`
+}
+
+// ErrResourceNotFound is returned by resource providers for unknown URIs
+var ErrResourceNotFound = errors.New("resource not found")
+
+// MCPPrompt describes a prompt returned by prompts/list
+type MCPPrompt struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	Arguments   []MCPPromptArgument `json:"arguments,omitempty"`
+}
+
+// MCPPromptArgument describes an argument of a prompt
+type MCPPromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required"`
 }
 
+// MCPPromptMessage is one message of a rendered prompt
+type MCPPromptMessage struct {
+	Role    string     `json:"role"`
+	Content MCPContent `json:"content"`
+}
+
+// MCPGetPromptResult is the result of prompts/get
+type MCPGetPromptResult struct {
+	Description string             `json:"description,omitempty"`
+	Messages    []MCPPromptMessage `json:"messages"`
+}
+
+// Errors returned by prompt providers for invalid prompts/get requests
+var (
+	ErrPromptNotFound         = errors.New("prompt not found")
+	ErrMissingPromptArguments = errors.New("missing required prompt arguments")
+)
+
 // MCPContent represents content in MCP responses
 type MCPContent struct {
 	Type string `json:"type"`
@@ -76,19 +138,50 @@ const (
 	MethodNotFound = -32601
 	InvalidParams  = -32602
 	InternalError  = -32603
+
+	// ResourceNotFound is the MCP-specific code for unknown resource URIs
+	ResourceNotFound = -32002
 )
 
 // MCP method names
 const (
 	MethodInitialize   = "initialize"
+	MethodPing         = "ping"
 	MethodListTools    = "tools/list"
 	MethodCallTool     = "tools/call"
 	MethodNotification = "notifications/initialized"
+	MethodCancelled    = "notifications/cancelled"
+
+	MethodListResources         = "resources/list"
+	MethodReadResource          = "resources/read"
+	MethodListResourceTemplates = "resources/templates/list"
+	MethodSubscribeResource     = "resources/subscribe"
+	MethodUnsubscribeResource   = "resources/unsubscribe"
+	MethodResourceUpdated       = "notifications/resources/updated"
+
+	MethodListPrompts = "prompts/list"
+	MethodGetPrompt   = "prompts/get"
 )
 
 // Tool names
 const (
 	ToolStoreCodingMemory        = "store_coding_memory"
+	ToolStoreCodingMemoriesBatch = "store_coding_memories_batch"
 	ToolRetrieveRelevantMemories = "retrieve_relevant_memories"
 	ToolEvolveMemoryNetwork      = "evolve_memory_network"
+	ToolGetMemory                = "get_memory"
+	ToolUpdateMemory             = "update_memory"
+	ToolDeleteMemory             = "delete_memory"
+	ToolListMemories             = "list_memories"
+	ToolReindexMemories          = "reindex_memories"
+	ToolExportMemories           = "export_memories"
+	ToolImportMemories           = "import_memories"
+	ToolIngestRepository         = "ingest_repository"
+)
+
+// Output formats for the text content of tool results
+const (
+	OutputFormatMarkdown = "markdown"
+	OutputFormatJSON     = "json"
+	OutputFormatCompact  = "compact"
 )
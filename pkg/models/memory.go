@@ -23,6 +23,7 @@ type Memory struct {
 
 // MemoryLink represents a connection between memories
 type MemoryLink struct {
+	SourceID string  `json:"source_id,omitempty"` // Only set on links suggested by evolution analysis
 	TargetID string  `json:"target_id"`
 	LinkType string  `json:"link_type"` // solution|pattern|technology|debugging|progression
 	Strength float32 `json:"strength"`  // 0.0-1.0
@@ -31,13 +32,29 @@ type MemoryLink struct {
 
 // StoreMemoryRequest represents the request to store a new memory
 type StoreMemoryRequest struct {
-	Content     string `json:"content" validate:"required"`
-	ProjectPath string `json:"project_path"` // Deprecated: use WorkspaceID
-	WorkspaceID string `json:"workspace_id"`
-	CodeType    string `json:"code_type"`
-	Context     string `json:"context"`
+	Content             string                 `json:"content" validate:"required"`
+	ProjectPath         string                 `json:"project_path"` // Deprecated: use WorkspaceID
+	WorkspaceID         string                 `json:"workspace_id"`
+	CodeType            string                 `json:"code_type"`
+	Context             string                 `json:"context"`
+	Dedupe              string                 `json:"dedupe"`               // skip|merge|force, empty uses the configured mode
+	SimilarityThreshold float32                `json:"similarity_threshold"` // Zero uses the configured threshold
+	Metadata            map[string]interface{} `json:"metadata,omitempty"`   // Stored with the memory; reserved keys are ignored
 }
 
+// Metadata keys recording the git checkout a memory was stored from
+const (
+	MetadataGitBranch = "git_branch"
+	MetadataGitCommit = "git_commit"
+)
+
+// Dedupe modes for memories similar to an existing one in the same workspace
+const (
+	DedupeSkip  = "skip"  // Keep the existing memory and return its ID
+	DedupeMerge = "merge" // Merge the new content into the existing memory
+	DedupeForce = "force" // Always store a new memory
+)
+
 // StoreMemoryResponse represents the response after storing a memory
 type StoreMemoryResponse struct {
 	MemoryID     string   `json:"memory_id"`
@@ -45,6 +62,40 @@ type StoreMemoryResponse struct {
 	Tags         []string `json:"tags"`
 	LinksCreated int      `json:"links_created"`
 	EventEmitted bool     `json:"event_emitted"`
+	Action       string   `json:"action"`                 // created|skipped|merged
+	DuplicateOf  string   `json:"duplicate_of,omitempty"` // Existing memory the content duplicated
+	Similarity   float32  `json:"similarity,omitempty"`   // Similarity to the duplicate
+}
+
+// Outcomes of storing a memory
+const (
+	StoreActionCreated = "created"
+	StoreActionSkipped = "skipped"
+	StoreActionMerged  = "merged"
+)
+
+// StoreMemoriesBatchRequest represents the request to store several memories at once
+type StoreMemoriesBatchRequest struct {
+	Memories    []StoreMemoryRequest `json:"memories" validate:"required"`
+	Concurrency int                  `json:"concurrency"` // Notes constructed at once, zero uses the default
+}
+
+// BatchStoreResult is the outcome of storing one memory of a batch
+type BatchStoreResult struct {
+	Index    int                  `json:"index"` // Position of the memory in the request
+	Success  bool                 `json:"success"`
+	Error    string               `json:"error,omitempty"`
+	Response *StoreMemoryResponse `json:"response,omitempty"`
+}
+
+// StoreMemoriesBatchResponse represents the response after storing a batch of memories
+type StoreMemoriesBatchResponse struct {
+	Results    []BatchStoreResult `json:"results"`
+	Created    int                `json:"created"`
+	Skipped    int                `json:"skipped"`
+	Merged     int                `json:"merged"`
+	Failed     int                `json:"failed"`
+	DurationMs int64              `json:"duration_ms"`
 }
 
 // RetrieveMemoryRequest represents the request to retrieve memories
@@ -54,9 +105,24 @@ type RetrieveMemoryRequest struct {
 	ProjectFilter string   `json:"project_filter"` // Deprecated: use WorkspaceID
 	WorkspaceID   string   `json:"workspace_id"`
 	CodeTypes     []string `json:"code_types"`
-	MinRelevance  float32  `json:"min_relevance"`
+	MinRelevance  float32  `json:"min_relevance"` // Applies to the relevance score of results without a keyword match
+	SearchMode    string   `json:"search_mode"`   // vector|keyword|hybrid, empty uses vector
+
+	// Cross-workspace scope. Parent workspaces of every searched workspace are
+	// included unless SkipInherited is set; AllWorkspaces searches everything.
+	WorkspaceIDs     []string           `json:"workspace_ids"`
+	AllWorkspaces    bool               `json:"all_workspaces"`
+	SkipInherited    bool               `json:"skip_inherited"`
+	WorkspaceWeights map[string]float32 `json:"workspace_weights"` // Score multiplier per workspace, overriding the defaults
 }
 
+// Search modes for memory retrieval
+const (
+	SearchModeVector  = "vector"
+	SearchModeKeyword = "keyword"
+	SearchModeHybrid  = "hybrid"
+)
+
 // RetrieveMemoryResponse represents the response with retrieved memories
 type RetrieveMemoryResponse struct {
 	Memories   []RetrievedMemory `json:"memories"`
@@ -66,10 +132,58 @@ type RetrieveMemoryResponse struct {
 // RetrievedMemory extends Memory with relevance information
 type RetrievedMemory struct {
 	Memory
-	RelevanceScore float32 `json:"relevance_score"`
+	RelevanceScore float32 `json:"relevance_score"`      // Calibrated similarity to the query, times the workspace weight
+	RankScore      float32 `json:"rank_score,omitempty"` // Normalized keyword or fused rank, outside vector mode
 	MatchReason    string  `json:"match_reason"`
 }
 
+// ListMemoriesRequest represents the request to page through stored memories
+type ListMemoriesRequest struct {
+	WorkspaceID   string    `json:"workspace_id"` // Empty lists every workspace
+	CodeTypes     []string  `json:"code_types"`
+	Tags          []string  `json:"tags"` // Memories must carry every tag
+	CreatedAfter  time.Time `json:"created_after"`
+	CreatedBefore time.Time `json:"created_before"`
+	SortBy        string    `json:"sort_by"`    // created_at|updated_at
+	SortOrder     string    `json:"sort_order"` // asc|desc
+	Limit         int       `json:"limit"`
+	Offset        int       `json:"offset"`
+	Cursor        string    `json:"cursor"` // Takes precedence over Offset
+}
+
+// ListMemoriesResponse represents one page of stored memories
+type ListMemoriesResponse struct {
+	Memories   []Memory `json:"memories"`
+	TotalCount int      `json:"total_count"`
+	Offset     int      `json:"offset"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+// UpdateMemoryRequest represents the request to correct an existing memory.
+// Empty fields are left unchanged.
+type UpdateMemoryRequest struct {
+	MemoryID string   `json:"memory_id" validate:"required"`
+	Content  string   `json:"content"`
+	CodeType string   `json:"code_type"`
+	Context  string   `json:"context"`
+	Tags     []string `json:"tags"`
+}
+
+// UpdateMemoryResponse represents the response after updating a memory
+type UpdateMemoryResponse struct {
+	MemoryID   string   `json:"memory_id"`
+	Context    string   `json:"context"`
+	Keywords   []string `json:"keywords"`
+	Tags       []string `json:"tags"`
+	Reembedded bool     `json:"reembedded"`
+}
+
+// DeleteMemoryResponse represents the response after deleting a memory
+type DeleteMemoryResponse struct {
+	MemoryID     string `json:"memory_id"`
+	LinksRemoved int    `json:"links_removed"`
+}
+
 // EvolveNetworkRequest represents the request to evolve memory network
 type EvolveNetworkRequest struct {
 	TriggerType string `json:"trigger_type"` // manual|scheduled|event
@@ -88,6 +202,123 @@ type EvolveNetworkResponse struct {
 	DurationMs        int `json:"duration_ms"`
 }
 
+// ReindexRequest represents a request to re-embed every memory with the
+// configured embedding model
+type ReindexRequest struct {
+	BatchSize int  `json:"batch_size"` // Memories re-embedded per embedding request
+	Switch    bool `json:"switch"`     // Switch to the rebuilt collection when done
+}
+
+// ReindexResponse reports the progress or outcome of a reindex
+type ReindexResponse struct {
+	Staging            string `json:"staging"`
+	Backup             string `json:"backup,omitempty"`
+	EmbeddingModel     string `json:"embedding_model"`
+	EmbeddingDimension int    `json:"embedding_dimension"`
+	Scanned            int    `json:"scanned"`
+	Reembedded         int    `json:"reembedded"`
+	Unchanged          int    `json:"unchanged"` // Already current in the staging collection
+	Removed            int    `json:"removed"`   // Deleted from the live collection since an earlier run
+	Switched           bool   `json:"switched"`
+	DurationMs         int    `json:"duration_ms"`
+}
+
+// IngestRequest represents a request to store the source files of a local
+// directory as memories
+type IngestRequest struct {
+	Path          string `json:"path" validate:"required"`
+	WorkspaceID   string `json:"workspace_id"`    // Defaults to the absolute path
+	MaxChunkLines int    `json:"max_chunk_lines"` // Zero uses the default
+	Concurrency   int    `json:"concurrency"`     // Notes constructed at once, zero uses the default
+	DryRun        bool   `json:"dry_run"`         // Report the chunks without storing them
+}
+
+// IngestFailure describes a chunk that could not be stored
+type IngestFailure struct {
+	FilePath  string `json:"file_path"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Error     string `json:"error"`
+}
+
+// IngestResponse represents the outcome of ingesting a directory
+type IngestResponse struct {
+	WorkspaceID  string          `json:"workspace_id"`
+	Files        int             `json:"files"`         // Files chunked
+	FilesIgnored int             `json:"files_ignored"` // Excluded by .gitignore
+	FilesSkipped int             `json:"files_skipped"` // Unknown type, binary or too large
+	Chunks       int             `json:"chunks"`
+	Created      int             `json:"created"`
+	Skipped      int             `json:"skipped"` // Duplicates of stored memories
+	Merged       int             `json:"merged"`
+	Failed       int             `json:"failed"`
+	Failures     []IngestFailure `json:"failures,omitempty"`
+	DryRun       bool            `json:"dry_run"`
+	DurationMs   int64           `json:"duration_ms"`
+}
+
+// Identifies memory exports in their header line
+const (
+	ExportFormat        = "amem-memories"
+	ExportFormatVersion = 1
+)
+
+// ExportHeader is the first line of a JSONL memory export. Every following
+// line is a Memory.
+type ExportHeader struct {
+	Format             string    `json:"format"`
+	Version            int       `json:"version"`
+	ExportedAt         time.Time `json:"exported_at"`
+	WorkspaceID        string    `json:"workspace_id,omitempty"` // Set when the export is limited to one workspace
+	Count              int       `json:"count"`
+	EmbeddingModel     string    `json:"embedding_model,omitempty"` // Set when embeddings are included
+	EmbeddingDimension int       `json:"embedding_dimension,omitempty"`
+}
+
+// ExportRequest represents a request to export memories
+type ExportRequest struct {
+	WorkspaceID       string `json:"workspace_id"`
+	IncludeEmbeddings bool   `json:"include_embeddings"`
+}
+
+// ExportResponse represents the outcome of an export
+type ExportResponse struct {
+	Path               string `json:"path,omitempty"` // File written by the export command
+	Data               string `json:"data,omitempty"` // JSONL returned by the export_memories tool
+	WorkspaceID        string `json:"workspace_id,omitempty"`
+	Exported           int    `json:"exported"`
+	IncludesEmbeddings bool   `json:"includes_embeddings"`
+	DurationMs         int64  `json:"duration_ms"`
+}
+
+// Policies for imported memories whose ID is already stored
+const (
+	ImportConflictSkip      = "skip"      // Keep the stored memory
+	ImportConflictOverwrite = "overwrite" // Replace the stored memory
+	ImportConflictNewID     = "new_id"    // Import the memory under a new ID
+	ImportConflictFail      = "fail"      // Abort the import before anything is written
+)
+
+// ImportRequest represents a request to import memories from an export
+type ImportRequest struct {
+	Data        string `json:"data"`         // JSONL passed to the import_memories tool
+	OnConflict  string `json:"on_conflict"`  // skip|overwrite|new_id|fail
+	WorkspaceID string `json:"workspace_id"` // Moves every memory into this workspace when set
+	Reembed     bool   `json:"reembed"`      // Re-embed every memory instead of reusing exported embeddings
+}
+
+// ImportResponse represents the outcome of an import
+type ImportResponse struct {
+	Path        string `json:"path,omitempty"` // File read by the import command
+	Read        int    `json:"read"`
+	Imported    int    `json:"imported"`    // Memories written, including overwritten and renamed ones
+	Overwritten int    `json:"overwritten"` // Stored memories replaced
+	Renamed     int    `json:"renamed"`     // Memories imported under a new ID
+	Skipped     int    `json:"skipped"`
+	Reembedded  int    `json:"reembedded"` // Memories whose embedding was regenerated
+	DurationMs  int64  `json:"duration_ms"`
+}
+
 // NoteConstructionResult represents the result of LLM-based note construction
 type NoteConstructionResult struct {
 	Keywords []string `json:"keywords"`
@@ -95,6 +326,15 @@ type NoteConstructionResult struct {
 	Tags     []string `json:"tags"`
 }
 
+// MemoryMergeResult represents the result of LLM-based merging of a duplicate
+// memory into an existing one
+type MemoryMergeResult struct {
+	Content  string   `json:"content"`
+	Context  string   `json:"context"`
+	Keywords []string `json:"keywords"`
+	Tags     []string `json:"tags"`
+}
+
 // EvolutionAnalysisResult represents the result of memory evolution analysis
 type EvolutionAnalysisResult struct {
 	ShouldEvolve         bool                   `json:"should_evolve"`
@@ -107,19 +347,26 @@ type EvolutionAnalysisResult struct {
 
 // Workspace represents a logical grouping of memories
 type Workspace struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	MemoryCount int       `json:"memory_count"`
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Owner       string                 `json:"owner,omitempty"`
+	Settings    map[string]interface{} `json:"settings,omitempty"`
+	Parents     []string               `json:"parents,omitempty"` // Workspaces whose memories retrieval inherits
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+	MemoryCount int                    `json:"memory_count"`
+	Registered  bool                   `json:"registered"` // False for workspaces without a registry record
 }
 
 // WorkspaceRequest represents a request to create or retrieve a workspace
 type WorkspaceRequest struct {
-	Identifier  string `json:"identifier"`  // Path or name for the workspace
-	Name        string `json:"name"`        // Human-readable name (optional)
-	Description string `json:"description"` // Workspace description (optional)
+	Identifier  string                 `json:"identifier"`  // Path or name for the workspace
+	Name        string                 `json:"name"`        // Human-readable name (optional)
+	Description string                 `json:"description"` // Workspace description (optional)
+	Owner       string                 `json:"owner"`       // Owner of the workspace (optional)
+	Settings    map[string]interface{} `json:"settings"`    // Free-form workspace settings (optional)
+	Parents     []string               `json:"parents"`     // Workspaces whose memories retrieval inherits (optional)
 }
 
 // WorkspaceResponse represents the response after workspace operations
@@ -127,3 +374,117 @@ type WorkspaceResponse struct {
 	Workspace Workspace `json:"workspace"`
 	Created   bool      `json:"created"` // True if workspace was created, false if retrieved
 }
+
+// WorkspaceUpdateRequest changes the registered details of a workspace.
+// Empty fields are left unchanged; settings are merged into the existing
+// ones and a setting set to null is removed. Parents replace the existing
+// ones when present, so an empty list removes them all.
+type WorkspaceUpdateRequest struct {
+	Identifier  string                 `json:"identifier" validate:"required"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Owner       string                 `json:"owner"`
+	Settings    map[string]interface{} `json:"settings"`
+	Parents     []string               `json:"parents"`
+}
+
+// WorkspaceListResponse lists registered workspaces and those holding memories
+type WorkspaceListResponse struct {
+	Workspaces []Workspace `json:"workspaces"`
+	TotalCount int         `json:"total_count"`
+}
+
+// WorkspaceDeleteRequest removes a workspace. A workspace that still holds
+// memories is only deleted together with them when DeleteMemories is set.
+type WorkspaceDeleteRequest struct {
+	Identifier     string `json:"identifier" validate:"required"`
+	DeleteMemories bool   `json:"delete_memories"`
+}
+
+// WorkspaceRenameRequest gives a workspace a new identifier, moving its
+// memories and registry record
+type WorkspaceRenameRequest struct {
+	Identifier    string `json:"identifier" validate:"required"`
+	NewIdentifier string `json:"new_identifier" validate:"required"`
+}
+
+// WorkspaceMergeRequest folds the memories of one workspace into another.
+// Source memories that duplicate a target memory are dropped in its favour.
+type WorkspaceMergeRequest struct {
+	Source              string  `json:"source" validate:"required"`
+	Target              string  `json:"target" validate:"required"`
+	SimilarityThreshold float32 `json:"similarity_threshold"` // Minimum similarity for a duplicate, defaults to the dedupe threshold
+}
+
+// MoveMemoriesRequest moves memories to another workspace, selected either by
+// ID or by a filter on one workspace
+type MoveMemoriesRequest struct {
+	MemoryIDs         []string `json:"memory_ids"`
+	WorkspaceID       string   `json:"workspace_id"` // Workspace to move matching memories from
+	CodeTypes         []string `json:"code_types"`
+	Tags              []string `json:"tags"` // Memories must carry every tag
+	TargetWorkspaceID string   `json:"target_workspace_id" validate:"required"`
+}
+
+// WorkspaceMoveResponse reports the outcome of a rename, merge or move
+type WorkspaceMoveResponse struct {
+	SourceWorkspaceID string   `json:"source_workspace_id,omitempty"`
+	TargetWorkspaceID string   `json:"target_workspace_id"`
+	Matched           int      `json:"matched"`
+	Moved             int      `json:"moved"`
+	Deduplicated      int      `json:"deduplicated"` // Source memories dropped as duplicates of target memories
+	Unchanged         int      `json:"unchanged"`    // Memories already in the target workspace
+	LinksRewritten    int      `json:"links_rewritten"`
+	NotFound          []string `json:"not_found,omitempty"`
+	DurationMs        int64    `json:"duration_ms"`
+}
+
+// WorkspaceDeleteResponse reports what a workspace deletion removed
+type WorkspaceDeleteResponse struct {
+	WorkspaceID     string `json:"workspace_id"`
+	WasRegistered   bool   `json:"was_registered"`
+	MemoriesDeleted int    `json:"memories_deleted"`
+}
+
+// WorkspaceStatsRequest selects the workspace to compute statistics for
+type WorkspaceStatsRequest struct {
+	WorkspaceID string `json:"workspace_id"` // Empty uses the default workspace
+	Limit       int    `json:"limit"`        // Entries in the tag and keyword rankings, zero uses 10
+}
+
+// WorkspaceStats summarizes every memory of a workspace
+type WorkspaceStats struct {
+	WorkspaceID   string         `json:"workspace_id"`
+	MemoryCount   int            `json:"memory_count"`
+	TotalMemories int            `json:"total_memories"` // Memories in all workspaces
+	CodeTypes     map[string]int `json:"code_types"`     // Memories without a code type count as "none"
+	Tags          []TermCount    `json:"tags"`           // Most used tags first
+	DistinctTags  int            `json:"distinct_tags"`
+	Untagged      int            `json:"untagged"`
+	TopKeywords   []TermCount    `json:"top_keywords"`
+	Ages          []AgeBucket    `json:"ages"` // By time since creation, newest bucket first
+	Links         LinkStats      `json:"links"`
+	OldestMemory  *time.Time     `json:"oldest_memory,omitempty"`
+	LastUpdated   *time.Time     `json:"last_updated,omitempty"`
+	DurationMs    int64          `json:"duration_ms"`
+}
+
+// TermCount is the number of memories a tag or keyword appears in
+type TermCount struct {
+	Term  string `json:"term"`
+	Count int    `json:"count"`
+}
+
+// AgeBucket counts the memories created within an age range
+type AgeBucket struct {
+	Bucket string `json:"bucket"` // day|week|month|quarter|older
+	Count  int    `json:"count"`
+}
+
+// LinkStats counts the links of a workspace's memories
+type LinkStats struct {
+	Total          int            `json:"total"`
+	ByType         map[string]int `json:"by_type"`
+	LinkedMemories int            `json:"linked_memories"` // Memories with at least one link
+	External       int            `json:"external"`        // Links to memories outside the workspace
+}
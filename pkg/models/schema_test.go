@@ -0,0 +1,39 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJSONSchemaForFollowsJSONEncoding(t *testing.T) {
+	schema := JSONSchemaFor(RetrieveMemoryResponse{})
+
+	properties := schema["properties"].(map[string]interface{})
+	memories := properties["memories"].(map[string]interface{})
+	if !reflect.DeepEqual(memories["type"], []string{"array", "null"}) {
+		t.Errorf("Expected memories to be a nullable array, got %v", memories["type"])
+	}
+
+	// Fields of the embedded Memory are flattened into RetrievedMemory
+	item := memories["items"].(map[string]interface{})
+	itemProperties := item["properties"].(map[string]interface{})
+	for _, name := range []string{"id", "content", "relevance_score", "created_at"} {
+		if _, ok := itemProperties[name]; !ok {
+			t.Errorf("Expected property %s in %v", name, itemProperties)
+		}
+	}
+
+	createdAt := itemProperties["created_at"].(map[string]interface{})
+	if createdAt["type"] != "string" || createdAt["format"] != "date-time" {
+		t.Errorf("Expected created_at to be a date-time string, got %v", createdAt)
+	}
+
+	// omitempty fields are not required
+	links := itemProperties["links"].(map[string]interface{})
+	link := links["items"].(map[string]interface{})
+	for _, name := range link["required"].([]string) {
+		if name == "source_id" {
+			t.Error("Expected omitempty field source_id not to be required")
+		}
+	}
+}
@@ -3,11 +3,15 @@ package services
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/amem/mcp-server/pkg/config"
@@ -21,7 +25,10 @@ type ChromaDBService struct {
 	logger       *zap.Logger
 	httpClient   *http.Client
 	baseURL      string
-	collectionID string // Cache the collection UUID
+	mu           sync.RWMutex  // Guards the cached collection state below
+	collectionID string        // Cache the collection UUID
+	metric       string        // Distance metric the collection was created with
+	embedding    EmbeddingInfo // Embedding model recorded in the collection metadata
 }
 
 // ChromaAddRequest represents a request to add documents to ChromaDB
@@ -48,6 +55,32 @@ type ChromaQueryResponse struct {
 	Documents [][]string                 `json:"documents"`
 }
 
+// ChromaGetRequest represents a request to fetch documents from ChromaDB by ID or filter
+type ChromaGetRequest struct {
+	IDs     []string               `json:"ids,omitempty"`
+	Where   map[string]interface{} `json:"where,omitempty"`
+	Limit   int                    `json:"limit,omitempty"`
+	Offset  int                    `json:"offset,omitempty"`
+	Include []string               `json:"include"`
+}
+
+// ChromaGetResponse represents a get response from ChromaDB
+type ChromaGetResponse struct {
+	IDs        []string                 `json:"ids"`
+	Embeddings [][]float32              `json:"embeddings"`
+	Metadatas  []map[string]interface{} `json:"metadatas"`
+	Documents  []string                 `json:"documents"`
+}
+
+// ChromaDeleteRequest represents a request to delete documents from ChromaDB
+type ChromaDeleteRequest struct {
+	IDs   []string               `json:"ids,omitempty"`
+	Where map[string]interface{} `json:"where,omitempty"`
+}
+
+// ErrMemoryNotFound is returned when a memory ID does not exist in the collection
+var ErrMemoryNotFound = errors.New("memory not found")
+
 // NewChromaDBService creates a new ChromaDB service
 func NewChromaDBService(cfg config.ChromaDBConfig, logger *zap.Logger) *ChromaDBService {
 	return &ChromaDBService{
@@ -57,52 +90,222 @@ func NewChromaDBService(cfg config.ChromaDBConfig, logger *zap.Logger) *ChromaDB
 			Timeout: 30 * time.Second,
 		},
 		baseURL: cfg.URL,
+		metric:  configuredMetric(cfg.DistanceMetric),
 	}
 }
 
+// configuredMetric returns the configured distance metric, defaulting to cosine
+func configuredMetric(metric string) string {
+	if metric == "" {
+		return DistanceCosine
+	}
+	return metric
+}
+
+// DistanceMetric returns the distance metric used by the collection
+func (c *ChromaDBService) DistanceMetric() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.metric
+}
+
+// chromaCollection is the part of a ChromaDB collection description we use
+type chromaCollection struct {
+	ID       string                 `json:"id"`
+	Name     string                 `json:"name"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
 // getCollectionID gets the UUID for a collection by name
 func (c *ChromaDBService) getCollectionID(ctx context.Context) (string, error) {
-	if c.collectionID != "" {
-		return c.collectionID, nil // Return cached ID
+	c.mu.RLock()
+	cached := c.collectionID
+	c.mu.RUnlock()
+	if cached != "" {
+		return cached, nil // Return cached ID
 	}
 
+	collection, err := c.fetchCollection(ctx, c.config.Collection)
+	if err != nil {
+		return "", err
+	}
+
+	// Collections created without hnsw:space use ChromaDB's default, l2. The
+	// metric cannot be changed after creation, so score with the actual one.
+	metric := DistanceL2
+	if space, ok := collection.Metadata["hnsw:space"].(string); ok && space != "" {
+		metric = space
+	}
+	if metric != configuredMetric(c.config.DistanceMetric) {
+		c.logger.Warn("Collection distance metric differs from configuration; recreate the collection to change it",
+			zap.String("collection", c.config.Collection),
+			zap.String("collection_metric", metric),
+			zap.String("configured_metric", configuredMetric(c.config.DistanceMetric)))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.embedding = embeddingInfoFromMetadata(collection.Metadata)
+	c.metric = metric
+	c.collectionID = collection.ID // Cache the ID
+	return c.collectionID, nil
+}
+
+// fetchCollection looks up a collection by name
+func (c *ChromaDBService) fetchCollection(ctx context.Context, name string) (*chromaCollection, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET",
-		fmt.Sprintf("%s/api/v1/collections/%s", c.baseURL, c.config.Collection), nil)
+		fmt.Sprintf("%s/api/v1/collections/%s", c.baseURL, name), nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to get collection: %w", err)
+		return nil, fmt.Errorf("failed to get collection: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("ChromaDB get collection error: %d - %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("ChromaDB get collection error: %d - %s", resp.StatusCode, string(body))
 	}
 
-	var collection struct {
-		ID string `json:"id"`
+	var collection chromaCollection
+	if err := json.NewDecoder(resp.Body).Decode(&collection); err != nil {
+		return nil, fmt.Errorf("failed to decode collection response: %w", err)
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&collection); err != nil {
-		return "", fmt.Errorf("failed to decode collection response: %w", err)
+	return &collection, nil
+}
+
+// renameCollection gives the collection with the given ID a new name
+func (c *ChromaDBService) renameCollection(ctx context.Context, id, name string) error {
+	requestBody, err := json.Marshal(map[string]interface{}{"new_name": name})
+	if err != nil {
+		return fmt.Errorf("failed to marshal rename request: %w", err)
 	}
 
-	c.collectionID = collection.ID // Cache the ID
-	return c.collectionID, nil
+	req, err := http.NewRequestWithContext(ctx, "PUT",
+		fmt.Sprintf("%s/api/v1/collections/%s", c.baseURL, id), bytes.NewBuffer(requestBody))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to rename collection: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("ChromaDB rename collection error: %d - %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// OpenStaging creates or reopens the staging collection <collection>_<name>
+func (c *ChromaDBService) OpenStaging(ctx context.Context, name string, embedding EmbeddingInfo) (MemoryStore, error) {
+	cfg := c.config
+	cfg.Collection = chromaCollectionName(c.config.Collection + "_" + name)
+
+	staging := NewChromaDBService(cfg, c.logger.With(zap.String("staging_collection", cfg.Collection)))
+	if err := staging.Initialize(ctx, embedding); err != nil {
+		return nil, fmt.Errorf("failed to open staging collection: %w", err)
+	}
+
+	return staging, nil
 }
 
-// Initialize initializes the ChromaDB collection
-func (c *ChromaDBService) Initialize(ctx context.Context) error {
+// PromoteStaging renames the live collection to a timestamped backup and the
+// staging collection to the configured name. If the second rename fails the
+// live collection is renamed back, so the configured name always resolves.
+func (c *ChromaDBService) PromoteStaging(ctx context.Context, name string) (string, error) {
+	staging, err := c.fetchCollection(ctx, chromaCollectionName(c.config.Collection+"_"+name))
+	if err != nil {
+		return "", fmt.Errorf("failed to find staging collection: %w", err)
+	}
+
+	liveID, err := c.getCollectionID(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	backup := chromaCollectionName(c.config.Collection + "_backup_" + time.Now().UTC().Format("20060102T150405"))
+	if err := c.renameCollection(ctx, liveID, backup); err != nil {
+		return "", err
+	}
+
+	if err := c.renameCollection(ctx, staging.ID, c.config.Collection); err != nil {
+		if rollbackErr := c.renameCollection(ctx, liveID, c.config.Collection); rollbackErr != nil {
+			c.logger.Error("Failed to restore live collection name",
+				zap.String("backup", backup),
+				zap.Error(rollbackErr))
+		}
+		return "", err
+	}
+
+	// Forget the cached collection so later calls resolve the promoted one
+	c.mu.Lock()
+	c.collectionID = ""
+	c.mu.Unlock()
+
+	if _, err := c.getCollectionID(ctx); err != nil {
+		return "", err
+	}
+
+	c.logger.Info("Staging collection promoted",
+		zap.String("collection", c.config.Collection),
+		zap.String("backup", backup))
+
+	return backup, nil
+}
+
+// chromaCollectionName keeps generated names within ChromaDB's 63 character
+// limit. Longer names are truncated and suffixed with a hash of the full name,
+// so names that share a long prefix still map to different collections.
+func chromaCollectionName(name string) string {
+	const maxLen = 63
+	if len(name) <= maxLen {
+		return strings.TrimRight(name, "_-.")
+	}
+
+	sum := sha256.Sum256([]byte(name))
+	suffix := hex.EncodeToString(sum[:4])
+	return strings.TrimRight(name[:maxLen-len(suffix)-1], "_-.") + "_" + suffix
+}
+
+// embeddingInfoFromMetadata reads the embedding model recorded on a collection
+func embeddingInfoFromMetadata(metadata map[string]interface{}) EmbeddingInfo {
+	var info EmbeddingInfo
+	if model, ok := metadata["embedding_model"].(string); ok {
+		info.Model = model
+	}
+	if dimension, ok := metadata["embedding_dimension"].(float64); ok {
+		info.Dimension = int(dimension)
+	}
+	return info
+}
+
+// Initialize initializes the ChromaDB collection, recording the embedding model
+// on new collections and refusing to use collections embedded with another one
+func (c *ChromaDBService) Initialize(ctx context.Context, embedding EmbeddingInfo) error {
 	// Create collection if it doesn't exist
+	metadata := map[string]interface{}{
+		"description": "A-MEM memory storage",
+		"hnsw:space":  configuredMetric(c.config.DistanceMetric),
+	}
+	if embedding != (EmbeddingInfo{}) {
+		metadata["embedding_model"] = embedding.Model
+		metadata["embedding_dimension"] = embedding.Dimension
+	}
+
 	collectionData := map[string]interface{}{
-		"name": c.config.Collection,
-		"metadata": map[string]interface{}{
-			"description": "A-MEM memory storage",
-		},
+		"name":     c.config.Collection,
+		"metadata": metadata,
 	}
 
 	requestBody, err := json.Marshal(collectionData)
@@ -141,55 +344,393 @@ func (c *ChromaDBService) Initialize(ctx context.Context) error {
 		return fmt.Errorf("ChromaDB API error: %d - %s", resp.StatusCode, string(body))
 	}
 
+	// Load the collection to cache its ID and learn its actual distance metric
+	if _, err := c.getCollectionID(ctx); err != nil {
+		return err
+	}
+
+	c.mu.RLock()
+	recorded, metric := c.embedding, c.metric
+	c.mu.RUnlock()
+
+	// A zero EmbeddingInfo means the caller only reads stored content
+	if recorded == (EmbeddingInfo{}) {
+		c.logger.Warn("Collection does not record its embedding model; dimension mismatches cannot be detected until it is reindexed",
+			zap.String("collection", c.config.Collection))
+	} else if embedding != (EmbeddingInfo{}) {
+		if err := CheckEmbeddingCompatibility(recorded, embedding); err != nil {
+			return fmt.Errorf("collection %s: %w", c.config.Collection, err)
+		}
+	}
+
 	c.logger.Info("ChromaDB collection initialized",
-		zap.String("collection", c.config.Collection))
+		zap.String("collection", c.config.Collection),
+		zap.String("distance_metric", metric),
+		zap.String("embedding_model", recorded.Model),
+		zap.Int("embedding_dimension", recorded.Dimension))
 
 	return nil
 }
 
 // StoreMemory stores a memory in ChromaDB
 func (c *ChromaDBService) StoreMemory(ctx context.Context, memory *models.Memory) error {
-	if len(memory.Embedding) == 0 {
-		return fmt.Errorf("memory embedding is required")
+	if err := c.writeMemories(ctx, "add", []*models.Memory{memory}); err != nil {
+		return err
 	}
 
-	// Get collection UUID
+	c.logger.Debug("Memory stored in ChromaDB",
+		zap.String("memory_id", memory.ID))
+
+	return nil
+}
+
+// StoreMemories stores memories in ChromaDB, sending them in batches of the
+// configured batch size
+func (c *ChromaDBService) StoreMemories(ctx context.Context, memories []*models.Memory) error {
+	if err := c.writeBatches(ctx, "add", memories); err != nil {
+		return err
+	}
+
+	c.logger.Debug("Memories stored in ChromaDB",
+		zap.Int("count", len(memories)))
+
+	return nil
+}
+
+// UpdateMemories overwrites existing memories, sending them in batches of the
+// configured batch size
+func (c *ChromaDBService) UpdateMemories(ctx context.Context, memories []*models.Memory) error {
+	if err := c.writeBatches(ctx, "update", memories); err != nil {
+		return err
+	}
+
+	c.logger.Debug("Memories updated in ChromaDB",
+		zap.Int("count", len(memories)))
+
+	return nil
+}
+
+// writeBatches splits memories into batches of the configured batch size and
+// sends each to the given write endpoint. Batches sent before a failing one
+// stay written, which is reported as a *PartialWriteError.
+func (c *ChromaDBService) writeBatches(ctx context.Context, operation string, memories []*models.Memory) error {
+	batchSize := c.config.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	for start := 0; start < len(memories); start += batchSize {
+		end := start + batchSize
+		if end > len(memories) {
+			end = len(memories)
+		}
+
+		if err := c.writeMemories(ctx, operation, memories[start:end]); err != nil {
+			if start > 0 {
+				return &PartialWriteError{Written: start, Err: err}
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UpdateMemory overwrites the document, embedding and metadata of an existing memory
+func (c *ChromaDBService) UpdateMemory(ctx context.Context, memory *models.Memory) error {
+	if err := c.writeMemories(ctx, "update", []*models.Memory{memory}); err != nil {
+		return err
+	}
+
+	c.logger.Debug("Memory updated in ChromaDB",
+		zap.String("memory_id", memory.ID))
+
+	return nil
+}
+
+// UpsertMemory stores a memory, replacing any existing memory with the same ID
+func (c *ChromaDBService) UpsertMemory(ctx context.Context, memory *models.Memory) error {
+	if err := c.writeMemories(ctx, "upsert", []*models.Memory{memory}); err != nil {
+		return err
+	}
+
+	c.logger.Debug("Memory upserted in ChromaDB",
+		zap.String("memory_id", memory.ID))
+
+	return nil
+}
+
+// writeMemories sends memories to one of the collection's add, update or upsert endpoints
+func (c *ChromaDBService) writeMemories(ctx context.Context, operation string, memories []*models.Memory) error {
+	request := ChromaAddRequest{
+		IDs:        make([]string, 0, len(memories)),
+		Embeddings: make([][]float32, 0, len(memories)),
+		Metadatas:  make([]map[string]interface{}, 0, len(memories)),
+		Documents:  make([]string, 0, len(memories)),
+	}
+
+	for _, memory := range memories {
+		if len(memory.Embedding) == 0 {
+			return fmt.Errorf("memory embedding is required")
+		}
+
+		request.IDs = append(request.IDs, memory.ID)
+		request.Embeddings = append(request.Embeddings, memory.Embedding)
+		request.Metadatas = append(request.Metadatas, memoryToMetadata(memory))
+		request.Documents = append(request.Documents, memory.Content)
+	}
+
+	if err := c.postCollection(ctx, operation, request, nil); err != nil {
+		return fmt.Errorf("failed to %s memory: %w", operation, err)
+	}
+
+	return nil
+}
+
+// GetMemory fetches a single memory, including its embedding, by ID
+func (c *ChromaDBService) GetMemory(ctx context.Context, id string) (*models.Memory, error) {
+	memories, err := c.GetMemories(ctx, []string{id})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(memories) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrMemoryNotFound, id)
+	}
+
+	return memories[0], nil
+}
+
+// GetMemories fetches memories, including their embeddings, by ID. IDs that do
+// not exist are omitted from the result.
+func (c *ChromaDBService) GetMemories(ctx context.Context, ids []string) ([]*models.Memory, error) {
+	if len(ids) == 0 {
+		return []*models.Memory{}, nil
+	}
+
+	request := ChromaGetRequest{
+		IDs:     ids,
+		Include: []string{"metadatas", "documents", "embeddings"},
+	}
+
+	var response ChromaGetResponse
+	if err := c.postCollection(ctx, "get", request, &response); err != nil {
+		return nil, fmt.Errorf("failed to get memories: %w", err)
+	}
+
+	memories := make([]*models.Memory, 0, len(response.IDs))
+	for i, id := range response.IDs {
+		var document string
+		if i < len(response.Documents) {
+			document = response.Documents[i]
+		}
+
+		var metadata map[string]interface{}
+		if i < len(response.Metadatas) {
+			metadata = response.Metadatas[i]
+		}
+
+		memory := metadataToMemory(id, document, metadata)
+		if i < len(response.Embeddings) {
+			memory.Embedding = response.Embeddings[i]
+		}
+
+		memories = append(memories, memory)
+	}
+
+	return memories, nil
+}
+
+// ListMemories fetches one page of memories matching a metadata filter, without
+// their embeddings, in the collection's storage order
+func (c *ChromaDBService) ListMemories(ctx context.Context, where map[string]interface{}, limit, offset int) ([]*models.Memory, error) {
+	return c.getPage(ctx, where, limit, offset, []string{"metadatas", "documents"})
+}
+
+// getPage fetches one page of memories matching a metadata filter with the
+// given fields included
+func (c *ChromaDBService) getPage(ctx context.Context, where map[string]interface{}, limit, offset int, include []string) ([]*models.Memory, error) {
+	request := ChromaGetRequest{
+		Limit:   limit,
+		Offset:  offset,
+		Include: include,
+	}
+
+	if len(where) > 0 {
+		request.Where = where
+	}
+
+	var response ChromaGetResponse
+	if err := c.postCollection(ctx, "get", request, &response); err != nil {
+		return nil, fmt.Errorf("failed to list memories: %w", err)
+	}
+
+	memories := make([]*models.Memory, 0, len(response.IDs))
+	for i, id := range response.IDs {
+		var document string
+		if i < len(response.Documents) {
+			document = response.Documents[i]
+		}
+
+		var metadata map[string]interface{}
+		if i < len(response.Metadatas) {
+			metadata = response.Metadatas[i]
+		}
+
+		memories = append(memories, metadataToMemory(id, document, metadata))
+	}
+
+	return memories, nil
+}
+
+// ListAllMemories fetches every memory matching a metadata filter, paging
+// through the collection in batches of the configured batch size
+func (c *ChromaDBService) ListAllMemories(ctx context.Context, where map[string]interface{}) ([]*models.Memory, error) {
+	batchSize := c.config.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	var all []*models.Memory
+	for offset := 0; ; offset += batchSize {
+		page, err := c.ListMemories(ctx, where, batchSize, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, page...)
+
+		if len(page) < batchSize {
+			break
+		}
+	}
+
+	return all, nil
+}
+
+// ScanMemories pages through the metadata of every memory matching a filter
+// in batches of the configured batch size, leaving out the documents
+func (c *ChromaDBService) ScanMemories(ctx context.Context, where map[string]interface{}, visit func(*models.Memory) error) error {
+	batchSize := c.config.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	for offset := 0; ; offset += batchSize {
+		page, err := c.getPage(ctx, where, batchSize, offset, []string{"metadatas"})
+		if err != nil {
+			return err
+		}
+
+		for _, memory := range page {
+			if err := visit(memory); err != nil {
+				return err
+			}
+		}
+
+		if len(page) < batchSize {
+			return nil
+		}
+	}
+}
+
+// countPageSize is the number of IDs fetched per request when counting the
+// memories that match a filter
+const countPageSize = 1000
+
+// CountMemories counts the memories matching a metadata filter. Without a
+// filter it asks the collection's count endpoint; otherwise it pages through
+// the IDs of the matching memories, leaving out their documents and metadata.
+func (c *ChromaDBService) CountMemories(ctx context.Context, where map[string]interface{}) (int, error) {
+	if len(where) == 0 {
+		return c.countCollection(ctx)
+	}
+
+	total := 0
+	for offset := 0; ; offset += countPageSize {
+		request := ChromaGetRequest{
+			Where:   where,
+			Limit:   countPageSize,
+			Offset:  offset,
+			Include: []string{},
+		}
+
+		var response ChromaGetResponse
+		if err := c.postCollection(ctx, "get", request, &response); err != nil {
+			return 0, fmt.Errorf("failed to count memories: %w", err)
+		}
+
+		total += len(response.IDs)
+		if len(response.IDs) < countPageSize {
+			return total, nil
+		}
+	}
+}
+
+// countCollection returns the number of memories in the collection
+func (c *ChromaDBService) countCollection(ctx context.Context) (int, error) {
 	collectionID, err := c.getCollectionID(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get collection ID: %w", err)
+		return 0, fmt.Errorf("failed to get collection ID: %w", err)
 	}
 
-	// Prepare metadata
-	metadata := map[string]interface{}{
-		"context":      memory.Context,
-		"keywords":     strings.Join(memory.Keywords, ","),
-		"tags":         strings.Join(memory.Tags, ","),
-		"project_path": memory.ProjectPath, // Keep for backward compatibility
-		"workspace_id": memory.WorkspaceID,
-		"code_type":    memory.CodeType,
-		"created_at":   memory.CreatedAt.Unix(),
-		"updated_at":   memory.UpdatedAt.Unix(),
+	req, err := http.NewRequestWithContext(ctx, "GET",
+		fmt.Sprintf("%s/api/v1/collections/%s/count", c.baseURL, collectionID), nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	// Add custom metadata
-	for k, v := range memory.Metadata {
-		metadata[k] = v
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("ChromaDB count request failed: %w", err)
 	}
+	defer resp.Body.Close()
 
-	request := ChromaAddRequest{
-		IDs:        []string{memory.ID},
-		Embeddings: [][]float32{memory.Embedding},
-		Metadatas:  []map[string]interface{}{metadata},
-		Documents:  []string{memory.Content},
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return 0, fmt.Errorf("ChromaDB count error: %d - %s", resp.StatusCode, string(body))
 	}
 
-	requestBody, err := json.Marshal(request)
+	var count int
+	if err := json.NewDecoder(resp.Body).Decode(&count); err != nil {
+		return 0, fmt.Errorf("failed to decode count response: %w", err)
+	}
+
+	return count, nil
+}
+
+// DeleteMemories removes memories from the collection by ID
+func (c *ChromaDBService) DeleteMemories(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	request := ChromaDeleteRequest{IDs: ids}
+	if err := c.postCollection(ctx, "delete", request, nil); err != nil {
+		return fmt.Errorf("failed to delete memories: %w", err)
+	}
+
+	c.logger.Debug("Memories deleted from ChromaDB",
+		zap.Strings("memory_ids", ids))
+
+	return nil
+}
+
+// postCollection POSTs a JSON payload to an endpoint of the configured collection
+// and decodes the response into out when it is non-nil
+func (c *ChromaDBService) postCollection(ctx context.Context, endpoint string, payload interface{}, out interface{}) error {
+	collectionID, err := c.getCollectionID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get collection ID: %w", err)
+	}
+
+	requestBody, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST",
-		fmt.Sprintf("%s/api/v1/collections/%s/add", c.baseURL, collectionID),
+		fmt.Sprintf("%s/api/v1/collections/%s/%s", c.baseURL, collectionID, endpoint),
 		bytes.NewBuffer(requestBody))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
@@ -199,17 +740,22 @@ func (c *ChromaDBService) StoreMemory(ctx context.Context, memory *models.Memory
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to store memory: %w", err)
+		return fmt.Errorf("ChromaDB %s request failed: %w", endpoint, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("ChromaDB store error: %d - %s", resp.StatusCode, string(body))
+		return fmt.Errorf("ChromaDB %s error: %d - %s", endpoint, resp.StatusCode, string(body))
 	}
 
-	c.logger.Debug("Memory stored in ChromaDB",
-		zap.String("memory_id", memory.ID))
+	if out == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", endpoint, err)
+	}
 
 	return nil
 }
@@ -275,44 +821,12 @@ func (c *ChromaDBService) SearchSimilar(ctx context.Context, queryEmbedding []fl
 	distances := response.Distances[0]
 
 	for i, id := range response.IDs[0] {
-		memory := &models.Memory{
-			ID:      id,
-			Content: response.Documents[0][i],
-		}
-
-		// Reconstruct memory from metadata
+		var metadata map[string]interface{}
 		if len(response.Metadatas) > 0 && len(response.Metadatas[0]) > i {
-			metadata := response.Metadatas[0][i]
-
-			if context, ok := metadata["context"].(string); ok {
-				memory.Context = context
-			}
-			if keywords, ok := metadata["keywords"].(string); ok && keywords != "" {
-				memory.Keywords = strings.Split(keywords, ",")
-			}
-			if tags, ok := metadata["tags"].(string); ok && tags != "" {
-				memory.Tags = strings.Split(tags, ",")
-			}
-			if projectPath, ok := metadata["project_path"].(string); ok {
-				memory.ProjectPath = projectPath
-			}
-			if workspaceID, ok := metadata["workspace_id"].(string); ok {
-				memory.WorkspaceID = workspaceID
-			}
-			if codeType, ok := metadata["code_type"].(string); ok {
-				memory.CodeType = codeType
-			}
-			if createdAt, ok := metadata["created_at"].(float64); ok {
-				memory.CreatedAt = time.Unix(int64(createdAt), 0)
-			}
-			if updatedAt, ok := metadata["updated_at"].(float64); ok {
-				memory.UpdatedAt = time.Unix(int64(updatedAt), 0)
-			}
-
-			memory.Metadata = metadata
+			metadata = response.Metadatas[0][i]
 		}
 
-		memories = append(memories, memory)
+		memories = append(memories, metadataToMemory(id, response.Documents[0][i], metadata))
 	}
 
 	c.logger.Debug("ChromaDB search completed",
@@ -320,3 +834,95 @@ func (c *ChromaDBService) SearchSimilar(ctx context.Context, queryEmbedding []fl
 
 	return memories, distances, nil
 }
+
+// reservedMetadataKeys are the metadata keys used to persist Memory fields; they
+// are never copied into or out of Memory.Metadata
+var reservedMetadataKeys = map[string]bool{
+	"context":      true,
+	"keywords":     true,
+	"tags":         true,
+	"project_path": true,
+	"workspace_id": true,
+	"code_type":    true,
+	"created_at":   true,
+	"updated_at":   true,
+	"links":        true,
+}
+
+// memoryToMetadata flattens a memory into ChromaDB metadata
+func memoryToMetadata(memory *models.Memory) map[string]interface{} {
+	metadata := make(map[string]interface{}, len(memory.Metadata)+len(reservedMetadataKeys))
+
+	// Add custom metadata first so it can never shadow the memory's own fields
+	for k, v := range memory.Metadata {
+		if !reservedMetadataKeys[k] {
+			metadata[k] = v
+		}
+	}
+
+	metadata["context"] = memory.Context
+	metadata["keywords"] = strings.Join(memory.Keywords, ",")
+	metadata["tags"] = strings.Join(memory.Tags, ",")
+	metadata["project_path"] = memory.ProjectPath // Keep for backward compatibility
+	metadata["workspace_id"] = memory.WorkspaceID
+	metadata["code_type"] = memory.CodeType
+	metadata["created_at"] = memory.CreatedAt.Unix()
+	metadata["updated_at"] = memory.UpdatedAt.Unix()
+
+	// ChromaDB metadata values must be scalars, so links are stored as a JSON string
+	links := memory.Links
+	if links == nil {
+		links = []models.MemoryLink{}
+	}
+	if encoded, err := json.Marshal(links); err == nil {
+		metadata["links"] = string(encoded)
+	}
+
+	return metadata
+}
+
+// metadataToMemory reconstructs a memory from a ChromaDB document and its metadata
+func metadataToMemory(id, document string, metadata map[string]interface{}) *models.Memory {
+	memory := &models.Memory{
+		ID:       id,
+		Content:  document,
+		Metadata: make(map[string]interface{}),
+	}
+
+	if context, ok := metadata["context"].(string); ok {
+		memory.Context = context
+	}
+	if keywords, ok := metadata["keywords"].(string); ok && keywords != "" {
+		memory.Keywords = strings.Split(keywords, ",")
+	}
+	if tags, ok := metadata["tags"].(string); ok && tags != "" {
+		memory.Tags = strings.Split(tags, ",")
+	}
+	if projectPath, ok := metadata["project_path"].(string); ok {
+		memory.ProjectPath = projectPath
+	}
+	if workspaceID, ok := metadata["workspace_id"].(string); ok {
+		memory.WorkspaceID = workspaceID
+	}
+	if codeType, ok := metadata["code_type"].(string); ok {
+		memory.CodeType = codeType
+	}
+	if createdAt, ok := metadata["created_at"].(float64); ok {
+		memory.CreatedAt = time.Unix(int64(createdAt), 0)
+	}
+	if updatedAt, ok := metadata["updated_at"].(float64); ok {
+		memory.UpdatedAt = time.Unix(int64(updatedAt), 0)
+	}
+	if links, ok := metadata["links"].(string); ok && links != "" {
+		// A malformed links value is treated as no links rather than failing the read
+		_ = json.Unmarshal([]byte(links), &memory.Links)
+	}
+
+	for k, v := range metadata {
+		if !reservedMetadataKeys[k] {
+			memory.Metadata[k] = v
+		}
+	}
+
+	return memory
+}
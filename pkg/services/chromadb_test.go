@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amem/mcp-server/pkg/config"
+	"github.com/amem/mcp-server/pkg/models"
+	"go.uber.org/zap"
+)
+
+func TestMemoryMetadataRoundTrip(t *testing.T) {
+	memory := &models.Memory{
+		ID:          "memory-1",
+		Content:     "func main() {}",
+		Context:     "Go entry point",
+		Keywords:    []string{"main", "go"},
+		Tags:        []string{"go", "basics"},
+		WorkspaceID: "/test/project",
+		CodeType:    "go",
+		CreatedAt:   time.Unix(1700000000, 0),
+		UpdatedAt:   time.Unix(1700000100, 0),
+		Links: []models.MemoryLink{
+			{TargetID: "memory-2", LinkType: "pattern", Strength: 0.8, Reason: "Same pattern"},
+		},
+		Metadata: map[string]interface{}{
+			"source":  "test",
+			"context": "stale context",
+		},
+	}
+
+	// Simulate the JSON round trip through ChromaDB
+	data, err := json.Marshal(memoryToMetadata(memory))
+	if err != nil {
+		t.Fatalf("Failed to marshal metadata: %v", err)
+	}
+
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		t.Fatalf("Failed to unmarshal metadata: %v", err)
+	}
+
+	restored := metadataToMemory(memory.ID, memory.Content, metadata)
+
+	if restored.Context != "Go entry point" {
+		t.Errorf("Expected custom metadata not to shadow context, got %s", restored.Context)
+	}
+
+	if len(restored.Keywords) != 2 || len(restored.Tags) != 2 {
+		t.Errorf("Expected 2 keywords and 2 tags, got %v and %v", restored.Keywords, restored.Tags)
+	}
+
+	if !restored.CreatedAt.Equal(memory.CreatedAt) || !restored.UpdatedAt.Equal(memory.UpdatedAt) {
+		t.Errorf("Expected timestamps to round trip, got %v and %v", restored.CreatedAt, restored.UpdatedAt)
+	}
+
+	if len(restored.Links) != 1 || restored.Links[0].TargetID != "memory-2" || restored.Links[0].Strength != 0.8 {
+		t.Errorf("Expected link to memory-2 to round trip, got %+v", restored.Links)
+	}
+
+	if restored.Metadata["source"] != "test" {
+		t.Errorf("Expected custom metadata to round trip, got %v", restored.Metadata)
+	}
+
+	if _, ok := restored.Metadata["links"]; ok {
+		t.Error("Expected reserved keys to be excluded from custom metadata")
+	}
+}
+
+func TestMetadataToMemoryWithoutLinks(t *testing.T) {
+	restored := metadataToMemory("memory-1", "content", map[string]interface{}{
+		"context": "legacy memory",
+	})
+
+	if len(restored.Links) != 0 {
+		t.Errorf("Expected no links for legacy memory, got %+v", restored.Links)
+	}
+}
+
+func TestStoreMemoriesReportsWrittenBatches(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 2 {
+			http.Error(w, "disk full", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("true"))
+	}))
+	defer server.Close()
+
+	store := NewChromaDBService(config.ChromaDBConfig{URL: server.URL, BatchSize: 2}, zap.NewNop())
+	store.collectionID = "memories"
+
+	var memories []*models.Memory
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		memories = append(memories, &models.Memory{ID: id, Embedding: []float32{1, 0}})
+	}
+
+	err := store.StoreMemories(context.Background(), memories)
+	var partial *PartialWriteError
+	if !errors.As(err, &partial) || partial.Written != 2 {
+		t.Fatalf("Expected the first batch to be reported written, got %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected writing to stop at the failed batch, got %d requests", requests)
+	}
+}
+
+func TestChromaCollectionNameKeepsLongNamesApart(t *testing.T) {
+	prefix := strings.Repeat("memories", 8)
+	a := chromaCollectionName(prefix + "_reindex_model-a_384")
+	b := chromaCollectionName(prefix + "_reindex_model-b_384")
+
+	if len(a) > 63 || len(b) > 63 {
+		t.Errorf("Expected names within 63 characters, got %q and %q", a, b)
+	}
+	if a == b {
+		t.Errorf("Expected long names sharing a prefix to differ, both were %q", a)
+	}
+	if a != chromaCollectionName(prefix+"_reindex_model-a_384") {
+		t.Error("Expected the same name to map to the same collection")
+	}
+	if name := chromaCollectionName("memories_workspaces"); name != "memories_workspaces" {
+		t.Errorf("Expected short names to be kept, got %q", name)
+	}
+}
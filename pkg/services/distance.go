@@ -0,0 +1,60 @@
+package services
+
+import "math"
+
+// Distance metrics supported by the memory stores, named as in ChromaDB's hnsw:space
+const (
+	DistanceCosine = "cosine"
+	DistanceL2     = "l2"
+	DistanceIP     = "ip"
+)
+
+// Similarity converts a distance reported under the given metric into a
+// similarity score in [0, 1]. Every metric is mapped onto cosine similarity,
+// assuming unit-length embeddings, so relevance thresholds mean the same thing
+// whichever metric the collection uses:
+//
+//   - cosine: distance is 1 - cos, so similarity is 1 - distance
+//   - l2: distance is the squared euclidean distance, 2 - 2cos for unit vectors
+//   - ip: distance is 1 - dot product, which equals 1 - cos for unit vectors
+func Similarity(metric string, distance float32) float32 {
+	var similarity float32
+	switch metric {
+	case DistanceL2:
+		similarity = 1 - distance/2
+	default:
+		similarity = 1 - distance
+	}
+
+	if similarity < 0 {
+		return 0
+	}
+	if similarity > 1 {
+		return 1
+	}
+	return similarity
+}
+
+// VectorDistance computes the distance between two equal-length vectors under a metric
+func VectorDistance(metric string, a, b []float32) float32 {
+	var dot, normA, normB, squared float64
+	for i := range a {
+		x, y := float64(a[i]), float64(b[i])
+		dot += x * y
+		normA += x * x
+		normB += y * y
+		squared += (x - y) * (x - y)
+	}
+
+	switch metric {
+	case DistanceL2:
+		return float32(squared)
+	case DistanceIP:
+		return float32(1 - dot)
+	default:
+		if normA == 0 || normB == 0 {
+			return 1
+		}
+		return float32(1 - dot/(math.Sqrt(normA)*math.Sqrt(normB)))
+	}
+}
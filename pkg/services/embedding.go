@@ -1,13 +1,13 @@
 package services
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
-	"os"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/amem/mcp-server/pkg/config"
@@ -16,247 +16,181 @@ import (
 
 // EmbeddingService handles text embedding generation
 type EmbeddingService struct {
-	config     config.EmbeddingConfig
-	logger     *zap.Logger
-	httpClient *http.Client
-	baseURL    string
+	config    config.EmbeddingConfig
+	logger    *zap.Logger
+	provider  EmbeddingProvider
+	mu        sync.Mutex
+	dimension int // Expected vector dimension, 0 until known
 }
 
-// EmbeddingRequest represents a request to the embedding service
-type EmbeddingRequest struct {
-	Input string `json:"input"`
-	Model string `json:"model"`
+// EmbeddingProvider generates embeddings from one kind of embedding backend
+type EmbeddingProvider interface {
+	// Embed returns one embedding per input text, in order
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
 }
 
-// EmbeddingResponse represents a response from the embedding service
-type EmbeddingResponse struct {
-	Object string `json:"object"`
-	Data   []struct {
-		Object    string    `json:"object"`
-		Embedding []float32 `json:"embedding"`
-		Index     int       `json:"index"`
-	} `json:"data"`
-	Model string `json:"model"`
-	Usage struct {
-		PromptTokens int `json:"prompt_tokens"`
-		TotalTokens  int `json:"total_tokens"`
-	} `json:"usage"`
-}
+// EmbeddingProviderFactory creates a provider from the embedding configuration
+type EmbeddingProviderFactory func(cfg config.EmbeddingConfig, httpClient *http.Client, logger *zap.Logger) (EmbeddingProvider, error)
 
-// SentenceTransformersRequest for local sentence-transformers service
-type SentenceTransformersRequest struct {
-	Sentences []string `json:"sentences"`
-	Model     string   `json:"model,omitempty"`
+// embeddingProviders maps EmbeddingConfig.Service values to provider factories
+var embeddingProviders = map[string]EmbeddingProviderFactory{
+	"openai":                newOpenAIEmbeddingProvider,
+	"sentence-transformers": newSentenceTransformersProvider,
+	"ollama":                newOllamaEmbeddingProvider,
 }
 
-// SentenceTransformersResponse from local sentence-transformers service
-type SentenceTransformersResponse struct {
-	Embeddings [][]float32 `json:"embeddings"`
+// EmbeddingInfo identifies the model that produced a set of stored vectors
+type EmbeddingInfo struct {
+	Model     string
+	Dimension int
 }
 
-// NewEmbeddingService creates a new embedding service
-func NewEmbeddingService(cfg config.EmbeddingConfig, logger *zap.Logger) *EmbeddingService {
-	baseURL := cfg.URL // Use configured URL
-	if cfg.Service == "openai" {
-		baseURL = "https://api.openai.com/v1"
-	}
-	// Environment variable can override config
-	if url := getEnvString("EMBEDDING_SERVICE_URL", ""); url != "" {
-		baseURL = url
-	}
-
-	return &EmbeddingService{
-		config: cfg,
-		logger: logger,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		baseURL: baseURL,
-	}
-}
+// ErrEmbeddingMismatch is returned when vectors do not match the stored collection
+var ErrEmbeddingMismatch = errors.New("embedding mismatch")
 
-// GenerateEmbedding generates an embedding for the given text
-func (s *EmbeddingService) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
-	switch s.config.Service {
-	case "openai":
-		return s.generateOpenAIEmbedding(ctx, text)
-	case "sentence-transformers":
-		return s.generateSentenceTransformersEmbedding(ctx, text)
-	default:
-		return s.generateFallbackEmbedding(text), nil
+// NewEmbeddingService creates a new embedding service for the configured provider
+func NewEmbeddingService(cfg config.EmbeddingConfig, logger *zap.Logger) (*EmbeddingService, error) {
+	factory, ok := embeddingProviders[cfg.Service]
+	if !ok {
+		return nil, fmt.Errorf("unknown embedding service %q (available: %s)",
+			cfg.Service, strings.Join(EmbeddingProviderNames(), ", "))
 	}
-}
 
-// GenerateBatchEmbeddings generates embeddings for multiple texts
-func (s *EmbeddingService) GenerateBatchEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
-	if len(texts) == 0 {
-		return [][]float32{}, nil
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
 	}
 
-	switch s.config.Service {
-	case "sentence-transformers":
-		return s.generateBatchSentenceTransformersEmbeddings(ctx, texts)
-	default:
-		// Fallback to individual calls
-		embeddings := make([][]float32, len(texts))
-		for i, text := range texts {
-			embedding, err := s.GenerateEmbedding(ctx, text)
-			if err != nil {
-				return nil, fmt.Errorf("failed to generate embedding for text %d: %w", i, err)
-			}
-			embeddings[i] = embedding
-		}
-		return embeddings, nil
-	}
-}
-
-// generateOpenAIEmbedding generates embedding using OpenAI API
-func (s *EmbeddingService) generateOpenAIEmbedding(ctx context.Context, text string) ([]float32, error) {
-	request := EmbeddingRequest{
-		Input: text,
-		Model: "text-embedding-ada-002", // Default OpenAI embedding model
-	}
-
-	requestBody, err := json.Marshal(request)
+	provider, err := factory(cfg, &http.Client{Timeout: timeout}, logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to create %s embedding provider: %w", cfg.Service, err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST",
-		s.baseURL+"/embeddings", bytes.NewBuffer(requestBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+getOpenAIKey())
-
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("OpenAI API error: %d - %s", resp.StatusCode, string(body))
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
+	return &EmbeddingService{
+		config:    cfg,
+		logger:    logger,
+		provider:  provider,
+		dimension: cfg.Dimension,
+	}, nil
+}
 
-	var response EmbeddingResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+// EmbeddingProviderNames returns the names of the registered embedding providers
+func EmbeddingProviderNames() []string {
+	names := make([]string, 0, len(embeddingProviders))
+	for name := range embeddingProviders {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names
+}
 
-	if len(response.Data) == 0 {
-		return nil, fmt.Errorf("no embeddings in response")
+// Info returns the configured model and the expected vector dimension,
+// embedding a probe text to learn the dimension if it is not configured
+func (s *EmbeddingService) Info(ctx context.Context) (EmbeddingInfo, error) {
+	s.mu.Lock()
+	dimension := s.dimension
+	s.mu.Unlock()
+
+	if dimension == 0 {
+		embedding, err := s.GenerateEmbedding(ctx, "dimension probe")
+		if err != nil {
+			return EmbeddingInfo{}, fmt.Errorf("failed to probe embedding dimension: %w", err)
+		}
+		dimension = len(embedding)
 	}
 
-	s.logger.Debug("OpenAI embedding generated",
-		zap.Int("prompt_tokens", response.Usage.PromptTokens),
-		zap.Int("embedding_dim", len(response.Data[0].Embedding)))
-
-	return response.Data[0].Embedding, nil
+	return EmbeddingInfo{Model: s.config.Model, Dimension: dimension}, nil
 }
 
-// generateSentenceTransformersEmbedding generates embedding using sentence-transformers
-func (s *EmbeddingService) generateSentenceTransformersEmbedding(ctx context.Context, text string) ([]float32, error) {
-	embeddings, err := s.generateBatchSentenceTransformersEmbeddings(ctx, []string{text})
+// GenerateEmbedding generates an embedding for the given text
+func (s *EmbeddingService) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := s.GenerateBatchEmbeddings(ctx, []string{text})
 	if err != nil {
 		return nil, err
 	}
-	if len(embeddings) == 0 {
-		return nil, fmt.Errorf("no embeddings returned")
-	}
 	return embeddings[0], nil
 }
 
-// generateBatchSentenceTransformersEmbeddings generates batch embeddings using sentence-transformers
-func (s *EmbeddingService) generateBatchSentenceTransformersEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
-	request := SentenceTransformersRequest{
-		Sentences: texts,
-		Model:     s.config.Model,
-	}
-
-	requestBody, err := json.Marshal(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+// GenerateBatchEmbeddings generates embeddings for multiple texts, splitting
+// them into requests of the configured batch size
+func (s *EmbeddingService) GenerateBatchEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return [][]float32{}, nil
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST",
-		s.baseURL+"/embeddings", bytes.NewBuffer(requestBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	batchSize := s.config.BatchSize
+	if batchSize <= 0 {
+		batchSize = 32
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	embeddings := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += batchSize {
+		end := start + batchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
 
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
-	}
-	defer resp.Body.Close()
+		batch, err := s.provider.Embed(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Sentence-Transformers API error: %d - %s", resp.StatusCode, string(body))
-	}
+		if len(batch) != end-start {
+			return nil, fmt.Errorf("%s returned %d embeddings for %d texts", s.config.Service, len(batch), end-start)
+		}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
+		for _, embedding := range batch {
+			if err := s.checkDimension(embedding); err != nil {
+				return nil, err
+			}
+		}
 
-	var response SentenceTransformersResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		embeddings = append(embeddings, batch...)
 	}
 
-	s.logger.Debug("Sentence-Transformers embeddings generated",
-		zap.Int("count", len(response.Embeddings)),
-		zap.Int("embedding_dim", len(response.Embeddings[0])))
+	s.logger.Debug("Embeddings generated",
+		zap.String("service", s.config.Service),
+		zap.Int("count", len(embeddings)),
+		zap.Int("embedding_dim", len(embeddings[0])))
 
-	return response.Embeddings, nil
+	return embeddings, nil
 }
 
-// generateFallbackEmbedding generates a simple hash-based embedding as fallback
-func (s *EmbeddingService) generateFallbackEmbedding(text string) []float32 {
-	s.logger.Warn("Using fallback embedding generation")
+// checkDimension rejects vectors whose dimension differs from the expected one,
+// learning the dimension from the first vector when none is configured
+func (s *EmbeddingService) checkDimension(embedding []float32) error {
+	if len(embedding) == 0 {
+		return fmt.Errorf("%s returned an empty embedding", s.config.Service)
+	}
 
-	// Simple hash-based embedding (same as before)
-	embedding := make([]float32, 384) // Common embedding dimension
-	hash := simpleHash(text)
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	for i := range embedding {
-		embedding[i] = float32((hash >> (i % 32)) & 1)
+	if s.dimension == 0 {
+		s.dimension = len(embedding)
+		return nil
 	}
 
-	return embedding
-}
+	if len(embedding) != s.dimension {
+		return fmt.Errorf("%w: %s model %s produced %d-dimensional vectors, expected %d",
+			ErrEmbeddingMismatch, s.config.Service, s.config.Model, len(embedding), s.dimension)
+	}
 
-// getOpenAIKey retrieves OpenAI API key from environment
-func getOpenAIKey() string {
-	// This should be injected via configuration in production
-	return "your-openai-key" // Placeholder
+	return nil
 }
 
-// simpleHash creates a simple hash of the input string
-func simpleHash(s string) uint64 {
-	var hash uint64 = 5381
-	for _, c := range s {
-		hash = ((hash << 5) + hash) + uint64(c)
+// CheckEmbeddingCompatibility compares the embedding model and dimension in use
+// against those recorded for the stored vectors
+func CheckEmbeddingCompatibility(recorded, current EmbeddingInfo) error {
+	if recorded.Dimension != 0 && recorded.Dimension != current.Dimension {
+		return fmt.Errorf("%w: stored vectors are %d-dimensional but %s produces %d-dimensional vectors; reindex the collection",
+			ErrEmbeddingMismatch, recorded.Dimension, current.Model, current.Dimension)
 	}
-	return hash
-}
 
-// getEnvString retrieves environment variable with default
-func getEnvString(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+	if recorded.Model != "" && recorded.Model != current.Model {
+		return fmt.Errorf("%w: stored vectors were embedded with %s but the configured model is %s; reindex the collection",
+			ErrEmbeddingMismatch, recorded.Model, current.Model)
 	}
-	return defaultValue
+
+	return nil
 }
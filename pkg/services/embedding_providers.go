@@ -0,0 +1,230 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/amem/mcp-server/pkg/config"
+	"go.uber.org/zap"
+)
+
+// openAIEmbeddingProvider calls an OpenAI-compatible /embeddings endpoint
+type openAIEmbeddingProvider struct {
+	model      string
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+	logger     *zap.Logger
+}
+
+// EmbeddingRequest represents a request to an OpenAI-compatible embedding API
+type EmbeddingRequest struct {
+	Input []string `json:"input"`
+	Model string   `json:"model"`
+}
+
+// EmbeddingResponse represents a response from an OpenAI-compatible embedding API
+type EmbeddingResponse struct {
+	Object string `json:"object"`
+	Data   []struct {
+		Object    string    `json:"object"`
+		Embedding []float32 `json:"embedding"`
+		Index     int       `json:"index"`
+	} `json:"data"`
+	Model string `json:"model"`
+	Usage struct {
+		PromptTokens int `json:"prompt_tokens"`
+		TotalTokens  int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+const openAIBaseURL = "https://api.openai.com/v1"
+
+func newOpenAIEmbeddingProvider(cfg config.EmbeddingConfig, httpClient *http.Client, logger *zap.Logger) (EmbeddingProvider, error) {
+	baseURL := strings.TrimSuffix(cfg.URL, "/")
+	if baseURL == "" {
+		baseURL = openAIBaseURL
+	}
+
+	apiKey := cfg.APIKey
+	if apiKey == "" {
+		apiKey = os.Getenv("OPENAI_API_KEY")
+	}
+
+	// Self-hosted OpenAI-compatible servers often need no key, OpenAI itself does
+	if apiKey == "" && baseURL == openAIBaseURL {
+		return nil, fmt.Errorf("an API key is required for OpenAI embeddings (set EMBEDDING_API_KEY or OPENAI_API_KEY)")
+	}
+
+	if cfg.Model == "" {
+		return nil, fmt.Errorf("an embedding model is required")
+	}
+
+	return &openAIEmbeddingProvider{
+		model:      cfg.Model,
+		apiKey:     apiKey,
+		baseURL:    baseURL,
+		httpClient: httpClient,
+		logger:     logger,
+	}, nil
+}
+
+func (p *openAIEmbeddingProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	headers := map[string]string{}
+	if p.apiKey != "" {
+		headers["Authorization"] = "Bearer " + p.apiKey
+	}
+
+	var response EmbeddingResponse
+	request := EmbeddingRequest{Input: texts, Model: p.model}
+	if err := postEmbeddingJSON(ctx, p.httpClient, p.baseURL+"/embeddings", headers, request, &response); err != nil {
+		return nil, fmt.Errorf("OpenAI embedding request failed: %w", err)
+	}
+
+	// The API may return items out of order, so place them by index
+	embeddings := make([][]float32, len(texts))
+	for _, item := range response.Data {
+		if item.Index < 0 || item.Index >= len(embeddings) {
+			return nil, fmt.Errorf("OpenAI embedding response has out of range index %d", item.Index)
+		}
+		embeddings[item.Index] = item.Embedding
+	}
+
+	p.logger.Debug("OpenAI embeddings generated",
+		zap.Int("prompt_tokens", response.Usage.PromptTokens),
+		zap.Int("count", len(response.Data)))
+
+	return embeddings, nil
+}
+
+// sentenceTransformersProvider calls the bundled sentence-transformers service
+type sentenceTransformersProvider struct {
+	model      string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// SentenceTransformersRequest for local sentence-transformers service
+type SentenceTransformersRequest struct {
+	Sentences []string `json:"sentences"`
+	Model     string   `json:"model,omitempty"`
+}
+
+// SentenceTransformersResponse from local sentence-transformers service
+type SentenceTransformersResponse struct {
+	Embeddings [][]float32 `json:"embeddings"`
+}
+
+func newSentenceTransformersProvider(cfg config.EmbeddingConfig, httpClient *http.Client, logger *zap.Logger) (EmbeddingProvider, error) {
+	baseURL := strings.TrimSuffix(cfg.URL, "/")
+	if baseURL == "" {
+		baseURL = "http://localhost:8005"
+	}
+
+	return &sentenceTransformersProvider{
+		model:      cfg.Model,
+		baseURL:    baseURL,
+		httpClient: httpClient,
+	}, nil
+}
+
+func (p *sentenceTransformersProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	var response SentenceTransformersResponse
+	request := SentenceTransformersRequest{Sentences: texts, Model: p.model}
+	if err := postEmbeddingJSON(ctx, p.httpClient, p.baseURL+"/embeddings", nil, request, &response); err != nil {
+		return nil, fmt.Errorf("Sentence-Transformers embedding request failed: %w", err)
+	}
+
+	return response.Embeddings, nil
+}
+
+// ollamaEmbeddingProvider calls an Ollama-style local /api/embed endpoint
+type ollamaEmbeddingProvider struct {
+	model      string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// OllamaEmbedRequest represents a request to Ollama's embed API
+type OllamaEmbedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// OllamaEmbedResponse represents a response from Ollama's embed API
+type OllamaEmbedResponse struct {
+	Model      string      `json:"model"`
+	Embeddings [][]float32 `json:"embeddings"`
+}
+
+func newOllamaEmbeddingProvider(cfg config.EmbeddingConfig, httpClient *http.Client, logger *zap.Logger) (EmbeddingProvider, error) {
+	baseURL := strings.TrimSuffix(cfg.URL, "/")
+	if baseURL == "" {
+		baseURL = "http://localhost:11434"
+	}
+
+	if cfg.Model == "" {
+		return nil, fmt.Errorf("an embedding model is required")
+	}
+
+	return &ollamaEmbeddingProvider{
+		model:      cfg.Model,
+		baseURL:    baseURL,
+		httpClient: httpClient,
+	}, nil
+}
+
+func (p *ollamaEmbeddingProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	var response OllamaEmbedResponse
+	request := OllamaEmbedRequest{Model: p.model, Input: texts}
+	if err := postEmbeddingJSON(ctx, p.httpClient, p.baseURL+"/api/embed", nil, request, &response); err != nil {
+		return nil, fmt.Errorf("Ollama embedding request failed: %w", err)
+	}
+
+	return response.Embeddings, nil
+}
+
+// postEmbeddingJSON POSTs a JSON payload and decodes the JSON response into out
+func postEmbeddingJSON(ctx context.Context, httpClient *http.Client, url string, headers map[string]string, payload, out interface{}) error {
+	requestBody, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("API error: %d - %s", resp.StatusCode, string(body))
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return nil
+}
@@ -28,6 +28,7 @@ type LiteLLMRequest struct {
 	Messages    []Message `json:"messages"`
 	Temperature float32   `json:"temperature,omitempty"`
 	MaxTokens   int       `json:"max_tokens,omitempty"`
+	TopP        float32   `json:"top_p,omitempty"`
 }
 
 // Message represents a chat message
@@ -72,12 +73,29 @@ func NewLiteLLMService(cfg config.LiteLLMConfig, logger *zap.Logger) *LiteLLMSer
 	}
 }
 
-// CallWithRetry calls LiteLLM with retry logic
+// defaultModelConfig is used for calls without a prompt template and fills in
+// settings a template leaves unset
+var defaultModelConfig = ModelConfig{Temperature: 0.1, MaxTokens: 1000}
+
+// CallWithRetry calls LiteLLM with retry logic using the default model settings
 func (s *LiteLLMService) CallWithRetry(ctx context.Context, prompt string, retryOnJSON bool) (string, error) {
+	return s.CallWithConfig(ctx, prompt, defaultModelConfig, retryOnJSON)
+}
+
+// CallWithConfig calls LiteLLM with retry logic, using the sampling settings
+// of a prompt template
+func (s *LiteLLMService) CallWithConfig(ctx context.Context, prompt string, modelConfig ModelConfig, retryOnJSON bool) (string, error) {
+	if modelConfig.Temperature <= 0 {
+		modelConfig.Temperature = defaultModelConfig.Temperature
+	}
+	if modelConfig.MaxTokens <= 0 {
+		modelConfig.MaxTokens = defaultModelConfig.MaxTokens
+	}
+
 	var lastErr error
 
 	for i := 0; i < s.config.MaxRetries; i++ {
-		response, err := s.call(ctx, prompt, s.config.DefaultModel)
+		response, err := s.call(ctx, prompt, s.config.DefaultModel, modelConfig)
 		if err != nil {
 			lastErr = err
 			s.logger.Warn("LiteLLM call failed, retrying",
@@ -109,7 +127,7 @@ func (s *LiteLLMService) CallWithRetry(ctx context.Context, prompt string, retry
 	// Try fallback models
 	for _, model := range s.config.FallbackModels {
 		s.logger.Info("Trying fallback model", zap.String("model", model))
-		response, err := s.call(ctx, prompt, model)
+		response, err := s.call(ctx, prompt, model, modelConfig)
 		if err != nil {
 			s.logger.Warn("Fallback model failed",
 				zap.String("model", model),
@@ -133,7 +151,7 @@ func (s *LiteLLMService) CallWithRetry(ctx context.Context, prompt string, retry
 }
 
 // call makes a single call to LiteLLM
-func (s *LiteLLMService) call(ctx context.Context, prompt, model string) (string, error) {
+func (s *LiteLLMService) call(ctx context.Context, prompt, model string, modelConfig ModelConfig) (string, error) {
 	request := LiteLLMRequest{
 		Model: model,
 		Messages: []Message{
@@ -142,8 +160,9 @@ func (s *LiteLLMService) call(ctx context.Context, prompt, model string) (string
 				Content: prompt,
 			},
 		},
-		Temperature: 0.1,
-		MaxTokens:   1000,
+		Temperature: modelConfig.Temperature,
+		MaxTokens:   modelConfig.MaxTokens,
+		TopP:        modelConfig.TopP,
 	}
 
 	requestBody, err := json.Marshal(request)
@@ -198,27 +217,3 @@ func (s *LiteLLMService) call(ctx context.Context, prompt, model string) (string
 
 	return response.Choices[0].Message.Content, nil
 }
-
-// GenerateEmbedding generates an embedding for the given text
-func (s *LiteLLMService) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
-	// For now, we'll use a simple hash-based embedding
-	// In production, this should call an actual embedding service
-	s.logger.Debug("Generating embedding", zap.String("text", text[:min(100, len(text))]))
-
-	// Simple hash-based embedding (placeholder)
-	embedding := make([]float32, 384) // Common embedding dimension
-	hash := simpleHash(text)
-
-	for i := range embedding {
-		embedding[i] = float32((hash >> (i % 32)) & 1)
-	}
-
-	return embedding, nil
-}
-
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
@@ -0,0 +1,678 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amem/mcp-server/pkg/config"
+	"github.com/amem/mcp-server/pkg/models"
+	"go.uber.org/zap"
+)
+
+// LocalStore is an embedded MemoryStore that keeps every memory in memory,
+// persists them to a single JSON file and answers similarity queries by
+// brute force. It is meant for single-user setups without a ChromaDB server.
+type LocalStore struct {
+	config    config.StorageConfig
+	metric    string
+	logger    *zap.Logger
+	mu        sync.RWMutex
+	memories  map[string]*models.Memory
+	order     []string // Insertion order, mirroring ChromaDB's get ordering
+	embedding EmbeddingInfo
+}
+
+// localStoreFile is the on-disk layout of the local store
+type localStoreFile struct {
+	Version            int              `json:"version"`
+	EmbeddingModel     string           `json:"embedding_model,omitempty"`
+	EmbeddingDimension int              `json:"embedding_dimension,omitempty"`
+	Memories           []*models.Memory `json:"memories"`
+}
+
+const localStoreVersion = 1
+
+// NewLocalStore creates a new embedded local store
+func NewLocalStore(cfg config.StorageConfig, metric string, logger *zap.Logger) *LocalStore {
+	return &LocalStore{
+		config:   cfg,
+		metric:   configuredMetric(metric),
+		logger:   logger,
+		memories: make(map[string]*models.Memory),
+	}
+}
+
+// Initialize loads the data file, creating its directory if needed, and checks
+// that its vectors come from the given embedding model
+func (l *LocalStore) Initialize(ctx context.Context, embedding EmbeddingInfo) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.embedding = embedding
+
+	if err := os.MkdirAll(filepath.Dir(l.config.Path), 0o755); err != nil {
+		return fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	file, err := readLocalStoreFile(l.config.Path)
+	if err != nil {
+		return err
+	}
+
+	recorded := EmbeddingInfo{Model: file.EmbeddingModel, Dimension: file.EmbeddingDimension}
+	if recorded == (EmbeddingInfo{}) && len(file.Memories) > 0 {
+		l.logger.Warn("Storage file does not record its embedding model; assuming the configured one",
+			zap.String("path", l.config.Path))
+	} else if embedding == (EmbeddingInfo{}) {
+		// A zero EmbeddingInfo means the caller only reads stored content
+		l.embedding = recorded
+	} else if err := CheckEmbeddingCompatibility(recorded, embedding); err != nil {
+		return fmt.Errorf("storage file %s: %w", l.config.Path, err)
+	}
+
+	l.load(file)
+
+	l.logger.Info("Local store initialized",
+		zap.String("path", l.config.Path),
+		zap.Int("memories", len(l.memories)))
+
+	return nil
+}
+
+// readLocalStoreFile parses a data file, treating a missing file as empty
+func readLocalStoreFile(path string) (*localStoreFile, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &localStoreFile{Version: localStoreVersion}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read storage file: %w", err)
+	}
+
+	var file localStoreFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse storage file: %w", err)
+	}
+
+	return &file, nil
+}
+
+// load replaces the in-memory contents. Callers must hold the write lock.
+func (l *LocalStore) load(file *localStoreFile) {
+	l.memories = make(map[string]*models.Memory, len(file.Memories))
+	l.order = make([]string, 0, len(file.Memories))
+	for _, memory := range file.Memories {
+		l.memories[memory.ID] = memory
+		l.order = append(l.order, memory.ID)
+	}
+}
+
+// stagingPath returns the data file used for a named staging area
+func (l *LocalStore) stagingPath(name string) string {
+	ext := filepath.Ext(l.config.Path)
+	return strings.TrimSuffix(l.config.Path, ext) + "." + name + ext
+}
+
+// OpenStaging creates or reopens a staging data file next to the live one
+func (l *LocalStore) OpenStaging(ctx context.Context, name string, embedding EmbeddingInfo) (MemoryStore, error) {
+	cfg := l.config
+	cfg.Path = l.stagingPath(name)
+
+	staging := NewLocalStore(cfg, l.metric, l.logger.With(zap.String("staging_path", cfg.Path)))
+	if err := staging.Initialize(ctx, embedding); err != nil {
+		return nil, fmt.Errorf("failed to open staging file: %w", err)
+	}
+
+	// Persist immediately so the staging file exists even if nothing is written
+	staging.mu.Lock()
+	defer staging.mu.Unlock()
+	if err := staging.persist(); err != nil {
+		return nil, err
+	}
+
+	return staging, nil
+}
+
+// PromoteStaging moves the live data file to a timestamped backup, moves the
+// staging file into its place and reloads it
+func (l *LocalStore) PromoteStaging(ctx context.Context, name string) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stagingPath := l.stagingPath(name)
+	file, err := readLocalStoreFile(stagingPath)
+	if err != nil {
+		return "", err
+	}
+
+	backup := l.config.Path + ".backup-" + time.Now().UTC().Format("20060102T150405")
+	if err := os.Rename(l.config.Path, backup); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to back up storage file: %w", err)
+	}
+
+	if err := os.Rename(stagingPath, l.config.Path); err != nil {
+		if rollbackErr := os.Rename(backup, l.config.Path); rollbackErr != nil {
+			l.logger.Error("Failed to restore storage file", zap.String("backup", backup), zap.Error(rollbackErr))
+		}
+		return "", fmt.Errorf("failed to promote staging file: %w", err)
+	}
+
+	l.embedding = EmbeddingInfo{Model: file.EmbeddingModel, Dimension: file.EmbeddingDimension}
+	l.load(file)
+
+	l.logger.Info("Staging file promoted",
+		zap.String("path", l.config.Path),
+		zap.String("backup", backup),
+		zap.Int("memories", len(l.memories)))
+
+	return backup, nil
+}
+
+// StoreMemory adds a new memory
+func (l *LocalStore) StoreMemory(ctx context.Context, memory *models.Memory) error {
+	return l.write(memory, func(exists bool) error {
+		if exists {
+			return fmt.Errorf("memory %s already exists", memory.ID)
+		}
+		return nil
+	})
+}
+
+// StoreMemories adds several new memories, persisting the store once
+func (l *LocalStore) StoreMemories(ctx context.Context, memories []*models.Memory) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Check every memory first so a failure leaves the store unchanged
+	seen := make(map[string]bool, len(memories))
+	for _, memory := range memories {
+		if len(memory.Embedding) == 0 {
+			return fmt.Errorf("memory embedding is required")
+		}
+		if _, exists := l.memories[memory.ID]; exists || seen[memory.ID] {
+			return fmt.Errorf("memory %s already exists", memory.ID)
+		}
+		seen[memory.ID] = true
+	}
+
+	orderLen := len(l.order)
+	for _, memory := range memories {
+		l.order = append(l.order, memory.ID)
+		l.memories[memory.ID] = cloneMemory(memory, true)
+	}
+
+	if err := l.persist(); err != nil {
+		l.order = l.order[:orderLen]
+		for _, memory := range memories {
+			delete(l.memories, memory.ID)
+		}
+		return err
+	}
+	return nil
+}
+
+// UpdateMemory overwrites an existing memory
+func (l *LocalStore) UpdateMemory(ctx context.Context, memory *models.Memory) error {
+	return l.write(memory, func(exists bool) error {
+		if !exists {
+			return fmt.Errorf("%w: %s", ErrMemoryNotFound, memory.ID)
+		}
+		return nil
+	})
+}
+
+// UpdateMemories overwrites several existing memories, persisting the store once
+func (l *LocalStore) UpdateMemories(ctx context.Context, memories []*models.Memory) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Check every memory first so a failure leaves the store unchanged
+	for _, memory := range memories {
+		if len(memory.Embedding) == 0 {
+			return fmt.Errorf("memory embedding is required")
+		}
+		if _, exists := l.memories[memory.ID]; !exists {
+			return fmt.Errorf("%w: %s", ErrMemoryNotFound, memory.ID)
+		}
+	}
+
+	previous := make(map[string]*models.Memory, len(memories))
+	for _, memory := range memories {
+		if _, saved := previous[memory.ID]; !saved {
+			previous[memory.ID] = l.memories[memory.ID]
+		}
+		l.memories[memory.ID] = cloneMemory(memory, true)
+	}
+
+	if err := l.persist(); err != nil {
+		for id, memory := range previous {
+			l.memories[id] = memory
+		}
+		return err
+	}
+	return nil
+}
+
+// UpsertMemory stores a memory, replacing any existing memory with the same ID
+func (l *LocalStore) UpsertMemory(ctx context.Context, memory *models.Memory) error {
+	return l.write(memory, func(bool) error { return nil })
+}
+
+// write stores a copy of memory after check approves it, then persists the store
+func (l *LocalStore) write(memory *models.Memory, check func(exists bool) error) error {
+	if len(memory.Embedding) == 0 {
+		return fmt.Errorf("memory embedding is required")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	previous, exists := l.memories[memory.ID]
+	if err := check(exists); err != nil {
+		return err
+	}
+
+	if !exists {
+		l.order = append(l.order, memory.ID)
+	}
+	l.memories[memory.ID] = cloneMemory(memory, true)
+
+	if err := l.persist(); err != nil {
+		if exists {
+			l.memories[memory.ID] = previous
+		} else {
+			l.order = l.order[:len(l.order)-1]
+			delete(l.memories, memory.ID)
+		}
+		return err
+	}
+	return nil
+}
+
+// GetMemory fetches a single memory, including its embedding, by ID
+func (l *LocalStore) GetMemory(ctx context.Context, id string) (*models.Memory, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	memory, ok := l.memories[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrMemoryNotFound, id)
+	}
+
+	return cloneMemory(memory, true), nil
+}
+
+// GetMemories fetches memories, including their embeddings, by ID. IDs that do
+// not exist are omitted from the result.
+func (l *LocalStore) GetMemories(ctx context.Context, ids []string) ([]*models.Memory, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	memories := make([]*models.Memory, 0, len(ids))
+	for _, id := range ids {
+		if memory, ok := l.memories[id]; ok {
+			memories = append(memories, cloneMemory(memory, true))
+		}
+	}
+
+	return memories, nil
+}
+
+// DeleteMemories removes memories by ID
+func (l *LocalStore) DeleteMemories(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	deleted := make(map[string]*models.Memory, len(ids))
+	for _, id := range ids {
+		if memory, ok := l.memories[id]; ok {
+			delete(l.memories, id)
+			deleted[id] = memory
+		}
+	}
+
+	previousOrder := l.order
+	order := make([]string, 0, len(l.order))
+	for _, id := range l.order {
+		if _, ok := deleted[id]; !ok {
+			order = append(order, id)
+		}
+	}
+	l.order = order
+
+	if err := l.persist(); err != nil {
+		l.order = previousOrder
+		for id, memory := range deleted {
+			l.memories[id] = memory
+		}
+		return err
+	}
+	return nil
+}
+
+// DistanceMetric returns the metric SearchSimilar distances are reported in
+func (l *LocalStore) DistanceMetric() string {
+	return l.metric
+}
+
+// SearchSimilar returns the memories nearest to an embedding, computing
+// distances the same way ChromaDB does for the configured metric
+func (l *LocalStore) SearchSimilar(ctx context.Context, queryEmbedding []float32, limit int, filters map[string]interface{}) ([]*models.Memory, []float32, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	type candidate struct {
+		memory   *models.Memory
+		distance float32
+	}
+
+	candidates := make([]candidate, 0, len(l.memories))
+	for _, id := range l.order {
+		memory := l.memories[id]
+		if !matchesWhere(memoryToMetadata(memory), filters) {
+			continue
+		}
+
+		if len(memory.Embedding) != len(queryEmbedding) {
+			return nil, nil, fmt.Errorf("embedding dimension %d does not match stored dimension %d",
+				len(queryEmbedding), len(memory.Embedding))
+		}
+
+		distance := VectorDistance(l.metric, queryEmbedding, memory.Embedding)
+
+		candidates = append(candidates, candidate{memory: memory, distance: distance})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+
+	memories := make([]*models.Memory, 0, len(candidates))
+	distances := make([]float32, 0, len(candidates))
+	for _, c := range candidates {
+		memories = append(memories, cloneMemory(c.memory, false))
+		distances = append(distances, c.distance)
+	}
+
+	l.logger.Debug("Local store search completed",
+		zap.Int("results", len(memories)))
+
+	return memories, distances, nil
+}
+
+// ListMemories fetches one page of memories matching a filter, without embeddings
+func (l *LocalStore) ListMemories(ctx context.Context, where map[string]interface{}, limit, offset int) ([]*models.Memory, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	memories := make([]*models.Memory, 0)
+	skipped := 0
+	for _, id := range l.order {
+		if limit > 0 && len(memories) >= limit {
+			break
+		}
+
+		memory := l.memories[id]
+		if !matchesWhere(memoryToMetadata(memory), where) {
+			continue
+		}
+
+		if skipped < offset {
+			skipped++
+			continue
+		}
+
+		memories = append(memories, cloneMemory(memory, false))
+	}
+
+	return memories, nil
+}
+
+// ListAllMemories fetches every memory matching a filter, without embeddings
+func (l *LocalStore) ListAllMemories(ctx context.Context, where map[string]interface{}) ([]*models.Memory, error) {
+	return l.ListMemories(ctx, where, 0, 0)
+}
+
+// ScanMemories calls visit with every memory matching a filter, without
+// content or embeddings
+func (l *LocalStore) ScanMemories(ctx context.Context, where map[string]interface{}, visit func(*models.Memory) error) error {
+	// Visit a snapshot so visit may call back into the store
+	l.mu.RLock()
+	var matched []*models.Memory
+	for _, id := range l.order {
+		memory := l.memories[id]
+		if matchesWhere(memoryToMetadata(memory), where) {
+			clone := cloneMemory(memory, false)
+			clone.Content = ""
+			matched = append(matched, clone)
+		}
+	}
+	l.mu.RUnlock()
+
+	for _, memory := range matched {
+		if err := visit(memory); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CountMemories counts the memories matching a filter
+func (l *LocalStore) CountMemories(ctx context.Context, where map[string]interface{}) (int, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if len(where) == 0 {
+		return len(l.order), nil
+	}
+
+	count := 0
+	for _, id := range l.order {
+		if matchesWhere(memoryToMetadata(l.memories[id]), where) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// persist atomically rewrites the data file. Callers must hold the write lock.
+func (l *LocalStore) persist() error {
+	file := localStoreFile{
+		Version:            localStoreVersion,
+		EmbeddingModel:     l.embedding.Model,
+		EmbeddingDimension: l.embedding.Dimension,
+		Memories:           make([]*models.Memory, 0, len(l.order)),
+	}
+	for _, id := range l.order {
+		file.Memories = append(file.Memories, l.memories[id])
+	}
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("failed to marshal storage file: %w", err)
+	}
+
+	tmpPath := l.config.Path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write storage file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, l.config.Path); err != nil {
+		return fmt.Errorf("failed to replace storage file: %w", err)
+	}
+
+	return nil
+}
+
+// cloneMemory copies a memory so callers cannot mutate the store's state
+func cloneMemory(memory *models.Memory, withEmbedding bool) *models.Memory {
+	clone := *memory
+	clone.Keywords = append([]string(nil), memory.Keywords...)
+	clone.Tags = append([]string(nil), memory.Tags...)
+	clone.Links = append([]models.MemoryLink(nil), memory.Links...)
+
+	clone.Embedding = nil
+	if withEmbedding {
+		clone.Embedding = append([]float32(nil), memory.Embedding...)
+	}
+
+	clone.Metadata = make(map[string]interface{}, len(memory.Metadata))
+	for k, v := range memory.Metadata {
+		clone.Metadata[k] = v
+	}
+
+	return &clone
+}
+
+// matchesWhere evaluates a ChromaDB where filter against flattened metadata.
+// It supports $and, $or and the $eq, $ne, $gt, $gte, $lt, $lte, $in and $nin operators.
+func matchesWhere(metadata map[string]interface{}, where map[string]interface{}) bool {
+	for key, condition := range where {
+		switch key {
+		case "$and":
+			for _, clause := range toSlice(condition) {
+				sub, ok := clause.(map[string]interface{})
+				if !ok || !matchesWhere(metadata, sub) {
+					return false
+				}
+			}
+		case "$or":
+			matched := false
+			for _, clause := range toSlice(condition) {
+				if sub, ok := clause.(map[string]interface{}); ok && matchesWhere(metadata, sub) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return false
+			}
+		default:
+			if !matchesCondition(metadata[key], condition) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// matchesCondition evaluates a single field condition, either a literal value
+// or a map of operators
+func matchesCondition(value interface{}, condition interface{}) bool {
+	operators, ok := condition.(map[string]interface{})
+	if !ok {
+		return valuesEqual(value, condition)
+	}
+
+	for op, operand := range operators {
+		var matched bool
+		switch op {
+		case "$eq":
+			matched = valuesEqual(value, operand)
+		case "$ne":
+			matched = !valuesEqual(value, operand)
+		case "$gt", "$gte", "$lt", "$lte":
+			a, okA := toFloat(value)
+			b, okB := toFloat(operand)
+			if !okA || !okB {
+				return false
+			}
+			switch op {
+			case "$gt":
+				matched = a > b
+			case "$gte":
+				matched = a >= b
+			case "$lt":
+				matched = a < b
+			case "$lte":
+				matched = a <= b
+			}
+		case "$in", "$nin":
+			for _, candidate := range toSlice(operand) {
+				if valuesEqual(value, candidate) {
+					matched = true
+					break
+				}
+			}
+			if op == "$nin" {
+				matched = !matched
+			}
+		default:
+			return false
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// valuesEqual compares metadata values, treating all numeric types alike.
+// Decoded JSON metadata may hold slices and maps, which cannot be compared
+// with ==.
+func valuesEqual(a, b interface{}) bool {
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		return ok && fa == fb
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// toFloat converts any numeric metadata value to float64
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+// toSlice converts any slice operand, such as []string or []interface{}, to []interface{}
+func toSlice(v interface{}) []interface{} {
+	if items, ok := v.([]interface{}); ok {
+		return items
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return nil
+	}
+
+	items := make([]interface{}, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+
+	return items
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/amem/mcp-server/pkg/config"
+	"github.com/amem/mcp-server/pkg/models"
+	"go.uber.org/zap"
+)
+
+var testEmbedding = EmbeddingInfo{Model: "test-model", Dimension: 2}
+
+func newTestLocalStore(t *testing.T, path string) *LocalStore {
+	t.Helper()
+
+	store := NewLocalStore(config.StorageConfig{Backend: "local", Path: path}, DistanceCosine, zap.NewNop())
+	if err := store.Initialize(context.Background(), testEmbedding); err != nil {
+		t.Fatalf("Failed to initialize local store: %v", err)
+	}
+
+	return store
+}
+
+func TestLocalStorePersistsAcrossRestarts(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "memories.json")
+
+	store := newTestLocalStore(t, path)
+	memory := &models.Memory{
+		ID:          "memory-1",
+		Content:     "func main() {}",
+		WorkspaceID: "project",
+		Embedding:   []float32{1, 0},
+		Links:       []models.MemoryLink{{TargetID: "memory-2", LinkType: "pattern", Strength: 0.9}},
+	}
+
+	if err := store.StoreMemory(ctx, memory); err != nil {
+		t.Fatalf("Failed to store memory: %v", err)
+	}
+
+	if err := store.StoreMemory(ctx, memory); err == nil {
+		t.Error("Expected storing a duplicate ID to fail")
+	}
+
+	reopened := newTestLocalStore(t, path)
+	restored, err := reopened.GetMemory(ctx, "memory-1")
+	if err != nil {
+		t.Fatalf("Failed to get memory after reopening: %v", err)
+	}
+
+	if restored.Content != memory.Content || len(restored.Links) != 1 || len(restored.Embedding) != 2 {
+		t.Errorf("Expected memory to round trip, got %+v", restored)
+	}
+
+	if err := reopened.DeleteMemories(ctx, []string{"memory-1"}); err != nil {
+		t.Fatalf("Failed to delete memory: %v", err)
+	}
+
+	if _, err := reopened.GetMemory(ctx, "memory-1"); !errors.Is(err, ErrMemoryNotFound) {
+		t.Errorf("Expected ErrMemoryNotFound after delete, got %v", err)
+	}
+}
+
+func TestLocalStoreSearchAndFilters(t *testing.T) {
+	ctx := context.Background()
+	store := newTestLocalStore(t, filepath.Join(t.TempDir(), "memories.json"))
+
+	now := time.Now()
+	memories := []*models.Memory{
+		{ID: "near", WorkspaceID: "a", CodeType: "go", Embedding: []float32{1, 0}, CreatedAt: now},
+		{ID: "far", WorkspaceID: "a", CodeType: "python", Embedding: []float32{-1, 0}, CreatedAt: now.Add(-48 * time.Hour)},
+		{ID: "other", WorkspaceID: "b", CodeType: "go", Embedding: []float32{1, 0.1}, CreatedAt: now},
+	}
+	for _, memory := range memories {
+		if err := store.StoreMemory(ctx, memory); err != nil {
+			t.Fatalf("Failed to store memory: %v", err)
+		}
+	}
+
+	results, distances, err := store.SearchSimilar(ctx, []float32{1, 0}, 10, map[string]interface{}{"workspace_id": "a"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+
+	if len(results) != 2 || results[0].ID != "near" || distances[0] != 0 {
+		t.Errorf("Expected 'near' first with distance 0, got %v %v", results, distances)
+	}
+
+	filtered, err := store.ListAllMemories(ctx, map[string]interface{}{
+		"$and": []map[string]interface{}{
+			{"code_type": map[string]interface{}{"$in": []string{"go"}}},
+			{"created_at": map[string]interface{}{"$gte": now.Add(-time.Hour).Unix()}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+
+	if len(filtered) != 2 {
+		t.Errorf("Expected 2 recent go memories, got %d", len(filtered))
+	}
+
+	if _, _, err := store.SearchSimilar(ctx, []float32{1, 0, 0}, 10, nil); err == nil {
+		t.Error("Expected a dimension mismatch error")
+	}
+}
+
+func TestLocalStoreFiltersOnNonScalarMetadata(t *testing.T) {
+	ctx := context.Background()
+	store := newTestLocalStore(t, filepath.Join(t.TempDir(), "memories.json"))
+
+	memory := &models.Memory{
+		ID:        "labelled",
+		Embedding: []float32{1, 0},
+		Metadata:  map[string]interface{}{"labels": []interface{}{"api", "retry"}},
+	}
+	if err := store.StoreMemory(ctx, memory); err != nil {
+		t.Fatalf("Failed to store memory: %v", err)
+	}
+
+	matched, err := store.ListAllMemories(ctx, map[string]interface{}{"labels": []interface{}{"api", "retry"}})
+	if err != nil || len(matched) != 1 {
+		t.Errorf("Expected an equal list value to match, got %v, %v", matched, err)
+	}
+
+	matched, err = store.ListAllMemories(ctx, map[string]interface{}{"labels": map[string]interface{}{"$ne": []interface{}{"api"}}})
+	if err != nil || len(matched) != 1 {
+		t.Errorf("Expected a different list value not to match, got %v, %v", matched, err)
+	}
+}
+
+func TestLocalStoreRollsBackFailedWrites(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "memories.json")
+	store := newTestLocalStore(t, path)
+
+	original := &models.Memory{ID: "kept", Content: "original", Embedding: []float32{1, 0}}
+	if err := store.StoreMemory(ctx, original); err != nil {
+		t.Fatalf("Failed to store memory: %v", err)
+	}
+
+	// A directory in the way of the temporary file makes every persist fail
+	if err := os.Mkdir(path+".tmp", 0o700); err != nil {
+		t.Fatalf("Failed to block storage file: %v", err)
+	}
+
+	if err := store.StoreMemory(ctx, &models.Memory{ID: "new", Embedding: []float32{0, 1}}); err == nil {
+		t.Error("Expected storing to fail")
+	}
+	if err := store.StoreMemories(ctx, []*models.Memory{{ID: "batch", Embedding: []float32{0, 1}}}); err == nil {
+		t.Error("Expected storing a batch to fail")
+	}
+	if err := store.UpdateMemory(ctx, &models.Memory{ID: "kept", Content: "changed", Embedding: []float32{1, 0}}); err == nil {
+		t.Error("Expected updating to fail")
+	}
+	if err := store.DeleteMemories(ctx, []string{"kept"}); err == nil {
+		t.Error("Expected deleting to fail")
+	}
+
+	count, err := store.CountMemories(ctx, nil)
+	if err != nil || count != 1 {
+		t.Errorf("Expected only the original memory to remain, got %d, %v", count, err)
+	}
+	kept, err := store.GetMemory(ctx, "kept")
+	if err != nil || kept.Content != "original" {
+		t.Errorf("Expected the original memory to be unchanged, got %+v, %v", kept, err)
+	}
+}
+
+func TestSimilarityIsConsistentAcrossMetrics(t *testing.T) {
+	a := []float32{1, 0}
+	b := []float32{0.6, 0.8} // cos(a, b) = 0.6
+
+	for _, metric := range []string{DistanceCosine, DistanceL2, DistanceIP} {
+		similarity := Similarity(metric, VectorDistance(metric, a, b))
+		if similarity < 0.599 || similarity > 0.601 {
+			t.Errorf("Expected similarity 0.6 under %s, got %f", metric, similarity)
+		}
+	}
+}
+
+func TestLocalStoreRejectsDifferentEmbeddingModel(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "memories.json")
+
+	store := newTestLocalStore(t, path)
+	if err := store.StoreMemory(ctx, &models.Memory{ID: "memory-1", Embedding: []float32{1, 0}}); err != nil {
+		t.Fatalf("Failed to store memory: %v", err)
+	}
+
+	reopened := NewLocalStore(config.StorageConfig{Backend: "local", Path: path}, DistanceCosine, zap.NewNop())
+	err := reopened.Initialize(ctx, EmbeddingInfo{Model: "other-model", Dimension: 3})
+	if !errors.Is(err, ErrEmbeddingMismatch) {
+		t.Fatalf("Expected ErrEmbeddingMismatch, got %v", err)
+	}
+}
+
+func TestLocalStorePromoteStaging(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "memories.json")
+
+	store := newTestLocalStore(t, path)
+	if err := store.StoreMemory(ctx, &models.Memory{ID: "memory-1", Embedding: []float32{1, 0}}); err != nil {
+		t.Fatalf("Failed to store memory: %v", err)
+	}
+
+	newEmbedding := EmbeddingInfo{Model: "new-model", Dimension: 3}
+	staging, err := store.OpenStaging(ctx, "reindex_new-model_3", newEmbedding)
+	if err != nil {
+		t.Fatalf("Failed to open staging: %v", err)
+	}
+	if err := staging.UpsertMemory(ctx, &models.Memory{ID: "memory-1", Embedding: []float32{0, 1, 0}}); err != nil {
+		t.Fatalf("Failed to write staged memory: %v", err)
+	}
+
+	if _, err := store.PromoteStaging(ctx, "reindex_new-model_3"); err != nil {
+		t.Fatalf("Failed to promote staging: %v", err)
+	}
+
+	promoted, err := store.GetMemory(ctx, "memory-1")
+	if err != nil {
+		t.Fatalf("Failed to get promoted memory: %v", err)
+	}
+	if len(promoted.Embedding) != 3 {
+		t.Errorf("Expected the re-embedded vector after promotion, got %v", promoted.Embedding)
+	}
+
+	// The promoted file now records the new model
+	reopened := NewLocalStore(config.StorageConfig{Backend: "local", Path: path}, DistanceCosine, zap.NewNop())
+	if err := reopened.Initialize(ctx, newEmbedding); err != nil {
+		t.Errorf("Expected promoted file to accept the new model, got %v", err)
+	}
+}
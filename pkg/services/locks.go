@@ -0,0 +1,74 @@
+package services
+
+import (
+	"sort"
+	"sync"
+)
+
+// MemoryLocks serializes read-modify-write cycles on the same memory. Requests
+// run concurrently, the scheduler evolves the network in the background and
+// workspace operations rewrite memories in bulk, so without it two writers
+// that load the same memory can each save their own change over the other's.
+// The zero value is ready to use.
+type MemoryLocks struct {
+	mu    sync.Mutex
+	locks map[string]*memoryLock
+}
+
+// memoryLock is the lock of one memory and the number of holders and waiters
+type memoryLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// Lock acquires the lock of a memory and returns the function releasing it.
+// Callers must not hold the lock of another memory; LockAll takes several at
+// once without a cycle ever waiting on itself.
+func (l *MemoryLocks) Lock(memoryID string) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*memoryLock)
+	}
+	entry, ok := l.locks[memoryID]
+	if !ok {
+		entry = &memoryLock{}
+		l.locks[memoryID] = entry
+	}
+	entry.refs++
+	l.mu.Unlock()
+
+	entry.mu.Lock()
+
+	return func() {
+		entry.mu.Unlock()
+
+		l.mu.Lock()
+		entry.refs--
+		if entry.refs == 0 {
+			delete(l.locks, memoryID)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// LockAll acquires the locks of several memories and returns the function
+// releasing them. They are taken in ID order, so two callers locking
+// overlapping sets cannot each wait on a lock the other holds.
+func (l *MemoryLocks) LockAll(memoryIDs []string) func() {
+	ids := append([]string(nil), memoryIDs...)
+	sort.Strings(ids)
+
+	unlocks := make([]func(), 0, len(ids))
+	for i, id := range ids {
+		if i > 0 && id == ids[i-1] {
+			continue
+		}
+		unlocks = append(unlocks, l.Lock(id))
+	}
+
+	return func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockAllTakesOverlappingSetsWithoutDeadlock(t *testing.T) {
+	var locks MemoryLocks
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(3)
+			go func() {
+				defer wg.Done()
+				locks.LockAll([]string{"a", "b", "c", "a"})()
+			}()
+			go func() {
+				defer wg.Done()
+				locks.LockAll([]string{"c", "b", "a"})()
+			}()
+			go func() {
+				defer wg.Done()
+				locks.Lock("b")()
+			}()
+		}
+		wg.Wait()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected overlapping LockAll calls to finish")
+	}
+
+	if len(locks.locks) != 0 {
+		t.Errorf("Expected every lock to be released, %d remain", len(locks.locks))
+	}
+}
@@ -1,15 +1,19 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"text/template"
+	"text/template/parse"
 	"time"
 
 	"github.com/amem/mcp-server/pkg/config"
+	"github.com/amem/mcp-server/pkg/models"
 	"go.uber.org/zap"
 	"gopkg.in/yaml.v3"
 )
@@ -28,9 +32,11 @@ type PromptManager struct {
 type PromptTemplate struct {
 	Name        string                 `yaml:"name"`
 	Version     string                 `yaml:"version"`
+	Description string                 `yaml:"description,omitempty"`
 	ModelConfig ModelConfig            `yaml:"model_config"`
 	Template    string                 `yaml:"template"`
-	Variables   map[string]interface{} `yaml:"variables,omitempty"`
+	Arguments   map[string]string      `yaml:"arguments,omitempty"` // Descriptions of template variables
+	Variables   map[string]interface{} `yaml:"variables,omitempty"` // Default values of template variables
 	Metadata    map[string]interface{} `yaml:"metadata,omitempty"`
 }
 
@@ -86,13 +92,14 @@ func (pm *PromptManager) LoadPrompt(name string) (*PromptTemplate, error) {
 		return nil, err
 	}
 
-	// Cache the prompt
+	// Cache the prompt, dropping any template compiled from an older version
+	pm.mu.Lock()
+	delete(pm.templates, name)
 	if pm.config.CacheEnabled {
-		pm.mu.Lock()
 		pm.cache[name] = prompt
 		pm.lastLoad = time.Now()
-		pm.mu.Unlock()
 	}
+	pm.mu.Unlock()
 
 	return prompt, nil
 }
@@ -126,6 +133,191 @@ func (pm *PromptManager) RenderPrompt(name string, data PromptData) (string, err
 	return result.String(), nil
 }
 
+// ListPromptDefinitions describes every prompt template as an MCP prompt, with
+// the template's variables as arguments
+func (pm *PromptManager) ListPromptDefinitions(ctx context.Context) ([]models.MCPPrompt, error) {
+	names, err := pm.ListPrompts()
+	if err != nil {
+		return nil, err
+	}
+
+	prompts := make([]models.MCPPrompt, 0, len(names))
+	for _, name := range names {
+		prompt, err := pm.LoadPrompt(name)
+		if err != nil {
+			// Skip broken files rather than hiding every prompt
+			pm.logger.Warn("Skipping invalid prompt", zap.String("name", name), zap.Error(err))
+			continue
+		}
+
+		tmpl, err := pm.getCompiledTemplate(name, prompt.Template)
+		if err != nil {
+			pm.logger.Warn("Skipping invalid prompt", zap.String("name", name), zap.Error(err))
+			continue
+		}
+
+		prompts = append(prompts, models.MCPPrompt{
+			Name:        name,
+			Description: promptDescription(prompt),
+			Arguments:   promptArguments(prompt, tmpl),
+		})
+	}
+
+	return prompts, nil
+}
+
+// GetPromptMessages renders a prompt template with the given argument values.
+// Arguments override the template's default variables.
+func (pm *PromptManager) GetPromptMessages(ctx context.Context, name string, args map[string]string) (*models.MCPGetPromptResult, error) {
+	if !validPromptName(name) {
+		return nil, fmt.Errorf("%w: %s", models.ErrPromptNotFound, name)
+	}
+
+	prompt, err := pm.LoadPrompt(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", models.ErrPromptNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := pm.getCompiledTemplate(name, prompt.Template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile template %s: %w", name, err)
+	}
+
+	var missing []string
+	for _, argument := range promptArguments(prompt, tmpl) {
+		if _, ok := args[argument.Name]; argument.Required && !ok {
+			missing = append(missing, argument.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s", models.ErrMissingPromptArguments, strings.Join(missing, ", "))
+	}
+
+	templateData := make(map[string]interface{}, len(prompt.Variables)+len(args))
+	for k, v := range prompt.Variables {
+		templateData[k] = v
+	}
+	for k, v := range args {
+		templateData[k] = v
+	}
+
+	var result strings.Builder
+	if err := tmpl.Execute(&result, templateData); err != nil {
+		return nil, fmt.Errorf("failed to execute template %s: %w", name, err)
+	}
+
+	return &models.MCPGetPromptResult{
+		Description: promptDescription(prompt),
+		Messages: []models.MCPPromptMessage{{
+			Role:    "user",
+			Content: models.MCPContent{Type: "text", Text: result.String()},
+		}},
+	}, nil
+}
+
+// promptDescription returns a prompt's description, falling back to its name and version
+func promptDescription(prompt *PromptTemplate) string {
+	if prompt.Description != "" {
+		return prompt.Description
+	}
+	return fmt.Sprintf("%s prompt template (version %s)", prompt.Name, prompt.Version)
+}
+
+// promptArguments lists the variables a template reads. Variables with a
+// default value are optional.
+func promptArguments(prompt *PromptTemplate, tmpl *template.Template) []models.MCPPromptArgument {
+	var arguments []models.MCPPromptArgument
+	for _, name := range templateFields(tmpl) {
+		_, hasDefault := prompt.Variables[name]
+
+		description := prompt.Arguments[name]
+		if description == "" {
+			description = fmt.Sprintf("Value for {{.%s}}", name)
+		}
+
+		arguments = append(arguments, models.MCPPromptArgument{
+			Name:        name,
+			Description: description,
+			Required:    !hasDefault,
+		})
+	}
+	return arguments
+}
+
+// templateFields returns the top-level fields a template references, such as
+// Content for {{.Content}}, in order of first use. Fields inside range and
+// with blocks refer to a different dot and are skipped.
+func templateFields(tmpl *template.Template) []string {
+	seen := make(map[string]bool)
+	var fields []string
+
+	var walk func(node parse.Node)
+	walkPipe := func(pipe *parse.PipeNode) {
+		if pipe != nil {
+			walk(pipe)
+		}
+	}
+
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walkPipe(n.Pipe)
+		case *parse.PipeNode:
+			for _, cmd := range n.Cmds {
+				walk(cmd)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.FieldNode:
+			if len(n.Ident) > 0 && !seen[n.Ident[0]] {
+				seen[n.Ident[0]] = true
+				fields = append(fields, n.Ident[0])
+			}
+		case *parse.IfNode:
+			walkPipe(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walkPipe(n.Pipe)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walkPipe(n.Pipe)
+			walk(n.ElseList)
+		}
+	}
+
+	if tmpl.Tree != nil {
+		walk(tmpl.Tree.Root)
+	}
+
+	return fields
+}
+
+// validPromptName rejects names that would escape the prompts directory
+func validPromptName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, `/\`) && !strings.Contains(name, "..")
+}
+
+// NoteConstructionPrompt returns the name of the template used to analyze new memories
+func (pm *PromptManager) NoteConstructionPrompt() string {
+	if pm.config.NoteConstruction == "" {
+		return "note_construction"
+	}
+	return pm.config.NoteConstruction
+}
+
 // GetModelConfig returns the model configuration for a prompt
 func (pm *PromptManager) GetModelConfig(name string) (*ModelConfig, error) {
 	prompt, err := pm.LoadPrompt(name)
@@ -213,22 +405,22 @@ func (pm *PromptManager) getCompiledTemplate(name, templateStr string) (*templat
 
 // prepareTemplateData prepares data for template execution
 func (pm *PromptManager) prepareTemplateData(data PromptData, variables map[string]interface{}) map[string]interface{} {
-	templateData := map[string]interface{}{
-		"Content":     data.Content,
-		"ProjectPath": data.ProjectPath,
-		"CodeType":    data.CodeType,
-		"Context":     data.Context,
-		"Query":       data.Query,
-		"Memories":    data.Memories,
-	}
+	templateData := make(map[string]interface{})
 
-	// Add custom data
-	for k, v := range data.Custom {
+	// Template variables are defaults, overridden by the caller's data
+	for k, v := range variables {
 		templateData[k] = v
 	}
 
-	// Add template variables
-	for k, v := range variables {
+	templateData["Content"] = data.Content
+	templateData["ProjectPath"] = data.ProjectPath
+	templateData["CodeType"] = data.CodeType
+	templateData["Context"] = data.Context
+	templateData["Query"] = data.Query
+	templateData["Memories"] = data.Memories
+
+	// Add custom data
+	for k, v := range data.Custom {
 		templateData[k] = v
 	}
 
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/amem/mcp-server/pkg/config"
+	"github.com/amem/mcp-server/pkg/models"
+	"go.uber.org/zap"
+)
+
+const testPrompt = `name: summary
+version: 1.0
+description: Summarize code
+model_config:
+  temperature: 0.1
+  max_tokens: 100
+arguments:
+  Content: Code to summarize
+variables:
+  Style: brief
+template: |
+  Summarize {{.Content}} in a {{.Style}} style.
+  {{with .Context}}Context: {{.Text}}{{end}}
+`
+
+func TestPromptDefinitionsAndRendering(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "summary.yaml"), []byte(testPrompt), 0644); err != nil {
+		t.Fatalf("Failed to write prompt: %v", err)
+	}
+
+	pm := NewPromptManager(config.PromptsConfig{Directory: dir, CacheEnabled: true}, zap.NewNop())
+
+	prompts, err := pm.ListPromptDefinitions(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list prompts: %v", err)
+	}
+	if len(prompts) != 1 || prompts[0].Description != "Summarize code" {
+		t.Fatalf("Expected one described prompt, got %+v", prompts)
+	}
+
+	// Fields inside with refer to its value, not the template data
+	var names []string
+	required := make(map[string]bool)
+	for _, argument := range prompts[0].Arguments {
+		names = append(names, argument.Name)
+		required[argument.Name] = argument.Required
+	}
+	if strings.Join(names, ",") != "Content,Style,Context" {
+		t.Errorf("Unexpected arguments %v", names)
+	}
+	if !required["Content"] || required["Style"] {
+		t.Errorf("Expected Content required and Style optional, got %v", required)
+	}
+	if prompts[0].Arguments[0].Description != "Code to summarize" {
+		t.Errorf("Expected argument description from the file, got %q", prompts[0].Arguments[0].Description)
+	}
+
+	if _, err := pm.GetPromptMessages(ctx, "summary", map[string]string{"Content": "x"}); !errors.Is(err, models.ErrMissingPromptArguments) {
+		t.Errorf("Expected missing arguments error, got %v", err)
+	}
+	if _, err := pm.GetPromptMessages(ctx, "../summary", nil); !errors.Is(err, models.ErrPromptNotFound) {
+		t.Errorf("Expected not found for a path outside the directory, got %v", err)
+	}
+
+	result, err := pm.GetPromptMessages(ctx, "summary", map[string]string{
+		"Content": "func main() {}",
+		"Context": "",
+	})
+	if err != nil {
+		t.Fatalf("Failed to render prompt: %v", err)
+	}
+	text := result.Messages[0].Content.Text
+	if !strings.Contains(text, "Summarize func main() {} in a brief style.") || strings.Contains(text, "Context:") {
+		t.Errorf("Unexpected rendering %q", text)
+	}
+}
+
+func TestShippedPromptsRender(t *testing.T) {
+	pm := NewPromptManager(config.PromptsConfig{Directory: "../../prompts"}, zap.NewNop())
+
+	for _, name := range []string{"note_construction", "enhanced_note_construction"} {
+		prompt, err := pm.RenderPrompt(name, PromptData{Content: "func main() {}", CodeType: "go"})
+		if err != nil {
+			t.Fatalf("Failed to render %s: %v", name, err)
+		}
+		if !strings.Contains(prompt, "func main() {}") {
+			t.Errorf("Expected %s to include the content", name)
+		}
+	}
+
+	prompt, err := pm.RenderPrompt("memory_evolution", PromptData{
+		Custom: map[string]interface{}{"AnalysisContext": "Memory ID: abc"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to render memory_evolution: %v", err)
+	}
+	if !strings.Contains(prompt, "Memory ID: abc") {
+		t.Error("Expected memory_evolution to include the analysis context")
+	}
+
+	modelConfig, err := pm.GetModelConfig("memory_evolution")
+	if err != nil || modelConfig.MaxTokens == 0 {
+		t.Errorf("Expected memory_evolution to set max_tokens, got %+v, %v", modelConfig, err)
+	}
+}
@@ -0,0 +1,389 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amem/mcp-server/pkg/config"
+	"github.com/amem/mcp-server/pkg/models"
+	"go.uber.org/zap"
+)
+
+// ErrWorkspaceNotFound is returned when a workspace is not in the registry
+var ErrWorkspaceNotFound = errors.New("workspace not found")
+
+// WorkspaceRegistry persists workspace records separately from memories, so a
+// workspace keeps its name, description, owner, settings and parents before
+// it holds any memory. MemoryCount is never stored; the workspace service
+// fills it in.
+type WorkspaceRegistry interface {
+	// Initialize prepares the registry for use
+	Initialize(ctx context.Context) error
+
+	// GetWorkspace fetches a workspace record, returning ErrWorkspaceNotFound
+	// if it is not registered
+	GetWorkspace(ctx context.Context, id string) (*models.Workspace, error)
+
+	// ListWorkspaces returns every registered workspace sorted by ID
+	ListWorkspaces(ctx context.Context) ([]*models.Workspace, error)
+
+	// SaveWorkspace stores a workspace record, replacing any with the same ID
+	SaveWorkspace(ctx context.Context, workspace *models.Workspace) error
+
+	// DeleteWorkspace removes a workspace record. Deleting an unregistered
+	// workspace is not an error.
+	DeleteWorkspace(ctx context.Context, id string) error
+}
+
+// NewWorkspaceRegistry creates the registry for the configured storage backend
+func NewWorkspaceRegistry(cfg *config.Config, logger *zap.Logger) (WorkspaceRegistry, error) {
+	switch cfg.Storage.Backend {
+	case "", "chromadb":
+		return NewChromaWorkspaceRegistry(cfg.ChromaDB, logger.Named("workspace_registry")), nil
+	case "local":
+		return NewLocalWorkspaceRegistry(cfg.Storage, logger.Named("workspace_registry")), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s", cfg.Storage.Backend)
+	}
+}
+
+// ChromaWorkspaceRegistry keeps workspace records in the collection
+// <collection>_workspaces, one document per workspace. ChromaDB requires a
+// vector for every record, so each gets the same one-dimensional placeholder.
+type ChromaWorkspaceRegistry struct {
+	chroma *ChromaDBService
+}
+
+// registryEmbedding identifies the placeholder vectors of the registry collection
+var registryEmbedding = EmbeddingInfo{Model: "workspace-registry", Dimension: 1}
+
+// NewChromaWorkspaceRegistry creates a registry backed by ChromaDB
+func NewChromaWorkspaceRegistry(cfg config.ChromaDBConfig, logger *zap.Logger) *ChromaWorkspaceRegistry {
+	cfg.Collection = chromaCollectionName(cfg.Collection + "_workspaces")
+	return &ChromaWorkspaceRegistry{chroma: NewChromaDBService(cfg, logger)}
+}
+
+// Initialize creates the registry collection if it does not exist
+func (r *ChromaWorkspaceRegistry) Initialize(ctx context.Context) error {
+	if err := r.chroma.Initialize(ctx, registryEmbedding); err != nil {
+		return fmt.Errorf("failed to initialize workspace registry: %w", err)
+	}
+	return nil
+}
+
+// GetWorkspace fetches a workspace record by ID
+func (r *ChromaWorkspaceRegistry) GetWorkspace(ctx context.Context, id string) (*models.Workspace, error) {
+	workspaces, err := r.get(ctx, ChromaGetRequest{IDs: []string{id}})
+	if err != nil {
+		return nil, err
+	}
+	if len(workspaces) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrWorkspaceNotFound, id)
+	}
+	return workspaces[0], nil
+}
+
+// ListWorkspaces returns every workspace record sorted by ID
+func (r *ChromaWorkspaceRegistry) ListWorkspaces(ctx context.Context) ([]*models.Workspace, error) {
+	workspaces, err := r.get(ctx, ChromaGetRequest{})
+	if err != nil {
+		return nil, err
+	}
+	sortWorkspaces(workspaces)
+	return workspaces, nil
+}
+
+// get fetches workspace records matching a get request
+func (r *ChromaWorkspaceRegistry) get(ctx context.Context, request ChromaGetRequest) ([]*models.Workspace, error) {
+	request.Include = []string{"metadatas", "documents"}
+
+	var response ChromaGetResponse
+	if err := r.chroma.postCollection(ctx, "get", request, &response); err != nil {
+		return nil, fmt.Errorf("failed to read workspace registry: %w", err)
+	}
+
+	workspaces := make([]*models.Workspace, 0, len(response.IDs))
+	for i, id := range response.IDs {
+		var document string
+		if i < len(response.Documents) {
+			document = response.Documents[i]
+		}
+
+		var metadata map[string]interface{}
+		if i < len(response.Metadatas) {
+			metadata = response.Metadatas[i]
+		}
+
+		workspaces = append(workspaces, metadataToWorkspace(id, document, metadata))
+	}
+
+	return workspaces, nil
+}
+
+// SaveWorkspace upserts a workspace record
+func (r *ChromaWorkspaceRegistry) SaveWorkspace(ctx context.Context, workspace *models.Workspace) error {
+	metadata, err := workspaceToMetadata(workspace)
+	if err != nil {
+		return err
+	}
+
+	request := ChromaAddRequest{
+		IDs:        []string{workspace.ID},
+		Embeddings: [][]float32{{1}},
+		Metadatas:  []map[string]interface{}{metadata},
+		Documents:  []string{workspace.Description},
+	}
+	if err := r.chroma.postCollection(ctx, "upsert", request, nil); err != nil {
+		return fmt.Errorf("failed to save workspace: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteWorkspace removes a workspace record
+func (r *ChromaWorkspaceRegistry) DeleteWorkspace(ctx context.Context, id string) error {
+	if err := r.chroma.postCollection(ctx, "delete", ChromaDeleteRequest{IDs: []string{id}}, nil); err != nil {
+		return fmt.Errorf("failed to delete workspace: %w", err)
+	}
+	return nil
+}
+
+// workspaceToMetadata flattens a workspace into ChromaDB metadata. ChromaDB
+// metadata values must be scalars, so settings and parents are stored as JSON
+// strings.
+func workspaceToMetadata(workspace *models.Workspace) (map[string]interface{}, error) {
+	metadata := map[string]interface{}{
+		"name":       workspace.Name,
+		"owner":      workspace.Owner,
+		"created_at": workspace.CreatedAt.Format(time.RFC3339Nano),
+		"updated_at": workspace.UpdatedAt.Format(time.RFC3339Nano),
+	}
+
+	if len(workspace.Settings) > 0 {
+		settings, err := json.Marshal(workspace.Settings)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode workspace settings: %w", err)
+		}
+		metadata["settings"] = string(settings)
+	}
+	if len(workspace.Parents) > 0 {
+		parents, err := json.Marshal(workspace.Parents)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode workspace parents: %w", err)
+		}
+		metadata["parents"] = string(parents)
+	}
+
+	return metadata, nil
+}
+
+// metadataToWorkspace rebuilds a workspace from ChromaDB metadata
+func metadataToWorkspace(id, document string, metadata map[string]interface{}) *models.Workspace {
+	workspace := &models.Workspace{ID: id, Description: document}
+
+	if name, ok := metadata["name"].(string); ok {
+		workspace.Name = name
+	}
+	if owner, ok := metadata["owner"].(string); ok {
+		workspace.Owner = owner
+	}
+	if createdAt, ok := metadata["created_at"].(string); ok {
+		workspace.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdAt)
+	}
+	if updatedAt, ok := metadata["updated_at"].(string); ok {
+		workspace.UpdatedAt, _ = time.Parse(time.RFC3339Nano, updatedAt)
+	}
+	if settings, ok := metadata["settings"].(string); ok && settings != "" {
+		_ = json.Unmarshal([]byte(settings), &workspace.Settings)
+	}
+	if parents, ok := metadata["parents"].(string); ok && parents != "" {
+		_ = json.Unmarshal([]byte(parents), &workspace.Parents)
+	}
+
+	return workspace
+}
+
+// LocalWorkspaceRegistry keeps workspace records in a JSON file next to the
+// local store's data file
+type LocalWorkspaceRegistry struct {
+	path       string
+	logger     *zap.Logger
+	mu         sync.RWMutex
+	workspaces map[string]*models.Workspace
+}
+
+// localRegistryFile is the on-disk layout of the local registry
+type localRegistryFile struct {
+	Version    int                 `json:"version"`
+	Workspaces []*models.Workspace `json:"workspaces"`
+}
+
+// NewLocalWorkspaceRegistry creates a registry stored beside the data file,
+// e.g. memories.workspaces.json for memories.json
+func NewLocalWorkspaceRegistry(cfg config.StorageConfig, logger *zap.Logger) *LocalWorkspaceRegistry {
+	ext := filepath.Ext(cfg.Path)
+	return &LocalWorkspaceRegistry{
+		path:       strings.TrimSuffix(cfg.Path, ext) + ".workspaces" + ext,
+		logger:     logger,
+		workspaces: make(map[string]*models.Workspace),
+	}
+}
+
+// Initialize loads the registry file, treating a missing file as empty
+func (r *LocalWorkspaceRegistry) Initialize(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	data, err := os.ReadFile(r.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read workspace registry: %w", err)
+	}
+
+	var file localRegistryFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse workspace registry: %w", err)
+	}
+
+	r.workspaces = make(map[string]*models.Workspace, len(file.Workspaces))
+	for _, workspace := range file.Workspaces {
+		r.workspaces[workspace.ID] = workspace
+	}
+
+	r.logger.Info("Workspace registry loaded",
+		zap.String("path", r.path),
+		zap.Int("workspaces", len(r.workspaces)))
+
+	return nil
+}
+
+// GetWorkspace fetches a workspace record by ID
+func (r *LocalWorkspaceRegistry) GetWorkspace(ctx context.Context, id string) (*models.Workspace, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	workspace, ok := r.workspaces[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrWorkspaceNotFound, id)
+	}
+	return cloneWorkspace(workspace), nil
+}
+
+// ListWorkspaces returns every workspace record sorted by ID
+func (r *LocalWorkspaceRegistry) ListWorkspaces(ctx context.Context) ([]*models.Workspace, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	workspaces := make([]*models.Workspace, 0, len(r.workspaces))
+	for _, workspace := range r.workspaces {
+		workspaces = append(workspaces, cloneWorkspace(workspace))
+	}
+	sortWorkspaces(workspaces)
+	return workspaces, nil
+}
+
+// SaveWorkspace stores a workspace record and rewrites the registry file
+func (r *LocalWorkspaceRegistry) SaveWorkspace(ctx context.Context, workspace *models.Workspace) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous, existed := r.workspaces[workspace.ID]
+	r.workspaces[workspace.ID] = cloneWorkspace(workspace)
+
+	if err := r.persist(); err != nil {
+		if existed {
+			r.workspaces[workspace.ID] = previous
+		} else {
+			delete(r.workspaces, workspace.ID)
+		}
+		return err
+	}
+	return nil
+}
+
+// DeleteWorkspace removes a workspace record and rewrites the registry file
+func (r *LocalWorkspaceRegistry) DeleteWorkspace(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous, ok := r.workspaces[id]
+	if !ok {
+		return nil
+	}
+	delete(r.workspaces, id)
+
+	if err := r.persist(); err != nil {
+		r.workspaces[id] = previous
+		return err
+	}
+	return nil
+}
+
+// persist atomically rewrites the registry file. Callers must hold the write lock.
+func (r *LocalWorkspaceRegistry) persist() error {
+	file := localRegistryFile{
+		Version:    1,
+		Workspaces: make([]*models.Workspace, 0, len(r.workspaces)),
+	}
+	for _, workspace := range r.workspaces {
+		file.Workspaces = append(file.Workspaces, workspace)
+	}
+	sortWorkspaces(file.Workspaces)
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("failed to marshal workspace registry: %w", err)
+	}
+
+	tmpPath := r.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write workspace registry: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, r.path); err != nil {
+		return fmt.Errorf("failed to replace workspace registry: %w", err)
+	}
+
+	return nil
+}
+
+// cloneWorkspace copies a workspace so callers cannot mutate registry state
+func cloneWorkspace(workspace *models.Workspace) *models.Workspace {
+	clone := *workspace
+	clone.MemoryCount = 0
+	if workspace.Settings != nil {
+		clone.Settings = make(map[string]interface{}, len(workspace.Settings))
+		for key, value := range workspace.Settings {
+			clone.Settings[key] = value
+		}
+	}
+	clone.Parents = append([]string(nil), workspace.Parents...)
+	return &clone
+}
+
+// sortWorkspaces orders workspaces by ID
+func sortWorkspaces(workspaces []*models.Workspace) {
+	sort.Slice(workspaces, func(i, j int) bool {
+		return workspaces[i].ID < workspaces[j].ID
+	})
+}
+
+// Both backends must satisfy WorkspaceRegistry
+var (
+	_ WorkspaceRegistry = (*ChromaWorkspaceRegistry)(nil)
+	_ WorkspaceRegistry = (*LocalWorkspaceRegistry)(nil)
+)
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/amem/mcp-server/pkg/config"
+	"github.com/amem/mcp-server/pkg/models"
+	"go.uber.org/zap"
+)
+
+// MemoryStore is implemented by every memory storage backend. Filters use
+// ChromaDB's where syntax so they can be passed straight through to ChromaDB.
+type MemoryStore interface {
+	// Initialize prepares the backend for use with vectors from the given
+	// embedding model, failing if it already holds vectors from another one.
+	// A zero EmbeddingInfo skips the check for callers that only read content.
+	Initialize(ctx context.Context, embedding EmbeddingInfo) error
+
+	// StoreMemory adds a new memory
+	StoreMemory(ctx context.Context, memory *models.Memory) error
+
+	// StoreMemories adds several new memories in as few writes as the backend
+	// allows. A failure after some of the writes succeeded is reported as a
+	// *PartialWriteError.
+	StoreMemories(ctx context.Context, memories []*models.Memory) error
+
+	// UpdateMemory overwrites an existing memory
+	UpdateMemory(ctx context.Context, memory *models.Memory) error
+
+	// UpdateMemories overwrites several existing memories in as few writes as
+	// the backend allows. A failure after some of the writes succeeded is
+	// reported as a *PartialWriteError.
+	UpdateMemories(ctx context.Context, memories []*models.Memory) error
+
+	// UpsertMemory stores a memory, replacing any existing memory with the same ID
+	UpsertMemory(ctx context.Context, memory *models.Memory) error
+
+	// GetMemory fetches a single memory, including its embedding, by ID
+	GetMemory(ctx context.Context, id string) (*models.Memory, error)
+
+	// GetMemories fetches memories, including their embeddings, by ID
+	GetMemories(ctx context.Context, ids []string) ([]*models.Memory, error)
+
+	// DeleteMemories removes memories by ID
+	DeleteMemories(ctx context.Context, ids []string) error
+
+	// SearchSimilar returns the memories nearest to an embedding with their distances
+	SearchSimilar(ctx context.Context, queryEmbedding []float32, limit int, filters map[string]interface{}) ([]*models.Memory, []float32, error)
+
+	// ListMemories fetches one page of memories matching a filter, without embeddings
+	ListMemories(ctx context.Context, where map[string]interface{}, limit, offset int) ([]*models.Memory, error)
+
+	// ListAllMemories fetches every memory matching a filter, without embeddings
+	ListAllMemories(ctx context.Context, where map[string]interface{}) ([]*models.Memory, error)
+
+	// ScanMemories calls visit with every memory matching a filter, without
+	// content or embeddings, paging through the backend. It stops at the first
+	// error visit returns.
+	ScanMemories(ctx context.Context, where map[string]interface{}, visit func(*models.Memory) error) error
+
+	// CountMemories counts the memories matching a filter without fetching them
+	CountMemories(ctx context.Context, where map[string]interface{}) (int, error)
+
+	// DistanceMetric returns the metric SearchSimilar distances are reported in
+	DistanceMetric() string
+}
+
+// PartialWriteError reports a batched write that failed after the first
+// Written memories, in the order given, were saved
+type PartialWriteError struct {
+	Written int
+	Err     error
+}
+
+func (e *PartialWriteError) Error() string {
+	return fmt.Sprintf("%v (%d memories were written before the failure)", e.Err, e.Written)
+}
+
+func (e *PartialWriteError) Unwrap() error {
+	return e.Err
+}
+
+// ReindexableStore is implemented by backends that can rebuild their vectors
+// in a separate staging area and then switch to it in one step
+type ReindexableStore interface {
+	MemoryStore
+
+	// OpenStaging creates or reopens the named staging area for vectors from
+	// the given embedding model. Reopening keeps its contents so an interrupted
+	// reindex can resume.
+	OpenStaging(ctx context.Context, name string, embedding EmbeddingInfo) (MemoryStore, error)
+
+	// PromoteStaging makes the named staging area the live data and returns
+	// where the previous data was kept
+	PromoteStaging(ctx context.Context, name string) (string, error)
+}
+
+// NewMemoryStore creates the storage backend selected in the configuration
+func NewMemoryStore(cfg *config.Config, logger *zap.Logger) (MemoryStore, error) {
+	switch cfg.Storage.Backend {
+	case "", "chromadb":
+		return NewChromaDBService(cfg.ChromaDB, logger.Named("chromadb")), nil
+	case "local":
+		return NewLocalStore(cfg.Storage, cfg.ChromaDB.DistanceMetric, logger.Named("localstore")), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s", cfg.Storage.Backend)
+	}
+}
+
+// Both backends must satisfy MemoryStore and support reindexing
+var (
+	_ ReindexableStore = (*ChromaDBService)(nil)
+	_ ReindexableStore = (*LocalStore)(nil)
+)
@@ -2,31 +2,59 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/amem/mcp-server/pkg/gitrepo"
 	"github.com/amem/mcp-server/pkg/models"
 	"go.uber.org/zap"
 )
 
 // WorkspaceService handles workspace management operations
 type WorkspaceService struct {
-	logger   *zap.Logger
-	chromaDB *ChromaDBService
+	logger          *zap.Logger
+	store           MemoryStore
+	registry        WorkspaceRegistry
+	changeListeners []MemoryChangeListener
+	locks           *MemoryLocks // Held around every read-modify-write of a memory
+	dedupeThreshold float32      // Default similarity at which a merged memory is a duplicate
+	gitIdentity     bool         // Key paths inside a git repository on the repository
+
+	registeredMu  sync.Mutex
+	registeredIDs map[string]bool // Workspaces known to have a registry record
 }
 
 // NewWorkspaceService creates a new workspace service
-func NewWorkspaceService(chromaDB *ChromaDBService, logger *zap.Logger) *WorkspaceService {
+func NewWorkspaceService(store MemoryStore, registry WorkspaceRegistry, logger *zap.Logger) *WorkspaceService {
 	return &WorkspaceService{
-		logger:   logger,
-		chromaDB: chromaDB,
+		logger:          logger,
+		store:           store,
+		registry:        registry,
+		locks:           &MemoryLocks{},
+		dedupeThreshold: defaultMergeThreshold,
+		registeredIDs:   make(map[string]bool),
 	}
 }
 
+// Locks returns the per-memory locks workspace operations hold while
+// rewriting memories, for other writers of the same store to share
+func (w *WorkspaceService) Locks() *MemoryLocks {
+	return w.locks
+}
+
+// SetGitIdentity sets whether paths inside a git repository resolve to the
+// repository's canonical remote instead of the path itself
+func (w *WorkspaceService) SetGitIdentity(enabled bool) {
+	w.gitIdentity = enabled
+}
+
 // ValidateWorkspaceID validates a workspace identifier
 func (w *WorkspaceService) ValidateWorkspaceID(id string) error {
 	if id == "" {
@@ -71,17 +99,47 @@ func (w *WorkspaceService) IsFilesystemPath(id string) bool {
 
 // NormalizeWorkspaceID normalizes a workspace identifier
 func (w *WorkspaceService) NormalizeWorkspaceID(id string) string {
+	workspaceID, _ := w.ResolveWorkspace(id)
+	return workspaceID
+}
+
+// ResolveWorkspace normalizes a workspace identifier and returns the git
+// repository it lies in, if any. An existing path inside a repository
+// resolves to the repository's canonical remote, or to its main working tree
+// when it has no remote, so clones and worktrees of one repository share a
+// workspace.
+func (w *WorkspaceService) ResolveWorkspace(id string) (string, *gitrepo.Repository) {
 	if id == "" {
-		return "default"
+		return "default", nil
+	}
+
+	// For logical names, just trim whitespace and convert to lowercase
+	if !w.IsFilesystemPath(id) {
+		return strings.ToLower(strings.TrimSpace(id)), nil
 	}
 
-	// If it's a filesystem path, clean it
-	if w.IsFilesystemPath(id) {
-		return filepath.Clean(id)
+	path := filepath.Clean(id)
+	if !w.gitIdentity {
+		return path, nil
 	}
 
-	// For logical names, just trim whitespace and convert to lowercase
-	return strings.ToLower(strings.TrimSpace(id))
+	repo, err := gitrepo.Find(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			w.logger.Debug("Failed to read git repository", zap.String("path", path), zap.Error(err))
+		}
+		return path, nil
+	}
+	if repo == nil {
+		return path, nil
+	}
+
+	// Remotes with unusual characters fall back to the working tree
+	workspaceID := repo.ID()
+	if w.ValidateWorkspaceID(workspaceID) != nil {
+		workspaceID = repo.Root
+	}
+	return workspaceID, repo
 }
 
 // GetDefaultWorkspaceID returns the default workspace ID
@@ -95,16 +153,22 @@ func (w *WorkspaceService) GetDefaultWorkspaceID() string {
 	return "default"
 }
 
-// WorkspaceExists checks if a workspace exists by looking for memories with that workspace_id
+// WorkspaceExists checks if a workspace is registered or holds at least one memory
 func (w *WorkspaceService) WorkspaceExists(ctx context.Context, workspaceID string) (bool, error) {
-	// Query ChromaDB for any memories with this workspace_id
+	registered, err := w.registeredWorkspace(ctx, workspaceID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check workspace existence: %w", err)
+	}
+	if registered != nil {
+		return true, nil
+	}
+
+	// Look for any memory with this workspace_id
 	filters := map[string]interface{}{
 		"workspace_id": workspaceID,
 	}
 
-	// Use a dummy embedding for the query (we only care about metadata)
-	dummyEmbedding := make([]float32, 384) // Match the embedding dimension
-	memories, _, err := w.chromaDB.SearchSimilar(ctx, dummyEmbedding, 1, filters)
+	memories, err := w.store.ListMemories(ctx, filters, 1, 0)
 	if err != nil {
 		return false, fmt.Errorf("failed to check workspace existence: %w", err)
 	}
@@ -112,16 +176,28 @@ func (w *WorkspaceService) WorkspaceExists(ctx context.Context, workspaceID stri
 	return len(memories) > 0, nil
 }
 
-// GetWorkspaceInfo retrieves information about a workspace
+// registeredWorkspace returns the registry record for a workspace, or nil if
+// it is not registered
+func (w *WorkspaceService) registeredWorkspace(ctx context.Context, workspaceID string) (*models.Workspace, error) {
+	workspace, err := w.registry.GetWorkspace(ctx, workspaceID)
+	if errors.Is(err, ErrWorkspaceNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	workspace.Registered = true
+	return workspace, nil
+}
+
+// GetWorkspaceInfo retrieves information about a workspace, combining its
+// registry record, if any, with the count and dates of the memories it holds
 func (w *WorkspaceService) GetWorkspaceInfo(ctx context.Context, workspaceID string) (*models.Workspace, error) {
-	// Query ChromaDB for memories in this workspace to get count and dates
 	filters := map[string]interface{}{
 		"workspace_id": workspaceID,
 	}
 
-	// Use a dummy embedding for the query (we only care about metadata)
-	dummyEmbedding := make([]float32, 384)
-	memories, _, err := w.chromaDB.SearchSimilar(ctx, dummyEmbedding, 1000, filters) // Get many to count
+	count, err := w.store.CountMemories(ctx, filters)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get workspace info: %w", err)
 	}
@@ -130,32 +206,147 @@ func (w *WorkspaceService) GetWorkspaceInfo(ctx context.Context, workspaceID str
 		ID:          workspaceID,
 		Name:        w.generateWorkspaceName(workspaceID),
 		Description: w.generateWorkspaceDescription(workspaceID),
-		MemoryCount: len(memories),
+		MemoryCount: count,
 		CreatedAt:   time.Now(), // Will be updated if we find memories
 		UpdatedAt:   time.Now(),
 	}
 
-	// If we have memories, use the oldest for CreatedAt and newest for UpdatedAt
-	if len(memories) > 0 {
-		oldest := memories[0].CreatedAt
-		newest := memories[0].UpdatedAt
-
-		for _, memory := range memories {
-			if memory.CreatedAt.Before(oldest) {
-				oldest = memory.CreatedAt
+	// If we have memories, use the oldest for CreatedAt and newest for
+	// UpdatedAt, reading only their metadata
+	if count > 0 {
+		first := true
+		err := w.store.ScanMemories(ctx, filters, func(memory *models.Memory) error {
+			if first || memory.CreatedAt.Before(workspace.CreatedAt) {
+				workspace.CreatedAt = memory.CreatedAt
 			}
-			if memory.UpdatedAt.After(newest) {
-				newest = memory.UpdatedAt
+			if first || memory.UpdatedAt.After(workspace.UpdatedAt) {
+				workspace.UpdatedAt = memory.UpdatedAt
 			}
+			first = false
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get workspace info: %w", err)
 		}
+	}
 
-		workspace.CreatedAt = oldest
-		workspace.UpdatedAt = newest
+	registered, err := w.registeredWorkspace(ctx, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspace info: %w", err)
+	}
+	if registered != nil {
+		workspace = withMemoryActivity(registered, workspace)
 	}
 
 	return workspace, nil
 }
 
+// withMemoryActivity returns a registry record with the memory count of the
+// workspace derived from its memories, bumping UpdatedAt to the last change
+// of any memory
+func withMemoryActivity(registered, derived *models.Workspace) *models.Workspace {
+	registered.MemoryCount = derived.MemoryCount
+	if derived.MemoryCount > 0 && derived.UpdatedAt.After(registered.UpdatedAt) {
+		registered.UpdatedAt = derived.UpdatedAt
+	}
+	return registered
+}
+
+// ListWorkspaces returns every registered workspace, sorted by ID, with the
+// number of memories it holds. Workspaces are registered when memories are
+// written to them, so the registry lists every workspace.
+func (w *WorkspaceService) ListWorkspaces(ctx context.Context) ([]*models.Workspace, error) {
+	workspaces, err := w.registry.ListWorkspaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspaces: %w", err)
+	}
+
+	for _, workspace := range workspaces {
+		workspace.Registered = true
+		workspace.MemoryCount, err = w.store.CountMemories(ctx, map[string]interface{}{"workspace_id": workspace.ID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to count memories of workspace %s: %w", workspace.ID, err)
+		}
+	}
+
+	return workspaces, nil
+}
+
+// RegisterMemoryWorkspace makes sure a workspace that memories are written to
+// has a registry record, creating one with a generated name and description
+func (w *WorkspaceService) RegisterMemoryWorkspace(ctx context.Context, workspaceID string) error {
+	return w.registerMemoryWorkspace(ctx, workspaceID, time.Now())
+}
+
+// registerMemoryWorkspace registers a workspace, if needed, as created at
+// createdAt
+func (w *WorkspaceService) registerMemoryWorkspace(ctx context.Context, workspaceID string, createdAt time.Time) error {
+	w.registeredMu.Lock()
+	defer w.registeredMu.Unlock()
+
+	if w.registeredIDs[workspaceID] {
+		return nil
+	}
+
+	_, err := w.registry.GetWorkspace(ctx, workspaceID)
+	if errors.Is(err, ErrWorkspaceNotFound) {
+		err = w.registry.SaveWorkspace(ctx, &models.Workspace{
+			ID:          workspaceID,
+			Name:        w.generateWorkspaceName(workspaceID),
+			Description: w.generateWorkspaceDescription(workspaceID),
+			CreatedAt:   createdAt,
+			UpdatedAt:   createdAt,
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to register workspace %s: %w", workspaceID, err)
+	}
+
+	w.registeredIDs[workspaceID] = true
+	return nil
+}
+
+// RegisterStoredWorkspaces registers every workspace holding memories that
+// has no registry record, such as those written before workspaces were
+// registered with their memories, as created with its oldest memory. It
+// reads only metadata and returns how many workspaces it registered.
+func (w *WorkspaceService) RegisterStoredWorkspaces(ctx context.Context) (int, error) {
+	oldest := make(map[string]time.Time)
+	err := w.store.ScanMemories(ctx, nil, func(memory *models.Memory) error {
+		if createdAt, ok := oldest[memory.WorkspaceID]; !ok || memory.CreatedAt.Before(createdAt) {
+			oldest[memory.WorkspaceID] = memory.CreatedAt
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan memories: %w", err)
+	}
+
+	registered, err := w.registry.ListWorkspaces(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list workspaces: %w", err)
+	}
+	for _, workspace := range registered {
+		delete(oldest, workspace.ID)
+	}
+
+	for workspaceID, createdAt := range oldest {
+		if err := w.registerMemoryWorkspace(ctx, workspaceID, createdAt); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(oldest), nil
+}
+
+// forgetRegistered drops a workspace from the registered cache after its
+// registry record is deleted
+func (w *WorkspaceService) forgetRegistered(workspaceID string) {
+	w.registeredMu.Lock()
+	defer w.registeredMu.Unlock()
+	delete(w.registeredIDs, workspaceID)
+}
+
 // generateWorkspaceName generates a human-readable name for a workspace
 func (w *WorkspaceService) generateWorkspaceName(workspaceID string) string {
 	if workspaceID == "default" {
@@ -203,13 +394,22 @@ func (w *WorkspaceService) CreateWorkspace(ctx context.Context, req *models.Work
 		return nil, fmt.Errorf("workspace '%s' already exists", workspaceID)
 	}
 
+	parents, err := w.validateParents(ctx, workspaceID, req.Parents)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create workspace info
+	now := time.Now()
 	workspace := &models.Workspace{
 		ID:          workspaceID,
 		Name:        req.Name,
 		Description: req.Description,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		Owner:       req.Owner,
+		Settings:    req.Settings,
+		Parents:     parents,
+		CreatedAt:   now,
+		UpdatedAt:   now,
 		MemoryCount: 0,
 	}
 
@@ -221,6 +421,11 @@ func (w *WorkspaceService) CreateWorkspace(ctx context.Context, req *models.Work
 		workspace.Description = w.generateWorkspaceDescription(workspaceID)
 	}
 
+	if err := w.registry.SaveWorkspace(ctx, workspace); err != nil {
+		return nil, fmt.Errorf("failed to register workspace: %w", err)
+	}
+	workspace.Registered = true
+
 	w.logger.Info("Created new workspace",
 		zap.String("workspace_id", workspaceID),
 		zap.String("name", workspace.Name))
@@ -261,6 +466,9 @@ func (w *WorkspaceService) InitializeWorkspace(ctx context.Context, req *models.
 		Identifier:  workspaceID,
 		Name:        req.Name,
 		Description: req.Description,
+		Owner:       req.Owner,
+		Settings:    req.Settings,
+		Parents:     req.Parents,
 	}
 
 	workspace, err := w.CreateWorkspace(ctx, createReq)
@@ -270,3 +478,81 @@ func (w *WorkspaceService) InitializeWorkspace(ctx context.Context, req *models.
 
 	return workspace, true, nil
 }
+
+// UpdateWorkspace changes the registered details of a workspace. A workspace
+// only known from its memories is registered with the changes applied.
+func (w *WorkspaceService) UpdateWorkspace(ctx context.Context, req *models.WorkspaceUpdateRequest) (*models.Workspace, error) {
+	workspaceID := w.NormalizeWorkspaceID(req.Identifier)
+
+	current, err := w.GetWorkspaceInfo(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if !current.Registered && current.MemoryCount == 0 {
+		return nil, fmt.Errorf("workspace '%s' does not exist", workspaceID)
+	}
+
+	if req.Name != "" {
+		current.Name = req.Name
+	}
+	if req.Description != "" {
+		current.Description = req.Description
+	}
+	if req.Owner != "" {
+		current.Owner = req.Owner
+	}
+	for key, value := range req.Settings {
+		if value == nil {
+			delete(current.Settings, key)
+			continue
+		}
+		if current.Settings == nil {
+			current.Settings = make(map[string]interface{})
+		}
+		current.Settings[key] = value
+	}
+	if req.Parents != nil {
+		current.Parents, err = w.validateParents(ctx, workspaceID, req.Parents)
+		if err != nil {
+			return nil, err
+		}
+	}
+	current.UpdatedAt = time.Now()
+
+	if err := w.registry.SaveWorkspace(ctx, current); err != nil {
+		return nil, fmt.Errorf("failed to update workspace: %w", err)
+	}
+	current.Registered = true
+
+	w.logger.Info("Updated workspace",
+		zap.String("workspace_id", workspaceID),
+		zap.String("name", current.Name))
+
+	return current, nil
+}
+
+// UnregisterWorkspace removes the registry record of a workspace, reporting
+// whether there was one, and drops it from the parents of other workspaces.
+// Its memories are left alone.
+func (w *WorkspaceService) UnregisterWorkspace(ctx context.Context, workspaceID string) (bool, error) {
+	registered, err := w.registeredWorkspace(ctx, workspaceID)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up workspace: %w", err)
+	}
+	if registered == nil {
+		return false, nil
+	}
+
+	if err := w.registry.DeleteWorkspace(ctx, workspaceID); err != nil {
+		return false, err
+	}
+	w.forgetRegistered(workspaceID)
+	if err := w.replaceParent(ctx, workspaceID, ""); err != nil {
+		return false, err
+	}
+
+	w.logger.Info("Unregistered workspace",
+		zap.String("workspace_id", workspaceID))
+
+	return true, nil
+}
@@ -0,0 +1,623 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/amem/mcp-server/pkg/models"
+	"go.uber.org/zap"
+)
+
+// workspaceBatchSize is the number of memories read and rewritten at a time
+// when moving memories between workspaces
+const workspaceBatchSize = 100
+
+// defaultMergeThreshold is the similarity at which a merged memory duplicates
+// a target memory until SetDedupeThreshold is called
+const defaultMergeThreshold = 0.95
+
+// MemoryChangeListener is called for every memory a workspace operation
+// changes, once for each workspace affected
+type MemoryChangeListener func(memoryID, workspaceID string)
+
+// AddChangeListener registers a function to call whenever a workspace
+// operation moves or rewrites a memory. Listeners must be added before the
+// service is used and must not block.
+func (w *WorkspaceService) AddChangeListener(listener MemoryChangeListener) {
+	w.changeListeners = append(w.changeListeners, listener)
+}
+
+// SetDedupeThreshold sets the default similarity at which a memory merged
+// from another workspace duplicates a target memory
+func (w *WorkspaceService) SetDedupeThreshold(threshold float32) {
+	if threshold > 0 {
+		w.dedupeThreshold = threshold
+	}
+}
+
+// notifyChange tells every listener that a memory changed in a workspace
+func (w *WorkspaceService) notifyChange(memoryID, workspaceID string) {
+	for _, listener := range w.changeListeners {
+		listener(memoryID, workspaceID)
+	}
+}
+
+// RenameWorkspace gives a workspace a new identifier, moving every memory and
+// the registry record and updating workspaces that inherit from it. The new
+// identifier must not be in use.
+func (w *WorkspaceService) RenameWorkspace(ctx context.Context, req *models.WorkspaceRenameRequest) (*models.WorkspaceMoveResponse, error) {
+	startTime := time.Now()
+
+	from, to, err := w.workspacePair(req.Identifier, req.NewIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.requireWorkspace(ctx, from); err != nil {
+		return nil, err
+	}
+	exists, err := w.WorkspaceExists(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("workspace '%s' already exists; merge into it instead", to)
+	}
+
+	ids, err := w.scanMemoryIDs(ctx, map[string]interface{}{"workspace_id": from}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspace memories: %w", err)
+	}
+
+	response := &models.WorkspaceMoveResponse{SourceWorkspaceID: from, TargetWorkspaceID: to}
+	if err := w.moveMemories(ctx, ids, to, nil, response); err != nil {
+		return nil, err
+	}
+
+	registered, err := w.registeredWorkspace(ctx, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up workspace: %w", err)
+	}
+	if registered != nil {
+		// Generated names and descriptions follow the identifier; chosen ones are kept
+		if registered.Name == w.generateWorkspaceName(from) {
+			registered.Name = w.generateWorkspaceName(to)
+		}
+		if registered.Description == w.generateWorkspaceDescription(from) {
+			registered.Description = w.generateWorkspaceDescription(to)
+		}
+		registered.ID = to
+		registered.UpdatedAt = time.Now()
+
+		if err := w.registry.SaveWorkspace(ctx, registered); err != nil {
+			return nil, fmt.Errorf("failed to register renamed workspace: %w", err)
+		}
+		if err := w.registry.DeleteWorkspace(ctx, from); err != nil {
+			return nil, fmt.Errorf("failed to unregister old workspace: %w", err)
+		}
+		w.forgetRegistered(from)
+	}
+	if err := w.replaceParent(ctx, from, to); err != nil {
+		return nil, err
+	}
+
+	response.DurationMs = time.Since(startTime).Milliseconds()
+
+	w.logger.Info("Renamed workspace",
+		zap.String("from", from),
+		zap.String("to", to),
+		zap.Int("moved", response.Moved))
+
+	return response, nil
+}
+
+// MergeWorkspaces moves every memory of the source workspace into the target
+// workspace and unregisters the source. A source memory at least as similar
+// as the threshold to a target memory is deleted instead: its tags, keywords
+// and links are folded into the target memory, and links to it are pointed
+// at the target memory.
+func (w *WorkspaceService) MergeWorkspaces(ctx context.Context, req *models.WorkspaceMergeRequest) (*models.WorkspaceMoveResponse, error) {
+	startTime := time.Now()
+
+	source, target, err := w.workspacePair(req.Source, req.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	threshold := req.SimilarityThreshold
+	if threshold <= 0 {
+		threshold = w.dedupeThreshold
+	}
+	if threshold > 1 {
+		return nil, fmt.Errorf("similarity threshold must be between 0 and 1")
+	}
+
+	if err := w.requireWorkspace(ctx, source); err != nil {
+		return nil, err
+	}
+	if err := w.requireWorkspace(ctx, target); err != nil {
+		return nil, err
+	}
+
+	ids, err := w.scanMemoryIDs(ctx, map[string]interface{}{"workspace_id": source}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspace memories: %w", err)
+	}
+
+	response := &models.WorkspaceMoveResponse{
+		SourceWorkspaceID: source,
+		TargetWorkspaceID: target,
+		Matched:           len(ids),
+	}
+
+	survivors, err := w.findMergeDuplicates(ctx, ids, target, threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	var moving []string
+	for _, id := range ids {
+		if _, ok := survivors[id]; !ok {
+			moving = append(moving, id)
+		}
+	}
+
+	if err := w.moveMemories(ctx, moving, target, survivors, response); err != nil {
+		return nil, err
+	}
+	// moveMemories counts every ID it is given, but duplicates were matched too
+	response.Matched = len(ids)
+
+	if err := w.foldDuplicates(ctx, survivors, response); err != nil {
+		return nil, err
+	}
+
+	// Workspaces inheriting from the source now inherit from the target
+	if err := w.replaceParent(ctx, source, target); err != nil {
+		return nil, err
+	}
+	if _, err := w.UnregisterWorkspace(ctx, source); err != nil {
+		return nil, err
+	}
+
+	response.DurationMs = time.Since(startTime).Milliseconds()
+
+	w.logger.Info("Merged workspaces",
+		zap.String("source", source),
+		zap.String("target", target),
+		zap.Int("moved", response.Moved),
+		zap.Int("deduplicated", response.Deduplicated))
+
+	return response, nil
+}
+
+// MoveMemories moves memories selected by ID, or by workspace with optional
+// code type and tag filters, into another workspace
+func (w *WorkspaceService) MoveMemories(ctx context.Context, req *models.MoveMemoriesRequest) (*models.WorkspaceMoveResponse, error) {
+	startTime := time.Now()
+
+	if req.TargetWorkspaceID == "" {
+		return nil, fmt.Errorf("target_workspace_id is required")
+	}
+	target := w.NormalizeWorkspaceID(req.TargetWorkspaceID)
+	if err := w.ValidateWorkspaceID(target); err != nil {
+		return nil, fmt.Errorf("invalid workspace ID: %w", err)
+	}
+
+	response := &models.WorkspaceMoveResponse{TargetWorkspaceID: target}
+
+	var ids []string
+	switch {
+	case len(req.MemoryIDs) > 0 && req.WorkspaceID != "":
+		return nil, fmt.Errorf("select memories by memory_ids or by workspace_id, not both")
+	case len(req.MemoryIDs) > 0:
+		if len(req.CodeTypes) > 0 || len(req.Tags) > 0 {
+			return nil, fmt.Errorf("code_types and tags filter a workspace_id and cannot be combined with memory_ids")
+		}
+		seen := make(map[string]bool, len(req.MemoryIDs))
+		for _, id := range req.MemoryIDs {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	case req.WorkspaceID != "":
+		source := w.NormalizeWorkspaceID(req.WorkspaceID)
+		response.SourceWorkspaceID = source
+
+		conditions := []map[string]interface{}{{"workspace_id": source}}
+		if len(req.CodeTypes) > 0 {
+			conditions = append(conditions, map[string]interface{}{
+				"code_type": map[string]interface{}{"$in": req.CodeTypes},
+			})
+		}
+		where := conditions[0]
+		if len(conditions) > 1 {
+			where = map[string]interface{}{"$and": conditions}
+		}
+
+		var err error
+		ids, err = w.scanMemoryIDs(ctx, where, func(memory *models.Memory) bool {
+			return HasAllTags(memory, req.Tags)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list memories: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("memory_ids or workspace_id is required")
+	}
+
+	if err := w.moveMemories(ctx, ids, target, nil, response); err != nil {
+		return nil, err
+	}
+
+	response.DurationMs = time.Since(startTime).Milliseconds()
+
+	w.logger.Info("Moved memories",
+		zap.String("target", target),
+		zap.Int("matched", response.Matched),
+		zap.Int("moved", response.Moved))
+
+	return response, nil
+}
+
+// workspacePair normalizes and validates the source and target of a workspace
+// operation, which must differ
+func (w *WorkspaceService) workspacePair(source, target string) (string, string, error) {
+	if source == "" || target == "" {
+		return "", "", fmt.Errorf("both workspaces are required")
+	}
+
+	source = w.NormalizeWorkspaceID(source)
+	target = w.NormalizeWorkspaceID(target)
+	for _, id := range []string{source, target} {
+		if err := w.ValidateWorkspaceID(id); err != nil {
+			return "", "", fmt.Errorf("invalid workspace ID: %w", err)
+		}
+	}
+
+	if source == target {
+		return "", "", fmt.Errorf("source and target workspace are both '%s'", source)
+	}
+
+	return source, target, nil
+}
+
+// requireWorkspace fails unless a workspace is registered or holds memories
+func (w *WorkspaceService) requireWorkspace(ctx context.Context, workspaceID string) error {
+	exists, err := w.WorkspaceExists(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("workspace '%s' does not exist", workspaceID)
+	}
+	return nil
+}
+
+// moveMemories rewrites the workspace of the given memories in batches,
+// pointing links at duplicates to their survivors. UpdatedAt is kept since
+// the content does not change. Counts are added to response.
+func (w *WorkspaceService) moveMemories(ctx context.Context, ids []string, target string, survivors map[string]string, response *models.WorkspaceMoveResponse) error {
+	if err := w.RegisterMemoryWorkspace(ctx, target); err != nil {
+		return err
+	}
+
+	for start := 0; start < len(ids); start += workspaceBatchSize {
+		end := start + workspaceBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		if err := w.moveBatch(ctx, ids[start:end], target, survivors, response); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// moveBatch moves one batch of memories for moveMemories, holding their locks
+// from the read to the write so no concurrent change is overwritten
+func (w *WorkspaceService) moveBatch(ctx context.Context, ids []string, target string, survivors map[string]string, response *models.WorkspaceMoveResponse) error {
+	defer w.locks.LockAll(ids)()
+
+	memories, err := w.store.GetMemories(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("failed to load memories: %w", err)
+	}
+
+	found := make(map[string]bool, len(memories))
+	sources := make(map[string]string, len(memories))
+	batch := make([]*models.Memory, 0, len(memories))
+	for _, memory := range memories {
+		found[memory.ID] = true
+		if memory.WorkspaceID == target {
+			response.Unchanged++
+			continue
+		}
+
+		sources[memory.ID] = memory.WorkspaceID
+		if memory.ProjectPath == memory.WorkspaceID {
+			memory.ProjectPath = target
+		}
+		memory.WorkspaceID = target
+
+		var rewritten int
+		memory.Links, rewritten = retargetLinks(memory.Links, survivors, memory.ID)
+		response.LinksRewritten += rewritten
+
+		batch = append(batch, memory)
+	}
+
+	for _, id := range ids {
+		if !found[id] {
+			response.NotFound = append(response.NotFound, id)
+		}
+	}
+	response.Matched += len(memories)
+
+	if err := w.store.UpdateMemories(ctx, batch); err != nil {
+		return fmt.Errorf("failed after moving %d memories: %w", response.Moved, err)
+	}
+
+	for _, memory := range batch {
+		w.notifyChange(memory.ID, sources[memory.ID])
+		w.notifyChange(memory.ID, target)
+	}
+	response.Moved += len(batch)
+
+	return nil
+}
+
+// scanMemoryIDs returns the IDs of the memories matching a filter and, when
+// keep is set, accepted by it, reading only metadata so that a large
+// workspace is never held in memory with its documents and embeddings
+func (w *WorkspaceService) scanMemoryIDs(ctx context.Context, where map[string]interface{}, keep func(*models.Memory) bool) ([]string, error) {
+	var ids []string
+	err := w.store.ScanMemories(ctx, where, func(memory *models.Memory) error {
+		if keep == nil || keep(memory) {
+			ids = append(ids, memory.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// findMergeDuplicates maps each source memory that duplicates a memory in the
+// target workspace to that survivor
+func (w *WorkspaceService) findMergeDuplicates(ctx context.Context, ids []string, target string, threshold float32) (map[string]string, error) {
+	survivors := make(map[string]string)
+
+	for start := 0; start < len(ids); start += workspaceBatchSize {
+		end := start + workspaceBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		memories, err := w.store.GetMemories(ctx, ids[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("failed to load memories: %w", err)
+		}
+
+		for _, memory := range memories {
+			matches, distances, err := w.store.SearchSimilar(ctx, memory.Embedding, 1, map[string]interface{}{
+				"workspace_id": target,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to search for duplicates: %w", err)
+			}
+			if len(matches) == 0 || len(distances) == 0 {
+				continue
+			}
+			if Similarity(w.store.DistanceMetric(), distances[0]) < threshold {
+				continue
+			}
+
+			survivors[memory.ID] = matches[0].ID
+		}
+	}
+
+	return survivors, nil
+}
+
+// foldDuplicates merges each duplicate's tags, keywords and links into its
+// survivor, points links at the duplicate from memories outside the merge to
+// the survivor, and deletes the duplicates
+func (w *WorkspaceService) foldDuplicates(ctx context.Context, survivors map[string]string, response *models.WorkspaceMoveResponse) error {
+	if len(survivors) == 0 {
+		return nil
+	}
+
+	// Back-links are missing for memories stored before links were written
+	// both ways, for imported ones and where a back-link write failed, so the
+	// duplicate's own links cannot be trusted to name every memory linking to
+	// it and the store's metadata is scanned instead. Moved memories had their
+	// links repointed when they were moved.
+	linking, err := w.memoriesLinkingTo(ctx, survivors)
+	if err != nil {
+		return err
+	}
+
+	// Every memory read here is locked until the duplicates are deleted, so
+	// no change made meanwhile is written over or lost with a duplicate
+	ids := make([]string, 0, len(survivors))
+	locked := append([]string(nil), linking...)
+	for id, survivorID := range survivors {
+		ids = append(ids, id)
+		locked = append(locked, id, survivorID)
+	}
+	sort.Strings(ids)
+	defer w.locks.LockAll(locked)()
+
+	var duplicates []*models.Memory
+	for start := 0; start < len(ids); start += workspaceBatchSize {
+		end := start + workspaceBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		memories, err := w.store.GetMemories(ctx, ids[start:end])
+		if err != nil {
+			return fmt.Errorf("failed to load duplicates: %w", err)
+		}
+		duplicates = append(duplicates, memories...)
+	}
+
+	// Every memory changed here is loaded once and written once
+	changed := make(map[string]*models.Memory)
+	load := func(id string) (*models.Memory, error) {
+		if memory, ok := changed[id]; ok {
+			return memory, nil
+		}
+		return w.store.GetMemory(ctx, id)
+	}
+
+	for _, duplicate := range duplicates {
+		survivor, err := load(survivors[duplicate.ID])
+		if err != nil {
+			return fmt.Errorf("failed to load duplicate target: %w", err)
+		}
+
+		survivor.Tags = appendMissing(survivor.Tags, duplicate.Tags)
+		survivor.Keywords = appendMissing(survivor.Keywords, duplicate.Keywords)
+
+		var rewritten int
+		survivor.Links, rewritten = retargetLinks(append(survivor.Links, duplicate.Links...), survivors, survivor.ID)
+		response.LinksRewritten += rewritten
+		changed[survivor.ID] = survivor
+	}
+
+	for _, id := range linking {
+		linked, err := load(id)
+		if err != nil {
+			w.logger.Warn("Failed to load linked memory",
+				zap.String("memory_id", id),
+				zap.Error(err))
+			continue
+		}
+
+		var rewritten int
+		linked.Links, rewritten = retargetLinks(linked.Links, survivors, linked.ID)
+		if rewritten > 0 {
+			response.LinksRewritten += rewritten
+			changed[linked.ID] = linked
+		}
+	}
+
+	updates := make([]*models.Memory, 0, len(changed))
+	for _, memory := range changed {
+		updates = append(updates, memory)
+	}
+	sort.Slice(updates, func(i, j int) bool {
+		return updates[i].ID < updates[j].ID
+	})
+
+	if err := w.store.UpdateMemories(ctx, updates); err != nil {
+		return fmt.Errorf("failed to update duplicate targets: %w", err)
+	}
+	for _, memory := range updates {
+		w.notifyChange(memory.ID, memory.WorkspaceID)
+	}
+
+	for start := 0; start < len(ids); start += workspaceBatchSize {
+		end := start + workspaceBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		if err := w.store.DeleteMemories(ctx, ids[start:end]); err != nil {
+			return fmt.Errorf("failed to delete duplicates: %w", err)
+		}
+	}
+	for _, duplicate := range duplicates {
+		w.notifyChange(duplicate.ID, duplicate.WorkspaceID)
+	}
+	response.Deduplicated = len(duplicates)
+
+	return nil
+}
+
+// memoriesLinkingTo returns the IDs of memories, other than the given ones,
+// with a link to any of them, reading only metadata
+func (w *WorkspaceService) memoriesLinkingTo(ctx context.Context, targets map[string]string) ([]string, error) {
+	var ids []string
+	err := w.store.ScanMemories(ctx, nil, func(memory *models.Memory) error {
+		if _, ok := targets[memory.ID]; ok {
+			return nil
+		}
+		for _, link := range memory.Links {
+			if _, ok := targets[link.TargetID]; ok {
+				ids = append(ids, memory.ID)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan for links to duplicates: %w", err)
+	}
+	return ids, nil
+}
+
+// retargetLinks points links at duplicates to their survivors, dropping links
+// that would then point at the memory itself or repeat another link. It
+// returns the links and the number repointed.
+func retargetLinks(links []models.MemoryLink, survivors map[string]string, selfID string) ([]models.MemoryLink, int) {
+	rewritten := 0
+	seen := make(map[string]bool, len(links))
+	result := make([]models.MemoryLink, 0, len(links))
+
+	for _, link := range links {
+		if survivor, ok := survivors[link.TargetID]; ok {
+			link.TargetID = survivor
+			rewritten++
+		}
+
+		key := link.TargetID + "\x00" + link.LinkType
+		if link.TargetID == selfID || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, link)
+	}
+
+	return result, rewritten
+}
+
+// appendMissing appends the values of added not already in existing
+func appendMissing(existing, added []string) []string {
+	for _, value := range added {
+		found := false
+		for _, current := range existing {
+			if current == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			existing = append(existing, value)
+		}
+	}
+	return existing
+}
+
+// HasAllTags reports whether a memory carries every one of the given tags
+func HasAllTags(memory *models.Memory, tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, memoryTag := range memory.Tags {
+			if strings.EqualFold(memoryTag, tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
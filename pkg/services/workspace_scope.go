@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SearchScope resolves the workspaces a search covers, mapping each to its
+// distance from the requested workspaces: 0 for the requested ones, 1 for
+// their parents, 2 for the parents' parents and so on. With inherit unset
+// only the requested workspaces are returned.
+func (w *WorkspaceService) SearchScope(ctx context.Context, workspaceIDs []string, inherit bool) (map[string]int, error) {
+	scope := make(map[string]int, len(workspaceIDs))
+	queue := make([]string, 0, len(workspaceIDs))
+	for _, id := range workspaceIDs {
+		if _, ok := scope[id]; !ok {
+			scope[id] = 0
+			queue = append(queue, id)
+		}
+	}
+
+	if !inherit {
+		return scope, nil
+	}
+
+	// Breadth first, so each workspace gets its shortest distance
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+
+		registered, err := w.registeredWorkspace(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve parents of '%s': %w", id, err)
+		}
+		if registered == nil {
+			continue
+		}
+
+		for _, parent := range registered.Parents {
+			if _, ok := scope[parent]; ok {
+				continue
+			}
+			scope[parent] = scope[id] + 1
+			queue = append(queue, parent)
+		}
+	}
+
+	return scope, nil
+}
+
+// validateParents normalizes the parents of a workspace and checks that each
+// exists and that none of them inherits from the workspace itself
+func (w *WorkspaceService) validateParents(ctx context.Context, workspaceID string, parents []string) ([]string, error) {
+	result := make([]string, 0, len(parents))
+	seen := make(map[string]bool, len(parents))
+
+	for _, parent := range parents {
+		parent = w.NormalizeWorkspaceID(parent)
+		if seen[parent] {
+			continue
+		}
+		seen[parent] = true
+
+		if parent == workspaceID {
+			return nil, fmt.Errorf("workspace '%s' cannot be its own parent", workspaceID)
+		}
+		if err := w.requireWorkspace(ctx, parent); err != nil {
+			return nil, fmt.Errorf("invalid parent: %w", err)
+		}
+
+		ancestors, err := w.SearchScope(ctx, []string{parent}, true)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := ancestors[workspaceID]; ok {
+			return nil, fmt.Errorf("workspace '%s' already inherits from '%s'", parent, workspaceID)
+		}
+
+		result = append(result, parent)
+	}
+
+	return result, nil
+}
+
+// replaceParent rewrites the parents of every registered workspace that
+// inherits from oldID to inherit from newID instead, or removes oldID when
+// newID is empty
+func (w *WorkspaceService) replaceParent(ctx context.Context, oldID, newID string) error {
+	workspaces, err := w.registry.ListWorkspaces(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list workspaces: %w", err)
+	}
+
+	for _, workspace := range workspaces {
+		parents := make([]string, 0, len(workspace.Parents))
+		changed := false
+		for _, parent := range workspace.Parents {
+			if parent != oldID {
+				parents = append(parents, parent)
+				continue
+			}
+			changed = true
+			if newID != "" && newID != workspace.ID && !containsString(workspace.Parents, newID) {
+				parents = append(parents, newID)
+			}
+		}
+		if !changed {
+			continue
+		}
+
+		workspace.Parents = parents
+		workspace.UpdatedAt = time.Now()
+		if err := w.registry.SaveWorkspace(ctx, workspace); err != nil {
+			return fmt.Errorf("failed to update parents of '%s': %w", workspace.ID, err)
+		}
+
+		w.logger.Info("Updated workspace parents",
+			zap.String("workspace_id", workspace.ID),
+			zap.Strings("parents", parents))
+	}
+
+	return nil
+}
+
+// containsString reports whether a list holds a value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
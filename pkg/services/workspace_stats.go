@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/amem/mcp-server/pkg/models"
+	"go.uber.org/zap"
+)
+
+// defaultStatsLimit is the number of tags and keywords ranked when a stats
+// request sets no limit
+const defaultStatsLimit = 10
+
+// ageBuckets are the ranges memories are counted in by time since creation,
+// each holding memories younger than maxAge and not in an earlier bucket
+var ageBuckets = []struct {
+	name   string
+	maxAge time.Duration
+}{
+	{"day", 24 * time.Hour},
+	{"week", 7 * 24 * time.Hour},
+	{"month", 30 * 24 * time.Hour},
+	{"quarter", 90 * 24 * time.Hour},
+	{"older", 0},
+}
+
+// WorkspaceStats computes exact statistics for a workspace from one scan of
+// the metadata of every memory in the workspace, however many there are. Only
+// the total across workspaces is counted by the store.
+func (w *WorkspaceService) WorkspaceStats(ctx context.Context, req *models.WorkspaceStatsRequest) (*models.WorkspaceStats, error) {
+	startTime := time.Now()
+
+	workspaceID := req.WorkspaceID
+	if workspaceID == "" {
+		workspaceID = w.GetDefaultWorkspaceID()
+	}
+	workspaceID = w.NormalizeWorkspaceID(workspaceID)
+	if err := w.requireWorkspace(ctx, workspaceID); err != nil {
+		return nil, err
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultStatsLimit
+	}
+
+	var memories []*models.Memory
+	err := w.store.ScanMemories(ctx, map[string]interface{}{"workspace_id": workspaceID}, func(memory *models.Memory) error {
+		memories = append(memories, memory)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan memories: %w", err)
+	}
+	total, err := w.store.CountMemories(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count memories: %w", err)
+	}
+
+	stats := &models.WorkspaceStats{
+		WorkspaceID:   workspaceID,
+		MemoryCount:   len(memories),
+		TotalMemories: total,
+		CodeTypes:     make(map[string]int),
+		Links:         models.LinkStats{ByType: make(map[string]int)},
+	}
+
+	inWorkspace := make(map[string]bool, len(memories))
+	for _, memory := range memories {
+		inWorkspace[memory.ID] = true
+	}
+
+	tags := make(map[string]int)
+	keywords := make(map[string]int)
+	ages := make([]int, len(ageBuckets))
+	now := time.Now()
+
+	for _, memory := range memories {
+		codeType := memory.CodeType
+		if codeType == "" {
+			codeType = "none"
+		}
+		stats.CodeTypes[codeType]++
+
+		if len(memory.Tags) == 0 {
+			stats.Untagged++
+		}
+		countTerms(tags, memory.Tags, false)
+		countTerms(keywords, memory.Keywords, true)
+
+		ages[ageBucket(now.Sub(memory.CreatedAt))]++
+
+		if len(memory.Links) > 0 {
+			stats.Links.LinkedMemories++
+		}
+		for _, link := range memory.Links {
+			stats.Links.Total++
+			stats.Links.ByType[link.LinkType]++
+			if !inWorkspace[link.TargetID] {
+				stats.Links.External++
+			}
+		}
+
+		if stats.OldestMemory == nil || memory.CreatedAt.Before(*stats.OldestMemory) {
+			createdAt := memory.CreatedAt
+			stats.OldestMemory = &createdAt
+		}
+		if stats.LastUpdated == nil || memory.UpdatedAt.After(*stats.LastUpdated) {
+			updatedAt := memory.UpdatedAt
+			stats.LastUpdated = &updatedAt
+		}
+	}
+
+	stats.DistinctTags = len(tags)
+	stats.Tags = rankTerms(tags, limit)
+	stats.TopKeywords = rankTerms(keywords, limit)
+	stats.Ages = make([]models.AgeBucket, len(ageBuckets))
+	for i, bucket := range ageBuckets {
+		stats.Ages[i] = models.AgeBucket{Bucket: bucket.name, Count: ages[i]}
+	}
+	stats.DurationMs = time.Since(startTime).Milliseconds()
+
+	w.logger.Debug("Computed workspace stats",
+		zap.String("workspace_id", workspaceID),
+		zap.Int("memory_count", stats.MemoryCount),
+		zap.Int64("duration_ms", stats.DurationMs))
+
+	return stats, nil
+}
+
+// ageBucket returns the index of the age bucket for a memory of the given age
+func ageBucket(age time.Duration) int {
+	for i, bucket := range ageBuckets {
+		if bucket.maxAge == 0 || age < bucket.maxAge {
+			return i
+		}
+	}
+	return len(ageBuckets) - 1
+}
+
+// countTerms counts each distinct term of one memory once, optionally folding
+// case so keywords written differently by the LLM are counted together
+func countTerms(counts map[string]int, terms []string, foldCase bool) {
+	seen := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		term = strings.TrimSpace(term)
+		if foldCase {
+			term = strings.ToLower(term)
+		}
+		if term == "" || seen[term] {
+			continue
+		}
+		seen[term] = true
+		counts[term]++
+	}
+}
+
+// rankTerms returns the limit most frequent terms, ties broken alphabetically
+func rankTerms(counts map[string]int, limit int) []models.TermCount {
+	ranked := make([]models.TermCount, 0, len(counts))
+	for term, count := range counts {
+		ranked = append(ranked, models.TermCount{Term: term, Count: count})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Count != ranked[j].Count {
+			return ranked[i].Count > ranked[j].Count
+		}
+		return ranked[i].Term < ranked[j].Term
+	})
+
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
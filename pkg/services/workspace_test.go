@@ -0,0 +1,381 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/amem/mcp-server/pkg/config"
+	"github.com/amem/mcp-server/pkg/models"
+	"go.uber.org/zap"
+)
+
+func newTestWorkspaceService(t *testing.T, path string) *WorkspaceService {
+	t.Helper()
+
+	registry := NewLocalWorkspaceRegistry(config.StorageConfig{Backend: "local", Path: path}, zap.NewNop())
+	if err := registry.Initialize(context.Background()); err != nil {
+		t.Fatalf("Failed to initialize workspace registry: %v", err)
+	}
+
+	return NewWorkspaceService(newTestLocalStore(t, path), registry, zap.NewNop())
+}
+
+func TestWorkspaceRegistryPersistsEmptyWorkspaces(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "memories.json")
+
+	service := newTestWorkspaceService(t, path)
+	if _, err := service.CreateWorkspace(ctx, &models.WorkspaceRequest{
+		Identifier:  "billing",
+		Name:        "Billing",
+		Description: "Invoices and payments",
+		Owner:       "team-payments",
+		Settings:    map[string]interface{}{"retention_days": float64(30), "language": "go"},
+	}); err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+
+	if _, err := service.CreateWorkspace(ctx, &models.WorkspaceRequest{Identifier: "billing"}); err == nil {
+		t.Error("Expected creating a registered workspace again to fail")
+	}
+
+	if err := service.store.StoreMemory(ctx, &models.Memory{ID: "m1", Content: "x", WorkspaceID: "scratch", Embedding: []float32{1, 0}}); err != nil {
+		t.Fatalf("Failed to store memory: %v", err)
+	}
+
+	// A restart keeps the workspace although it holds no memories, and
+	// registers the workspace only known from its memories
+	reopened := newTestWorkspaceService(t, path)
+	if registered, err := reopened.RegisterStoredWorkspaces(ctx); err != nil || registered != 1 {
+		t.Fatalf("Expected scratch to be registered, got %d, %v", registered, err)
+	}
+	workspaces, err := reopened.ListWorkspaces(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list workspaces: %v", err)
+	}
+	if len(workspaces) != 2 {
+		t.Fatalf("Expected billing and scratch, got %+v", workspaces)
+	}
+
+	billing, scratch := workspaces[0], workspaces[1]
+	if billing.ID != "billing" || !billing.Registered || billing.Owner != "team-payments" || billing.Description != "Invoices and payments" {
+		t.Errorf("Unexpected registered workspace %+v", billing)
+	}
+	if scratch.ID != "scratch" || !scratch.Registered || scratch.MemoryCount != 1 || scratch.Name == "" {
+		t.Errorf("Unexpected backfilled workspace %+v", scratch)
+	}
+
+	updated, err := reopened.UpdateWorkspace(ctx, &models.WorkspaceUpdateRequest{
+		Identifier: "billing",
+		Owner:      "team-finance",
+		Settings:   map[string]interface{}{"retention_days": nil, "reviewer": "sam"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to update workspace: %v", err)
+	}
+	if updated.Name != "Billing" || updated.Owner != "team-finance" {
+		t.Errorf("Expected only the owner to change, got %+v", updated)
+	}
+	if _, ok := updated.Settings["retention_days"]; ok || updated.Settings["language"] != "go" || updated.Settings["reviewer"] != "sam" {
+		t.Errorf("Expected settings to be merged, got %v", updated.Settings)
+	}
+
+	if _, err := reopened.UpdateWorkspace(ctx, &models.WorkspaceUpdateRequest{Identifier: "missing", Name: "x"}); err == nil {
+		t.Error("Expected updating an unknown workspace to fail")
+	}
+
+	removed, err := reopened.UnregisterWorkspace(ctx, "billing")
+	if err != nil || !removed {
+		t.Fatalf("Expected billing to be unregistered, got %t, %v", removed, err)
+	}
+	if exists, _ := reopened.WorkspaceExists(ctx, "billing"); exists {
+		t.Error("Expected billing to be gone after unregistering")
+	}
+}
+
+func TestMergeWorkspacesFoldsDuplicates(t *testing.T) {
+	ctx := context.Background()
+	service := newTestWorkspaceService(t, filepath.Join(t.TempDir(), "memories.json"))
+
+	memories := []*models.Memory{
+		{ID: "target-retry", Content: "retry loop", WorkspaceID: "api", Tags: []string{"go"}, Embedding: []float32{1, 0},
+			Links: []models.MemoryLink{{TargetID: "target-db", LinkType: "pattern"}}},
+		{ID: "target-db", Content: "db pool", WorkspaceID: "api", Embedding: []float32{0.7, 0.7},
+			Links: []models.MemoryLink{{TargetID: "target-retry", LinkType: "pattern"}}},
+		{ID: "source-retry", Content: "retry loop again", WorkspaceID: "api-old", Tags: []string{"http"}, Embedding: []float32{1, 0.01},
+			Links: []models.MemoryLink{{TargetID: "source-cache", LinkType: "solution"}}},
+		{ID: "source-cache", Content: "cache layer", WorkspaceID: "api-old", Embedding: []float32{0, 1},
+			Links: []models.MemoryLink{{TargetID: "source-retry", LinkType: "solution"}}},
+		// Links to the duplicate without a back-link, as imported memories may
+		{ID: "one-way", Content: "client retries", WorkspaceID: "web", Embedding: []float32{0, 1},
+			Links: []models.MemoryLink{{TargetID: "source-retry", LinkType: "pattern"}}},
+	}
+	if err := service.store.StoreMemories(ctx, memories); err != nil {
+		t.Fatalf("Failed to store memories: %v", err)
+	}
+
+	response, err := service.MergeWorkspaces(ctx, &models.WorkspaceMergeRequest{Source: "api-old", Target: "api"})
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if response.Matched != 2 || response.Moved != 1 || response.Deduplicated != 1 {
+		t.Errorf("Expected one memory moved and one folded, got %+v", response)
+	}
+
+	if _, err := service.store.GetMemory(ctx, "source-retry"); err == nil {
+		t.Error("Expected the duplicate to be deleted")
+	}
+
+	survivor, err := service.store.GetMemory(ctx, "target-retry")
+	if err != nil {
+		t.Fatalf("Failed to load survivor: %v", err)
+	}
+	if !HasAllTags(survivor, []string{"go", "http"}) || len(survivor.Links) != 2 {
+		t.Errorf("Expected the duplicate's tags and links on the survivor, got %+v", survivor)
+	}
+
+	moved, err := service.store.GetMemory(ctx, "source-cache")
+	if err != nil {
+		t.Fatalf("Failed to load moved memory: %v", err)
+	}
+	if moved.WorkspaceID != "api" || len(moved.Links) != 1 || moved.Links[0].TargetID != "target-retry" {
+		t.Errorf("Expected the moved memory to link to the survivor, got %+v", moved)
+	}
+
+	oneWay, err := service.store.GetMemory(ctx, "one-way")
+	if err != nil {
+		t.Fatalf("Failed to load linking memory: %v", err)
+	}
+	if len(oneWay.Links) != 1 || oneWay.Links[0].TargetID != "target-retry" {
+		t.Errorf("Expected a link without a back-link to be pointed at the survivor, got %+v", oneWay.Links)
+	}
+
+	if exists, _ := service.WorkspaceExists(ctx, "api-old"); exists {
+		t.Error("Expected the source workspace to be gone")
+	}
+}
+
+func TestRenameAndMoveMemories(t *testing.T) {
+	ctx := context.Background()
+	service := newTestWorkspaceService(t, filepath.Join(t.TempDir(), "memories.json"))
+
+	if _, err := service.CreateWorkspace(ctx, &models.WorkspaceRequest{Identifier: "mono", Owner: "core"}); err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	memories := []*models.Memory{
+		{ID: "m1", Content: "a", WorkspaceID: "mono", CodeType: "go", Tags: []string{"billing"}, Embedding: []float32{1, 0}},
+		{ID: "m2", Content: "b", WorkspaceID: "mono", CodeType: "go", Embedding: []float32{0, 1}},
+		{ID: "m3", Content: "c", WorkspaceID: "mono", CodeType: "sql", Tags: []string{"billing"}, Embedding: []float32{1, 1}},
+	}
+	if err := service.store.StoreMemories(ctx, memories); err != nil {
+		t.Fatalf("Failed to store memories: %v", err)
+	}
+
+	renamed, err := service.RenameWorkspace(ctx, &models.WorkspaceRenameRequest{Identifier: "mono", NewIdentifier: "platform"})
+	if err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if renamed.Moved != 3 {
+		t.Errorf("Expected 3 memories moved, got %+v", renamed)
+	}
+
+	info, err := service.GetWorkspaceInfo(ctx, "platform")
+	if err != nil {
+		t.Fatalf("Failed to get workspace: %v", err)
+	}
+	if !info.Registered || info.Owner != "core" || info.Name != "Platform" || info.MemoryCount != 3 {
+		t.Errorf("Expected the registry record to follow the rename, got %+v", info)
+	}
+
+	moved, err := service.MoveMemories(ctx, &models.MoveMemoriesRequest{
+		WorkspaceID:       "platform",
+		CodeTypes:         []string{"go"},
+		Tags:              []string{"billing"},
+		TargetWorkspaceID: "billing",
+	})
+	if err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+	if moved.Matched != 1 || moved.Moved != 1 {
+		t.Errorf("Expected only m1 to match the filter, got %+v", moved)
+	}
+
+	byID, err := service.MoveMemories(ctx, &models.MoveMemoriesRequest{
+		MemoryIDs:         []string{"m1", "m3", "missing"},
+		TargetWorkspaceID: "billing",
+	})
+	if err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+	if byID.Moved != 1 || byID.Unchanged != 1 || len(byID.NotFound) != 1 || byID.NotFound[0] != "missing" {
+		t.Errorf("Expected m3 moved, m1 unchanged and one ID missing, got %+v", byID)
+	}
+}
+
+func TestResolveWorkspaceKeysClonesOnTheirRemote(t *testing.T) {
+	dir := t.TempDir()
+	service := newTestWorkspaceService(t, filepath.Join(dir, "memories.json"))
+
+	clones := map[string]string{
+		"work/billing": "https://github.com/acme/billing.git",
+		"home/billing": "git@github.com:acme/billing.git",
+		"local/notes":  "/srv/git/notes.git",
+	}
+	for clone, remote := range clones {
+		gitDir := filepath.Join(dir, clone, ".git")
+		if err := os.MkdirAll(filepath.Join(gitDir, "refs", "heads"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		for name, content := range map[string]string{
+			"config":          "[remote \"origin\"]\n\turl = " + remote + "\n",
+			"HEAD":            "ref: refs/heads/main\n",
+			"refs/heads/main": "3333333333333333333333333333333333333333\n",
+		} {
+			if err := os.WriteFile(filepath.Join(gitDir, filepath.FromSlash(name)), []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// Existing workspaces are keyed on paths, so git identity is opt-in
+	if id := service.NormalizeWorkspaceID(filepath.Join(dir, "work", "billing")); id != filepath.Join(dir, "work", "billing") {
+		t.Errorf("Expected the raw path by default, got %s", id)
+	}
+
+	service.SetGitIdentity(true)
+	work, repo := service.ResolveWorkspace(filepath.Join(dir, "work", "billing"))
+	home := service.NormalizeWorkspaceID(filepath.Join(dir, "home", "billing") + "/")
+	if work != "github.com/acme/billing" || home != work {
+		t.Errorf("Expected both clones in github.com/acme/billing, got %s and %s", work, home)
+	}
+	if repo == nil || repo.Branch != "main" || repo.Commit == "" {
+		t.Errorf("Expected the checked out branch and commit, got %+v", repo)
+	}
+
+	local := filepath.Join(dir, "local", "notes")
+	if id := service.NormalizeWorkspaceID(local); id != local {
+		t.Errorf("Expected a repository with a local remote to keep its root, got %s", id)
+	}
+
+	missing := filepath.Join(dir, "work", "billing-missing")
+	if id, repo := service.ResolveWorkspace(missing); id != missing || repo != nil {
+		t.Errorf("Expected a missing path to be kept, got %s, %+v", id, repo)
+	}
+}
+
+func TestWorkspaceStatsCountsEveryMemory(t *testing.T) {
+	ctx := context.Background()
+	service := newTestWorkspaceService(t, filepath.Join(t.TempDir(), "memories.json"))
+
+	now := time.Now()
+	memories := []*models.Memory{
+		{ID: "other", Content: "x", WorkspaceID: "web", Embedding: []float32{1, 0}},
+		{ID: "old", Content: "x", WorkspaceID: "api", CodeType: "sql", Tags: []string{"db"}, Keywords: []string{"Postgres"},
+			CreatedAt: now.AddDate(0, -6, 0), UpdatedAt: now.AddDate(0, -6, 0), Embedding: []float32{1, 0},
+			Links: []models.MemoryLink{{TargetID: "m0", LinkType: "pattern"}, {TargetID: "other", LinkType: "technology"}}},
+	}
+	// More memories than a single page used to hold
+	for i := 0; i < 1200; i++ {
+		tags := []string{"go"}
+		if i%2 == 0 {
+			tags = nil
+		}
+		memories = append(memories, &models.Memory{
+			ID: fmt.Sprintf("m%d", i), Content: "x", WorkspaceID: "api", CodeType: "go", Tags: tags,
+			Keywords: []string{"retry", "postgres"}, CreatedAt: now.Add(-time.Hour), UpdatedAt: now, Embedding: []float32{0, 1},
+		})
+	}
+	if err := service.store.StoreMemories(ctx, memories); err != nil {
+		t.Fatalf("Failed to store memories: %v", err)
+	}
+
+	stats, err := service.WorkspaceStats(ctx, &models.WorkspaceStatsRequest{WorkspaceID: "api", Limit: 2})
+	if err != nil {
+		t.Fatalf("Failed to compute stats: %v", err)
+	}
+	if stats.MemoryCount != 1201 || stats.TotalMemories != 1202 {
+		t.Errorf("Expected exact counts, got %d of %d", stats.MemoryCount, stats.TotalMemories)
+	}
+	if stats.CodeTypes["go"] != 1200 || stats.CodeTypes["sql"] != 1 {
+		t.Errorf("Unexpected code types %v", stats.CodeTypes)
+	}
+	if stats.DistinctTags != 2 || stats.Untagged != 600 || stats.Tags[0] != (models.TermCount{Term: "go", Count: 600}) {
+		t.Errorf("Unexpected tags %+v, %d distinct, %d untagged", stats.Tags, stats.DistinctTags, stats.Untagged)
+	}
+	if len(stats.TopKeywords) != 2 || stats.TopKeywords[0] != (models.TermCount{Term: "postgres", Count: 1201}) {
+		t.Errorf("Expected keywords counted case-insensitively, got %+v", stats.TopKeywords)
+	}
+	if stats.Ages[0].Bucket != "day" || stats.Ages[0].Count != 1200 || stats.Ages[4].Count != 1 {
+		t.Errorf("Unexpected ages %+v", stats.Ages)
+	}
+	if stats.Links.Total != 2 || stats.Links.LinkedMemories != 1 || stats.Links.External != 1 || stats.Links.ByType["pattern"] != 1 {
+		t.Errorf("Unexpected links %+v", stats.Links)
+	}
+
+	info, err := service.GetWorkspaceInfo(ctx, "api")
+	if err != nil || info.MemoryCount != 1201 {
+		t.Errorf("Expected workspace info to count every memory, got %+v, %v", info, err)
+	}
+	if err == nil && (!info.CreatedAt.Equal(memories[1].CreatedAt) || !info.UpdatedAt.Equal(now)) {
+		t.Errorf("Expected the dates of the oldest and newest memories, got %v and %v", info.CreatedAt, info.UpdatedAt)
+	}
+
+	if _, err := service.WorkspaceStats(ctx, &models.WorkspaceStatsRequest{WorkspaceID: "missing"}); err == nil {
+		t.Error("Expected stats for an unknown workspace to fail")
+	}
+}
+
+func TestMoveMemoriesKeepsChangesMadeUnderTheMemoryLock(t *testing.T) {
+	ctx := context.Background()
+	service := newTestWorkspaceService(t, filepath.Join(t.TempDir(), "memories.json"))
+
+	memory := &models.Memory{ID: "m1", Content: "retry with backoff", WorkspaceID: "api", Embedding: []float32{1, 0}}
+	if err := service.store.StoreMemories(ctx, []*models.Memory{memory}); err != nil {
+		t.Fatalf("Failed to store memory: %v", err)
+	}
+
+	// Another writer is part way through changing the memory
+	unlock := service.Locks().Lock("m1")
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := service.MoveMemories(ctx, &models.MoveMemoriesRequest{
+			MemoryIDs:         []string{"m1"},
+			TargetWorkspaceID: "web",
+		})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		unlock()
+		t.Fatalf("Expected the move to wait for the memory lock, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	current, err := service.store.GetMemory(ctx, "m1")
+	if err != nil {
+		t.Fatalf("Failed to load memory: %v", err)
+	}
+	current.Tags = append(current.Tags, "resilience")
+	if err := service.store.UpdateMemories(ctx, []*models.Memory{current}); err != nil {
+		t.Fatalf("Failed to update memory: %v", err)
+	}
+	unlock()
+
+	if err := <-done; err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+
+	moved, err := service.store.GetMemory(ctx, "m1")
+	if err != nil {
+		t.Fatalf("Failed to load memory: %v", err)
+	}
+	if moved.WorkspaceID != "web" || len(moved.Tags) != 1 || moved.Tags[0] != "resilience" {
+		t.Errorf("Expected the move to keep the concurrent change, got workspace %q tags %v", moved.WorkspaceID, moved.Tags)
+	}
+}